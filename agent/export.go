@@ -8,6 +8,7 @@ import l "github.com/pradord/llm/internal/llm"
 // Re-exports
 type (
     Executor = i.Executor
+    Result = i.Result
 )
 
 // Constructors
@@ -0,0 +1,58 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+
+    "github.com/pradord/llm/pkg/dataset"
+)
+
+// multiFlag collects a repeatable string flag
+type multiFlag []string
+
+func (m *multiFlag) String() string     { return strings.Join(*m, ",") }
+func (m *multiFlag) Set(v string) error { *m = append(*m, v); return nil }
+
+// runDataset implements `llm dataset [flags]`
+func runDataset(args []string) int {
+    fs := flag.NewFlagSet("dataset", flag.ContinueOnError)
+    threadsDir := fs.String("threads-dir", ".llm_threads", "Directory that stores conversation threads")
+    configPath := fs.String("config", "", "Path to config file (for the encryption key)")
+    out := fs.String("out", "dataset", "Output directory for train.jsonl, validation.jsonl and stats.json")
+    format := fs.String("format", "openai", "Output format: openai or anthropic")
+    projectID := fs.String("project", "", "Only threads of this project")
+    skill := fs.String("skill", "", "Only threads run with this skill")
+    minRating := fs.Float64("min-rating", 0, "Only threads with metadata rating >= this (0 = no filter)")
+    val := fs.Float64("validation", 0.1, "Fraction of examples for the validation split")
+    seed := fs.String("seed", "", "Seed for the train/validation split")
+    keepSecrets := fs.Bool("keep-secrets", false, "Do not redact secrets")
+    dropSystem := fs.Bool("drop-system", false, "Leave system prompts out of examples")
+    var redact multiFlag
+    fs.Var(&redact, "redact", "Additional regular expression to redact (repeatable)")
+    if err := fs.Parse(args); err != nil { return 2 }
+
+    if *val == 0 { *val = -1 } // an explicit 0 disables the split
+    store, _, err := openThreadStore(*threadsDir, *configPath)
+    if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+    ds, err := dataset.Build(store, dataset.Options{
+        ProjectID: *projectID, Skill: *skill, MinRating: *minRating,
+        ValidationSplit: *val, Seed: *seed, KeepSecrets: *keepSecrets,
+        ExtraRedactions: redact, DropSystem: *dropSystem,
+    })
+    if err != nil { fmt.Fprintf(os.Stderr, "build dataset: %v\n", err); return 1 }
+    paths, err := ds.WriteFiles(*out, dataset.Format(strings.ToLower(*format)))
+    if err != nil { fmt.Fprintf(os.Stderr, "write dataset: %v\n", err); return 1 }
+
+    st := ds.Stats
+    fmt.Printf("Threads: %d, selected: %d, duplicates: %d, skipped: %d, redactions: %d\n", st.Threads, st.Selected, st.Duplicates, st.Skipped, st.Redactions)
+    for _, s := range []struct {
+        name string
+        st   dataset.SplitStats
+    }{{"train", st.Train}, {"validation", st.Validation}} {
+        fmt.Printf("%-10s %4d examples  %6d messages  ~%d tokens (min %d, avg %d, max %d)\n", s.name, s.st.Examples, s.st.Messages, s.st.Tokens, s.st.MinTokens, s.st.AvgTokens, s.st.MaxTokens)
+    }
+    for _, p := range paths { fmt.Println("Wrote", p) }
+    return 0
+}
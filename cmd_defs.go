@@ -0,0 +1,91 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "os"
+    "os/signal"
+    "time"
+
+    "github.com/pradord/llm/pkg/defs"
+    "github.com/pradord/llm/pkg/llm"
+    "github.com/pradord/llm/pkg/project"
+    "github.com/pradord/llm/pkg/skills"
+    "github.com/pradord/llm/pkg/tools"
+)
+
+// builtinRegistries returns registries holding the built-in tools and skills,
+// as a server would before loading definitions
+func builtinRegistries() (*tools.ToolRegistry, *skills.SkillRegistry) {
+    toolReg := tools.NewToolRegistry()
+    for _, t := range tools.GetAllBuiltInTools() { toolReg.Register(t) }
+    toolReg.Register(tools.NewWebSearch(nil, ""))
+    skillReg := skills.NewSkillRegistry(toolReg)
+    for _, s := range skills.GetAllBuiltInSkills() { _ = skillReg.Register(s) }
+    return toolReg, skillReg
+}
+
+// runDefs implements `llm defs <lint|watch> [flags]`
+func runDefs(args []string) int {
+    if len(args) == 0 {
+        fmt.Fprintln(os.Stderr, "Usage: llm defs <lint|watch> [flags]")
+        return 2
+    }
+    cmd := args[0]
+    fs := flag.NewFlagSet("defs "+cmd, flag.ContinueOnError)
+    defsDir := fs.String("defs-dir", "defs", "Directory containing tools/ and skills/ YAML")
+    projectsDir := fs.String("projects-dir", "defs/projects", "Directory with project YAML definitions")
+    interval := fs.Duration("interval", 2*time.Second, "watch: how often to check for changes")
+    strict := fs.Bool("strict", false, "watch: reject changes that lint reports errors for")
+    var extraTools multiFlag
+    fs.Var(&extraTools, "tool", "lint: name of an app-registered tool that definitions may use (repeatable)")
+    if err := fs.Parse(args[1:]); err != nil { return 2 }
+
+    toolReg, skillReg := builtinRegistries()
+    validModel := func(m string) bool { return llm.Model(m).IsValid() }
+    switch cmd {
+    case "lint":
+        var builtins []string
+        for _, s := range skillReg.List() { builtins = append(builtins, s.Name) }
+        diags, err := defs.Lint(defs.LintConfig{
+            DefsDir:    *defsDir,
+            Tools:      append(toolReg.Names(), extraTools...),
+            Skills:     builtins,
+            ValidModel: validModel,
+        })
+        if err != nil { fmt.Fprintf(os.Stderr, "lint: %v\n", err); return 1 }
+        for _, d := range diags { fmt.Println(d) }
+        if defs.HasErrors(diags) { return 1 }
+        fmt.Printf("%d warning(s), no errors\n", len(diags))
+    case "watch":
+        w := defs.NewWatcher(defs.Config{
+            DefsDir:     *defsDir,
+            ProjectsDir: *projectsDir,
+            Interval:    *interval,
+            Tools:       toolReg,
+            Skills:      skillReg,
+            Strict:      *strict,
+            ValidModel:  validModel,
+            Validator: &project.Validator{
+                HasTool:    func(name string) bool { _, ok := toolReg.Get(name); return ok },
+                HasSkill:   func(name string) bool { _, ok := skillReg.Get(name); return ok },
+                ValidModel: validModel,
+            },
+            OnEvent: func(e defs.Event) {
+                fmt.Printf("%s %s\n", e.Time.Format("15:04:05"), e)
+                for _, f := range e.Added { fmt.Printf("  + %s\n", f) }
+                for _, f := range e.Changed { fmt.Printf("  ~ %s\n", f) }
+                for _, f := range e.Removed { fmt.Printf("  - %s\n", f) }
+            },
+        })
+        ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+        defer stop()
+        fmt.Printf("Watching %s and %s every %s (Ctrl-C to stop)\n", *defsDir, *projectsDir, *interval)
+        w.Run(ctx)
+    default:
+        fmt.Fprintf(os.Stderr, "defs: unknown command %q (want lint or watch)\n", cmd)
+        return 2
+    }
+    return 0
+}
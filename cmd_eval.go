@@ -0,0 +1,115 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "os"
+    "os/signal"
+    "path/filepath"
+
+    "github.com/pradord/llm/pkg/config"
+    "github.com/pradord/llm/pkg/eval"
+    "github.com/pradord/llm/pkg/llm"
+    "github.com/pradord/llm/pkg/skillpack"
+    "github.com/pradord/llm/pkg/skills"
+    "github.com/pradord/llm/pkg/tools"
+)
+
+// runEval implements `llm eval <run|compare> [flags]`
+func runEval(args []string) int {
+    if len(args) == 0 {
+        fmt.Fprintln(os.Stderr, "Usage: llm eval run [flags] | llm eval compare <report.json>...")
+        return 2
+    }
+    cmd := args[0]
+    fs := flag.NewFlagSet("eval "+cmd, flag.ContinueOnError)
+    suitesDir := fs.String("suites", "defs/evals", "Directory of eval suite YAML")
+    var suiteFiles, models multiFlag
+    fs.Var(&suiteFiles, "suite", "Run only this suite file (repeatable)")
+    fs.Var(&models, "model", "Also run every suite with this model (repeatable)")
+    defsDir := fs.String("defs-dir", "defs", "Directory whose tools/ and skills/ YAML are evaluated alongside the built-ins")
+    packsDir := fs.String("packs-dir", ".llm_packs", "Directory of installed skill packs, whose skills are evaluated too")
+    configPath := fs.String("config", "", "Path to config file (provider credentials)")
+    mock := fs.Bool("mock", false, "Replay the cases' scripted mock replies instead of calling the provider")
+    parallel := fs.Int("parallel", 4, "Cases run at once")
+    out := fs.String("out", "", "Write the reports as JSON to this file")
+    if err := fs.Parse(args[1:]); err != nil { return 2 }
+
+    switch cmd {
+    case "run":
+        var suites []*eval.Suite
+        if len(suiteFiles) > 0 {
+            for _, f := range suiteFiles {
+                s, err := eval.LoadSuite(f)
+                if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+                suites = append(suites, s)
+            }
+        } else {
+            var err error
+            if suites, err = eval.LoadDir(*suitesDir); err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+        }
+        if len(suites) == 0 { fmt.Fprintf(os.Stderr, "no eval suites in %s\n", *suitesDir); return 1 }
+
+        toolReg, skillReg := builtinRegistries()
+        runner := &eval.Runner{Skills: skillReg, Parallel: *parallel}
+        for _, m := range models { runner.Models = append(runner.Models, llm.Model(m)) }
+        if !*mock {
+            cfg, err := config.Load(*configPath)
+            if err != nil { fmt.Fprintf(os.Stderr, "config: %v\n", err); return 1 }
+            if err := cfg.Validate(); err != nil { fmt.Fprintf(os.Stderr, "config: %v (use --mock to run offline)\n", err); return 1 }
+            client := llm.NewClient(llm.ClientConfig{
+                APIKey:         cfg.LLM.APIKey,
+                BaseURL:        cfg.LLM.BaseURL,
+                DefaultModel:   cfg.LLM.DefaultModel,
+                DefaultTemp:    cfg.LLM.DefaultTemp,
+                TimeoutSeconds: cfg.LLM.TimeoutSeconds,
+                MaxRetries:     cfg.LLM.MaxRetries,
+                RequestsPerMin: cfg.LLM.RequestsPerMin,
+            })
+            toolReg.Register(tools.NewWebSearch(client, llm.ModelPerplexitySonar))
+            runner.Client = apiClient(client, cfg)
+            runner.DefaultModel = cfg.LLM.DefaultModel
+        }
+        // tool YAML first: skills may use the http tools it defines
+        metas, err := tools.LoadToolMetadataDir(filepath.Join(*defsDir, "tools"))
+        if err == nil { err = tools.ApplyToolMetadata(toolReg, metas) }
+        if err != nil { fmt.Fprintf(os.Stderr, "load tools: %v\n", err); return 1 }
+        if err := skills.LoadSkillsDir(filepath.Join(*defsDir, "skills"), skillReg); err != nil { fmt.Fprintf(os.Stderr, "load skills: %v\n", err); return 1 }
+        if _, err := skillpack.Load(*packsDir, toolReg, skillReg); err != nil { fmt.Fprintf(os.Stderr, "warning: %v\n", err) }
+
+        // Ctrl-C stops starting cases; the ones already running finish
+        ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+        defer stop()
+        var reports []*eval.Report
+        failed := false
+        for _, s := range suites {
+            if ctx.Err() != nil { break }
+            reps, err := runner.Run(ctx, s)
+            for _, r := range reps {
+                fmt.Print(r)
+                if r.Passed < r.Total { failed = true }
+            }
+            reports = append(reports, reps...)
+            if err != nil { fmt.Fprintf(os.Stderr, "%v\n", err); failed = true }
+        }
+        if len(reports) > 1 { fmt.Println(); fmt.Print(eval.Compare(reports...)) }
+        if *out != "" {
+            if err := eval.WriteReports(*out, reports); err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+        }
+        if failed { return 1 }
+    case "compare":
+        var reports []*eval.Report
+        for _, f := range fs.Args() {
+            reps, err := eval.ReadReports(f)
+            if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+            reports = append(reports, reps...)
+        }
+        if len(reports) == 0 { fmt.Fprintln(os.Stderr, "Usage: llm eval compare <report.json>..."); return 2 }
+        fmt.Print(eval.Compare(reports...))
+    default:
+        fmt.Fprintf(os.Stderr, "eval: unknown command %q (want run or compare)\n", cmd)
+        return 2
+    }
+    return 0
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+    "bufio"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+
+    "github.com/pradord/llm/pkg/conversation"
+)
+
+// runExport implements `llm export [flags] <thread-id>`
+func runExport(args []string) int {
+    fs := flag.NewFlagSet("export", flag.ContinueOnError)
+    threadsDir := fs.String("threads-dir", ".llm_threads", "Directory that stores conversation threads")
+    configPath := fs.String("config", "", "Path to config file (for the encryption key)")
+    format := fs.String("format", "md", "Output format: md, html or jsonl")
+    output := fs.String("o", "", "Output file (default stdout)")
+    fs.Usage = func() {
+        fmt.Fprintln(fs.Output(), "Usage: llm export [flags] <thread-id>")
+        fs.PrintDefaults()
+    }
+    if err := fs.Parse(args); err != nil { return 2 }
+    if fs.NArg() != 1 { fs.Usage(); return 2 }
+
+    write := map[string]func(io.Writer, *conversation.Thread) error{
+        "md": conversation.WriteMarkdown, "markdown": conversation.WriteMarkdown,
+        "html": conversation.WriteHTML,
+        "jsonl": conversation.WriteJSONL,
+    }[strings.ToLower(*format)]
+    if write == nil { fmt.Fprintf(os.Stderr, "unknown format %q (want md, html or jsonl)\n", *format); return 2 }
+
+    store, _, err := openThreadStore(*threadsDir, *configPath)
+    if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+    th, err := store.GetThread(fs.Arg(0))
+    if err != nil { fmt.Fprintf(os.Stderr, "load thread: %v\n", err); return 1 }
+
+    var w io.Writer = os.Stdout
+    if *output != "" {
+        f, err := os.Create(*output)
+        if err != nil { fmt.Fprintf(os.Stderr, "create output: %v\n", err); return 1 }
+        defer f.Close()
+        w = f
+    }
+    bw := bufio.NewWriter(w)
+    if err := write(bw, th); err != nil { fmt.Fprintf(os.Stderr, "export: %v\n", err); return 1 }
+    if err := bw.Flush(); err != nil { fmt.Fprintf(os.Stderr, "export: %v\n", err); return 1 }
+    return 0
+}
+
+// runImport implements `llm import [flags] <file>`
+func runImport(args []string) int {
+    fs := flag.NewFlagSet("import", flag.ContinueOnError)
+    threadsDir := fs.String("threads-dir", ".llm_threads", "Directory that stores conversation threads")
+    configPath := fs.String("config", "", "Path to config file (for the encryption key)")
+    source := fs.String("from", "", "Export format: chatgpt, claude or jsonl")
+    projectID := fs.String("project", "", "Assign imported threads to this project")
+    fs.Usage = func() {
+        fmt.Fprintln(fs.Output(), "Usage: llm import --from chatgpt|claude|jsonl [flags] <file>")
+        fs.PrintDefaults()
+    }
+    if err := fs.Parse(args); err != nil { return 2 }
+    if fs.NArg() != 1 || *source == "" { fs.Usage(); return 2 }
+
+    store, idx, err := openThreadStore(*threadsDir, *configPath)
+    if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+    res, err := conversation.ImportFile(store, strings.ToLower(*source), fs.Arg(0), *projectID)
+    if res != nil { fmt.Printf("Imported %d thread(s), skipped %d already imported\n", res.Imported, res.Skipped) }
+    if err != nil { fmt.Fprintf(os.Stderr, "import: %v\n", err); return 1 }
+
+    // keep `llm search` current with the imported threads
+    if res.Imported > 0 {
+        if err := idx.Save(); err != nil { fmt.Fprintf(os.Stderr, "Warning: search index not updated: %v\n", err) }
+    }
+    return 0
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+    "unicode/utf8"
+
+    "github.com/pradord/llm/pkg/config"
+    "github.com/pradord/llm/pkg/knowledge"
+    "github.com/pradord/llm/pkg/project"
+)
+
+// openKnowledge opens the knowledge base of a project with the configured
+// embeddings model and encryption key
+func openKnowledge(cfg *config.Config, dir, projectID string) (*knowledge.Base, error) {
+    key, err := cfg.Persistence.Key()
+    if err != nil { return nil, fmt.Errorf("encryption key: %w", err) }
+    if dir == "" { dir = cfg.Persistence.KnowledgeDir }
+    embedder := knowledge.NewEmbeddingsClient(knowledge.EmbeddingsConfig{
+        APIKey: cfg.LLM.APIKey, BaseURL: cfg.LLM.BaseURL, Model: cfg.Persistence.EmbeddingModel,
+    })
+    return knowledge.Open(knowledge.Dir(dir, projectID), embedder, knowledge.Config{Model: cfg.Persistence.EmbeddingModel, Key: key})
+}
+
+// runKnowledge implements `llm knowledge <ingest|search> [flags]`
+func runKnowledge(args []string) int {
+    if len(args) == 0 {
+        fmt.Fprintln(os.Stderr, "Usage: llm knowledge <ingest|search> --project ID [flags]")
+        return 2
+    }
+    cmd := args[0]
+    fs := flag.NewFlagSet("knowledge "+cmd, flag.ContinueOnError)
+    projectsDir := fs.String("projects-dir", "defs/projects", "Directory with project YAML definitions")
+    projectID := fs.String("project", "", "Project whose knowledge base to use (required)")
+    docs := fs.String("docs", "", "ingest: document folder (default: the project's knowledge setting)")
+    kbDir := fs.String("knowledge-dir", "", "Directory of knowledge indexes (default from config, .llm_knowledge)")
+    configPath := fs.String("config", "", "Path to config file")
+    limit := fs.Int("limit", 5, "search: number of passages")
+    asJSON := fs.Bool("json", false, "Print JSON")
+    if err := fs.Parse(args[1:]); err != nil { return 2 }
+    if *projectID == "" { fmt.Fprintln(os.Stderr, "knowledge: --project is required"); return 2 }
+
+    cfg, err := config.Load(*configPath)
+    if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+    kb, err := openKnowledge(cfg, *kbDir, *projectID)
+    if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+    ctx := context.Background()
+
+    switch cmd {
+    case "ingest":
+        if *docs == "" {
+            projs, err := project.LoadDir(*projectsDir)
+            if err != nil { fmt.Fprintf(os.Stderr, "load projects: %v\n", err); return 1 }
+            if p := projs[*projectID]; p != nil { *docs = p.Knowledge }
+        }
+        if *docs == "" { fmt.Fprintf(os.Stderr, "knowledge: project %s has no knowledge folder; pass --docs\n", *projectID); return 2 }
+        rep, err := kb.Ingest(ctx, *docs)
+        if rep != nil {
+            if *asJSON {
+                out, _ := json.MarshalIndent(rep, "", "  ")
+                fmt.Println(string(out))
+            } else {
+                fmt.Printf("%d added, %d updated, %d removed, %d unchanged, %d chunks embedded\n",
+                    len(rep.Added), len(rep.Updated), len(rep.Removed), rep.Unchanged, rep.Chunks)
+                for path, reason := range rep.Skipped { fmt.Printf("skipped %s: %s\n", path, reason) }
+            }
+        }
+        if err != nil { fmt.Fprintf(os.Stderr, "ingest: %v\n", err); return 1 }
+    case "search":
+        query := strings.Join(fs.Args(), " ")
+        if strings.TrimSpace(query) == "" { fmt.Fprintln(os.Stderr, "knowledge search: query is required"); return 2 }
+        results, err := kb.Search(ctx, query, *limit)
+        if err != nil { fmt.Fprintf(os.Stderr, "search: %v\n", err); return 1 }
+        if *asJSON {
+            for i := range results { results[i].Vector = nil }
+            out, _ := json.MarshalIndent(results, "", "  ")
+            fmt.Println(string(out))
+            return 0
+        }
+        for _, r := range results {
+            fmt.Printf("%.3f  %s:%d\n    %s\n", r.Score, r.Path, r.Line, strings.ReplaceAll(truncate(r.Text, 300), "\n", "\n    "))
+        }
+    default:
+        fmt.Fprintf(os.Stderr, "knowledge: unknown command %q (want ingest or search)\n", cmd)
+        return 2
+    }
+    return 0
+}
+
+func truncate(s string, n int) string {
+    if len(s) <= n { return s }
+    for n > 0 && !utf8.RuneStart(s[n]) { n-- }
+    return s[:n] + "…"
+}
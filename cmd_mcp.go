@@ -0,0 +1,170 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "time"
+
+    "github.com/pradord/llm/pkg/agent"
+    "github.com/pradord/llm/pkg/config"
+    "github.com/pradord/llm/pkg/defs"
+    "github.com/pradord/llm/pkg/llm"
+    "github.com/pradord/llm/pkg/mcp"
+    "github.com/pradord/llm/pkg/project"
+    "github.com/pradord/llm/pkg/skillpack"
+    "github.com/pradord/llm/pkg/skills"
+    "github.com/pradord/llm/pkg/tools"
+)
+
+// runMCP implements `llm mcp <tools|serve> [flags]`: tools lists the tools of
+// the MCP servers configured under mcp.servers, serve publishes our own tools,
+// skills and project knowledge over stdio
+func runMCP(args []string) int {
+    if len(args) == 0 {
+        fmt.Fprintln(os.Stderr, "Usage: llm mcp <tools|serve> [flags]")
+        return 2
+    }
+    cmd := args[0]
+    fs := flag.NewFlagSet("mcp "+cmd, flag.ContinueOnError)
+    configPath := fs.String("config", "", "Path to config file (auto-detected if not provided)")
+    server := fs.String("server", "", "tools: only this server")
+    defsDir := fs.String("defs-dir", "defs", "serve: directory containing tools/ and skills/ YAML")
+    packsDir := fs.String("packs-dir", ".llm_packs", "serve: directory of installed skill packs")
+    projectsDir := fs.String("projects-dir", "defs/projects", "serve: directory with project YAML definitions")
+    projectID := fs.String("project", "", "serve: project whose tool and skill allowlists, system prompt and knowledge apply")
+    skillMode := fs.String("skills", mcp.SkillsAsBoth, "serve: publish skills as tools, prompts or both")
+    watch := fs.Duration("watch", 0, "serve: reload tool and skill definitions from --defs-dir at this interval, e.g. 2s (0 disables)")
+    var allowTools multiFlag
+    fs.Var(&allowTools, "tool", "serve: tool to publish and let skills use (repeatable); narrows the project's tools, default web_search only")
+    if err := fs.Parse(args[1:]); err != nil { return 2 }
+    cfg, err := config.Load(*configPath)
+    if err != nil { fmt.Fprintf(os.Stderr, "config: %v\n", err); return 1 }
+
+    switch cmd {
+    case "tools":
+        status := 0
+        found := false
+        for _, sc := range cfg.MCP.Servers {
+            if *server != "" && sc.Name != *server { continue }
+            found = true
+            c, err := mcp.Connect(context.Background(), sc)
+            if err != nil { fmt.Fprintln(os.Stderr, err); status = 1; continue }
+            reg := tools.NewToolRegistry()
+            names, err := mcp.Register(context.Background(), reg, c, sc.Tools)
+            if err != nil { fmt.Fprintln(os.Stderr, err); status = 1 }
+            fmt.Printf("%s (%s %s)\n", sc.Name, c.ServerInfo.Name, c.ServerInfo.Version)
+            for _, n := range names {
+                t, _ := reg.Get(n)
+                fmt.Printf("  %-32s %s\n", n, truncate(t.Description(), 80))
+            }
+            c.Close()
+        }
+        if !found { fmt.Fprintln(os.Stderr, "no matching MCP servers configured (mcp.servers)"); return 1 }
+        return status
+    case "serve":
+        switch *skillMode {
+        case mcp.SkillsAsTools, mcp.SkillsAsPrompts, mcp.SkillsAsBoth:
+        default:
+            fmt.Fprintf(os.Stderr, "--skills must be tools, prompts or both, not %q\n", *skillMode)
+            return 2
+        }
+        srv, packs, err := newMCPServer(cfg, *defsDir, *packsDir, *projectsDir, *projectID)
+        if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+        srv.SkillMode = *skillMode
+        if len(allowTools) > 0 { srv.Call = []agent.Layer{{Name: agent.LayerCall, Settings: agent.Settings{Tools: allowTools}}} }
+        ctx, stop := context.WithCancel(context.Background())
+        defer stop()
+        if *watch > 0 { go watchMCPDefs(ctx, srv, *defsDir, *watch, packs) }
+        // stdout carries the protocol; everything else goes to stderr
+        if err := srv.Serve(ctx, os.Stdin, os.Stdout); err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+        return 0
+    default:
+        fmt.Fprintf(os.Stderr, "mcp: unknown command %q (want tools or serve)\n", cmd)
+        return 2
+    }
+}
+
+// newMCPServer sets up the tools and skills the CLI runs with. Tools imported
+// from MCP servers are not published again, so a server cannot end up
+// starting itself; execute_code stays opt-in as in agent runs.
+func newMCPServer(cfg *config.Config, defsDir, packsDir, projectsDir, projectID string) (*mcp.Server, []*skillpack.Pack, error) {
+    var proj *project.Project
+    if projectID != "" {
+        projs, err := project.LoadDir(projectsDir)
+        if err != nil { return nil, nil, fmt.Errorf("projects: %w", err) }
+        if proj = projs[projectID]; proj == nil { return nil, nil, fmt.Errorf("unknown project %q", projectID) }
+    }
+
+    var client *llm.Client
+    if err := cfg.Validate(); err != nil {
+        fmt.Fprintf(os.Stderr, "warning: config: %v; skills are published as prompts only\n", err)
+    } else {
+        client = llm.NewClient(llm.ClientConfig{
+            APIKey:         cfg.LLM.APIKey,
+            BaseURL:        cfg.LLM.BaseURL,
+            DefaultModel:   cfg.LLM.DefaultModel,
+            DefaultTemp:    cfg.LLM.DefaultTemp,
+            TimeoutSeconds: cfg.LLM.TimeoutSeconds,
+            MaxRetries:     cfg.LLM.MaxRetries,
+            RequestsPerMin: cfg.LLM.RequestsPerMin,
+        })
+    }
+    toolRegistry := tools.NewToolRegistry()
+    imageAPIKey := cfg.Tools.ImageAPIKey
+    if imageAPIKey == "" { imageAPIKey = cfg.LLM.APIKey }
+    if client != nil { toolRegistry.Register(tools.NewWebSearch(client, llm.ModelPerplexitySonar)) }
+    toolRegistry.Register(tools.NewImageAnalyzer(imageAPIKey))
+    toolRegistry.Register(tools.NewURLFetcher())
+    toolRegistry.Register(tools.NewCalculator())
+    toolRegistry.Register(tools.NewImageGenerator(imageAPIKey, cfg.LLM.BaseURL))
+    toolRegistry.Register(tools.NewAudioTTS(cfg.Tools.ImageAPIKey))
+    toolRegistry.Register(tools.NewVideoGenerator(cfg.Tools.ImageAPIKey))
+
+    skillRegistry := skills.NewSkillRegistry(toolRegistry)
+    for _, s := range skills.GetAllBuiltInSkills() { _ = skillRegistry.Register(s) }
+    if metas, err := tools.LoadToolMetadataDir(filepath.Join(defsDir, "tools")); err == nil {
+        if err := tools.ApplyToolMetadata(toolRegistry, metas); err != nil { fmt.Fprintf(os.Stderr, "warning: tool defs: %v\n", err) }
+    } else {
+        fmt.Fprintf(os.Stderr, "warning: tool defs: %v\n", err)
+    }
+    if err := skills.LoadSkillsDir(filepath.Join(defsDir, "skills"), skillRegistry); err != nil { fmt.Fprintf(os.Stderr, "warning: skill defs: %v\n", err) }
+    packs, err := skillpack.Load(packsDir, toolRegistry, skillRegistry)
+    if err != nil { fmt.Fprintf(os.Stderr, "warning: skill packs: %v\n", err) }
+
+    srv := mcp.NewServer(toolRegistry, skillRegistry)
+    srv.Layers = []agent.Layer{agent.DefaultsLayer(), agent.ConfigLayer(cfg), agent.ProjectLayer(proj)}
+    srv.Project = proj
+    if client != nil {
+        srv.Executor = agent.NewExecutorWithClient(apiClient(client, cfg))
+        if fetcher, ok := toolRegistry.Get("fetch_url"); ok { srv.Executor.SetResourceLoader(agent.NewResourceLoader(fetcher)) }
+    }
+    return srv, packs, nil
+}
+
+// watchMCPDefs keeps the server's tools and skills in sync with defsDir until
+// ctx is done. Changes that lint reports errors for are rejected, and the
+// installed packs stay layered on top as at startup. The project is read once.
+func watchMCPDefs(ctx context.Context, srv *mcp.Server, defsDir string, interval time.Duration, packs []*skillpack.Pack) {
+    w := defs.NewWatcher(defs.Config{
+        DefsDir:    defsDir,
+        Interval:   interval,
+        Tools:      srv.Tools,
+        Skills:     srv.Skills,
+        Strict:     true,
+        ValidModel: func(m string) bool { return llm.Model(m).IsValid() },
+        Layered: func() ([]tools.ToolMetadata, []*skills.Skill) {
+            var metas []tools.ToolMetadata
+            var list []*skills.Skill
+            for _, p := range packs {
+                metas = append(metas, p.Tools...)
+                list = append(list, p.Skills...)
+            }
+            return metas, list
+        },
+        OnEvent: func(e defs.Event) { fmt.Fprintf(os.Stderr, "defs: %s\n", e) },
+    })
+    w.Run(ctx)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "github.com/pradord/llm/pkg/skillpack"
+    "github.com/pradord/llm/pkg/skills"
+    "github.com/pradord/llm/pkg/tools"
+)
+
+// runPacks implements `llm packs <install|uninstall|list> [flags] [path|name]`
+func runPacks(args []string) int {
+    if len(args) == 0 {
+        fmt.Fprintln(os.Stderr, "Usage: llm packs <install|uninstall|list> [flags] [path|name]")
+        return 2
+    }
+    cmd := args[0]
+    fs := flag.NewFlagSet("packs "+cmd, flag.ContinueOnError)
+    packsDir := fs.String("packs-dir", ".llm_packs", "Directory of installed skill packs")
+    defsDir := fs.String("defs-dir", "defs", "install: directory whose skills/ YAML pack skills must not clash with")
+    force := fs.Bool("force", false, "install: replace an installed pack of the same name")
+    var extraTools multiFlag
+    fs.Var(&extraTools, "tool", "install: name of an app-registered tool that packs may use (repeatable)")
+    var allowEnv multiFlag
+    fs.Var(&allowEnv, "allow-env", "install: environment variable the pack's http tools may read (repeatable)")
+    if err := fs.Parse(args[1:]); err != nil { return 2 }
+
+    switch cmd {
+    case "install":
+        if fs.NArg() != 1 { fmt.Fprintln(os.Stderr, "Usage: llm packs install [flags] <dir|pack.zip>"); return 2 }
+        toolReg, skillReg := builtinRegistries()
+        for _, name := range extraTools { toolReg.Register(placeholderTool(name)) }
+        if err := skills.LoadSkillsDir(filepath.Join(*defsDir, "skills"), skillReg); err != nil { fmt.Fprintf(os.Stderr, "load skills: %v\n", err); return 1 }
+        if _, err := skillpack.Load(*packsDir, toolReg, skillReg); err != nil { fmt.Fprintf(os.Stderr, "warning: %v\n", err) }
+        p, err := skillpack.Install(fs.Arg(0), *packsDir, skillpack.Options{Tools: toolReg, Skills: skillReg, Force: *force, AllowEnv: allowEnv})
+        if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+        fmt.Printf("installed %s %s: %s\n", p.Name, p.Version, strings.Join(p.SkillNames(), ", "))
+        if len(p.Tools) > 0 { fmt.Printf("tools: %s\n", strings.Join(p.ToolNames(), ", ")) }
+        if len(p.Env) > 0 { fmt.Printf("environment: %s\n", strings.Join(p.Env, ", ")) }
+        if _, err := os.Stat(p.EvalsDir()); err == nil { fmt.Printf("evals: llm eval run --packs-dir %s --suites %s\n", *packsDir, p.EvalsDir()) }
+    case "uninstall":
+        if fs.NArg() != 1 { fmt.Fprintln(os.Stderr, "Usage: llm packs uninstall [flags] <name>"); return 2 }
+        if err := skillpack.Uninstall(*packsDir, fs.Arg(0), nil, nil); err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+        fmt.Printf("uninstalled %s\n", fs.Arg(0))
+    case "list":
+        packs, err := skillpack.List(*packsDir)
+        if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+        toolReg, _ := builtinRegistries()
+        for _, name := range extraTools { toolReg.Register(placeholderTool(name)) }
+        for _, p := range packs {
+            status := "ok"
+            if err := p.Check(toolReg, nil); err != nil { status = "incompatible" }
+            fmt.Printf("%-24s %-10s %-12s %-40s %s\n", p.Name, p.Version, status, strings.Join(p.SkillNames(), ","), p.Description)
+        }
+    default:
+        fmt.Fprintf(os.Stderr, "packs: unknown command %q (want install, uninstall or list)\n", cmd)
+        return 2
+    }
+    return 0
+}
+
+// placeholderTool stands in for a tool the app registers itself, so packs
+// that use it pass the compatibility check
+func placeholderTool(name string) tools.Tool {
+    return tools.NewSimpleTool(name, "app-registered tool", func(ctx context.Context, args map[string]interface{}) (string, error) {
+        return "", fmt.Errorf("tool %s is not available here", name)
+    })
+}
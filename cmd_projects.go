@@ -0,0 +1,111 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+
+    "github.com/pradord/llm/pkg/llm"
+    "github.com/pradord/llm/pkg/project"
+    "github.com/pradord/llm/pkg/skillpack"
+    "github.com/pradord/llm/pkg/skills"
+    "github.com/pradord/llm/pkg/tools"
+)
+
+// runProjects implements `llm projects <sync|validate|list> [flags]`
+func runProjects(args []string) int {
+    if len(args) == 0 {
+        fmt.Fprintln(os.Stderr, "Usage: llm projects <sync|validate|list> [flags]")
+        return 2
+    }
+    cmd := args[0]
+    fs := flag.NewFlagSet("projects "+cmd, flag.ContinueOnError)
+    projectsDir := fs.String("projects-dir", "defs/projects", "Directory with project YAML definitions")
+    storeDir := fs.String("store-dir", ".llm_projects", "Directory of the runtime project store")
+    configPath := fs.String("config", "", "Path to config file (for the encryption key)")
+    prune := fs.Bool("prune", false, "sync: archive stored projects whose definition file was removed")
+    defsDir := fs.String("defs-dir", "defs", "Directory containing tools/ and skills/ YAML that projects may use")
+    packsDir := fs.String("packs-dir", ".llm_packs", "Directory of installed skill packs that projects may use")
+    var extraTools multiFlag
+    fs.Var(&extraTools, "tool", "name of an app-registered tool that projects may use (repeatable)")
+    if err := fs.Parse(args[1:]); err != nil { return 2 }
+
+    // Projects may name the built-in tools and skills, those defined in
+    // --defs-dir and those of installed packs
+    toolReg, skillReg := builtinRegistries()
+    if metas, err := tools.LoadToolMetadataDir(filepath.Join(*defsDir, "tools")); err == nil {
+        if err := tools.ApplyToolMetadata(toolReg, metas); err != nil { fmt.Fprintf(os.Stderr, "warning: tool defs: %v\n", err) }
+    } else {
+        fmt.Fprintf(os.Stderr, "warning: tool defs: %v\n", err)
+    }
+    if err := skills.LoadSkillsDir(filepath.Join(*defsDir, "skills"), skillReg); err != nil { fmt.Fprintf(os.Stderr, "warning: skill defs: %v\n", err) }
+    if _, err := skillpack.Load(*packsDir, toolReg, skillReg); err != nil { fmt.Fprintf(os.Stderr, "warning: skill packs: %v\n", err) }
+    v := &project.Validator{
+        HasTool: func(name string) bool {
+            if _, ok := toolReg.Get(name); ok { return true }
+            for _, t := range extraTools { if t == name { return true } }
+            return false
+        },
+        HasSkill:   func(name string) bool { _, ok := skillReg.Get(name); return ok },
+        ValidModel: func(m string) bool { return llm.Model(m).IsValid() },
+    }
+
+    openStore := func() (*project.FileStore, error) {
+        key, err := storeKey(*configPath)
+        if err != nil { return nil, err }
+        return project.NewFileStoreWithConfig(*storeDir, project.FileStoreConfig{Key: key, Validator: v})
+    }
+
+    switch cmd {
+    case "validate":
+        defs, err := project.LoadDir(*projectsDir)
+        if err != nil { fmt.Fprintf(os.Stderr, "load projects: %v\n", err); return 1 }
+        failed := false
+        for _, id := range sortedIDs(defs) {
+            if err := v.Validate(defs[id]); err != nil {
+                fmt.Printf("%s: %v\n", defs[id].Source, err)
+                failed = true
+            }
+        }
+        if failed { return 1 }
+        fmt.Printf("%d project definition(s) OK\n", len(defs))
+    case "sync":
+        defs, err := project.LoadDir(*projectsDir)
+        if err != nil { fmt.Fprintf(os.Stderr, "load projects: %v\n", err); return 1 }
+        store, err := openStore()
+        if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+        rep, err := project.Sync(store, defs, v, *prune)
+        if rep != nil {
+            fmt.Printf("created %d, updated %d, unchanged %d, archived %d\n", len(rep.Created), len(rep.Updated), len(rep.Unchanged), len(rep.Archived))
+            skipped := make([]string, 0, len(rep.Skipped))
+            for id := range rep.Skipped { skipped = append(skipped, id) }
+            sort.Strings(skipped)
+            for _, id := range skipped { fmt.Printf("skipped %s: %s\n", id, rep.Skipped[id]) }
+        }
+        if err != nil { fmt.Fprintf(os.Stderr, "sync: %v\n", err); return 1 }
+    case "list":
+        store, err := openStore()
+        if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+        ps, err := store.List()
+        if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+        sort.Slice(ps, func(i, j int) bool { return ps[i].ID < ps[j].ID })
+        for _, p := range ps {
+            src := p.Source
+            if src == "" { src = "(runtime)" }
+            fmt.Printf("%-24s v%-3d %-30s %s\n", p.ID, p.Version, p.Name, src)
+        }
+    default:
+        fmt.Fprintf(os.Stderr, "projects: unknown command %q (want sync, validate or list)\n", cmd)
+        return 2
+    }
+    return 0
+}
+
+func sortedIDs(defs map[string]*project.Project) []string {
+    ids := make([]string, 0, len(defs))
+    for id := range defs { ids = append(ids, id) }
+    sort.Strings(ids)
+    return ids
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "sort"
+    "strings"
+
+    "github.com/pradord/llm/pkg/conversation"
+    "github.com/pradord/llm/pkg/envelope"
+    "github.com/pradord/llm/pkg/project"
+)
+
+// runRetention implements `llm retention [flags]`: applies the retention rules of
+// every project definition to the thread store
+func runRetention(args []string) int {
+    fs := flag.NewFlagSet("retention", flag.ContinueOnError)
+    threadsDir := fs.String("threads-dir", ".llm_threads", "Directory that stores conversation threads")
+    projectsDir := fs.String("projects-dir", "", "Directory with project YAML definitions")
+    configPath := fs.String("config", "", "Path to config file (for the encryption key)")
+    dryRun := fs.Bool("dry-run", false, "Only report which threads would be archived or deleted")
+    if err := fs.Parse(args); err != nil { return 2 }
+    if *projectsDir == "" { fmt.Fprintln(os.Stderr, "retention: --projects-dir is required"); return 2 }
+
+    projs, err := project.LoadDir(*projectsDir)
+    if err != nil { fmt.Fprintf(os.Stderr, "load projects: %v\n", err); return 1 }
+    store, idx, err := openThreadStore(*threadsDir, *configPath)
+    if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+
+    ids := make([]string, 0, len(projs))
+    for id := range projs { ids = append(ids, id) }
+    sort.Strings(ids)
+    changed, failed := false, false
+    for _, id := range ids {
+        r := projs[id].Retention
+        if r == nil { continue }
+        maxAge, err := r.MaxAgeDuration()
+        if err != nil { fmt.Fprintf(os.Stderr, "project %s: %v\n", id, err); failed = true; continue }
+        rep, err := store.ApplyRetention(conversation.RetentionPolicy{
+            ProjectID: id, MaxAge: maxAge, MaxThreads: r.MaxThreads, Action: r.Action, DryRun: *dryRun,
+        })
+        if err != nil { fmt.Fprintf(os.Stderr, "project %s: %v\n", id, err); failed = true }
+        if rep == nil { continue }
+        verb := ""
+        if *dryRun { verb = "would be " }
+        fmt.Printf("%s: %d %sarchived, %d %sdeleted\n", id, len(rep.Archived), verb, len(rep.Deleted), verb)
+        if len(rep.Archived)+len(rep.Deleted) > 0 && !*dryRun { changed = true }
+    }
+    if changed {
+        if err := idx.Save(); err != nil { fmt.Fprintf(os.Stderr, "Warning: search index not updated: %v\n", err) }
+    }
+    if failed { return 1 }
+    return 0
+}
+
+// runPurgeUser implements `llm purge-user <user-id>`
+func runPurgeUser(args []string) int {
+    fs := flag.NewFlagSet("purge-user", flag.ContinueOnError)
+    threadsDir := fs.String("threads-dir", ".llm_threads", "Directory that stores conversation threads")
+    configPath := fs.String("config", "", "Path to config file (for the encryption key)")
+    fs.Usage = func() {
+        fmt.Fprintln(fs.Output(), "Usage: llm purge-user [flags] <user-id>")
+        fs.PrintDefaults()
+    }
+    if err := fs.Parse(args); err != nil { return 2 }
+    if fs.NArg() != 1 || strings.TrimSpace(fs.Arg(0)) == "" { fs.Usage(); return 2 }
+
+    store, idx, err := openThreadStore(*threadsDir, *configPath)
+    if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+    n, err := store.PurgeUser(fs.Arg(0))
+    fmt.Printf("Deleted %d thread(s) of user %s\n", n, fs.Arg(0))
+    if err != nil { fmt.Fprintf(os.Stderr, "purge: %v\n", err); return 1 }
+    // the search index holds message text too
+    if err := idx.Save(); err != nil {
+        fmt.Fprintf(os.Stderr, "Warning: search index not updated, delete %s: %v\n", conversation.SearchIndexPath(*threadsDir), err)
+        return 1
+    }
+    return 0
+}
+
+// runKeygen implements `llm keygen`: prints a new encryption key
+func runKeygen(args []string) int {
+    key, err := envelope.GenerateKey()
+    if err != nil { fmt.Fprintf(os.Stderr, "keygen: %v\n", err); return 1 }
+    fmt.Println(key)
+    return 0
+}
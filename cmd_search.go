@@ -0,0 +1,70 @@
+package main
+
+import (
+    "encoding/json"
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/pradord/llm/pkg/conversation"
+)
+
+// runSearch implements `llm search [flags] <query>`
+func runSearch(args []string) int {
+    fs := flag.NewFlagSet("search", flag.ContinueOnError)
+    threadsDir := fs.String("threads-dir", ".llm_threads", "Directory that stores conversation threads")
+    configPath := fs.String("config", "", "Path to config file (for the encryption key)")
+    projectID := fs.String("project", "", "Only threads of this project")
+    role := fs.String("role", "", "Only messages with this role (user, assistant, tool, system)")
+    from := fs.String("from", "", "Only messages created on or after this date (YYYY-MM-DD)")
+    to := fs.String("to", "", "Only messages created on or before this date (YYYY-MM-DD)")
+    limit := fs.Int("limit", 20, "Maximum number of results")
+    asJSON := fs.Bool("json", false, "Print results as JSON")
+    fs.Usage = func() {
+        fmt.Fprintln(fs.Output(), "Usage: llm search [flags] <query>")
+        fs.PrintDefaults()
+    }
+    if err := fs.Parse(args); err != nil { return 2 }
+    query := strings.Join(fs.Args(), " ")
+    if strings.TrimSpace(query) == "" { fs.Usage(); return 2 }
+
+    opts := conversation.SearchOptions{ProjectID: *projectID, Role: *role, Limit: *limit}
+    var err error
+    if opts.From, err = parseDate(*from, false); err != nil { fmt.Fprintf(os.Stderr, "invalid --from: %v\n", err); return 2 }
+    if opts.To, err = parseDate(*to, true); err != nil { fmt.Fprintf(os.Stderr, "invalid --to: %v\n", err); return 2 }
+
+    store, idx, err := openThreadStore(*threadsDir, *configPath)
+    if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+    if err := idx.Sync(store); err != nil { fmt.Fprintf(os.Stderr, "index threads: %v\n", err); return 1 }
+    if err := idx.Save(); err != nil { fmt.Fprintf(os.Stderr, "Warning: could not save search index: %v\n", err) }
+
+    results := idx.Search(query, opts)
+    if *asJSON {
+        enc := json.NewEncoder(os.Stdout)
+        enc.SetIndent("", "  ")
+        _ = enc.Encode(results)
+        return 0
+    }
+    if len(results) == 0 {
+        fmt.Println("No matches.")
+        return 0
+    }
+    for _, r := range results {
+        where := r.Field
+        if r.Role != "" { where = r.Role }
+        fmt.Printf("%.2f  %s [%s]  %s %s\n", r.Score, r.ThreadTitle, r.ThreadID, where, r.CreatedAt.Format("2006-01-02 15:04"))
+        fmt.Printf("      %s\n", r.Snippet)
+    }
+    return 0
+}
+
+// parseDate parses YYYY-MM-DD; endOfDay moves the result to the last instant of that day
+func parseDate(s string, endOfDay bool) (time.Time, error) {
+    if s == "" { return time.Time{}, nil }
+    t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+    if err != nil { return t, err }
+    if endOfDay { t = t.Add(24*time.Hour - time.Nanosecond) }
+    return t, nil
+}
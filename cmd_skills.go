@@ -0,0 +1,58 @@
+package main
+
+import (
+    "encoding/json"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+
+    "github.com/pradord/llm/pkg/skills"
+    "github.com/pradord/llm/pkg/tools"
+)
+
+// runSkills implements `llm skills <list|schema> [flags] [name]`. schema prints a
+// skill's inputs as JSON Schema, e.g. for building a form.
+func runSkills(args []string) int {
+    if len(args) == 0 {
+        fmt.Fprintln(os.Stderr, "Usage: llm skills <list|schema> [flags] [name]")
+        return 2
+    }
+    cmd := args[0]
+    fs := flag.NewFlagSet("skills "+cmd, flag.ContinueOnError)
+    defsDir := fs.String("defs-dir", "", "Path to defs directory containing skills/ YAML")
+    if err := fs.Parse(args[1:]); err != nil { return 2 }
+
+    reg := skills.NewSkillRegistry(tools.NewToolRegistry())
+    for _, s := range skills.GetAllBuiltInSkills() { _ = reg.Register(s) }
+    if *defsDir != "" {
+        if err := skills.LoadSkillsDir(filepath.Join(*defsDir, "skills"), reg); err != nil {
+            fmt.Fprintf(os.Stderr, "load skills: %v\n", err)
+            return 1
+        }
+    }
+
+    switch cmd {
+    case "list":
+        list := reg.List()
+        sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+        for _, s := range list {
+            names := make([]string, 0, len(s.Inputs))
+            for _, in := range s.Inputs { names = append(names, in.Name) }
+            fmt.Printf("%-24s %-30v %s\n", s.Name, names, s.Description)
+        }
+    case "schema":
+        if fs.NArg() != 1 { fmt.Fprintln(os.Stderr, "Usage: llm skills schema [flags] <name>"); return 2 }
+        s, ok := reg.Get(fs.Arg(0))
+        if !ok { fmt.Fprintf(os.Stderr, "unknown skill %q\n", fs.Arg(0)); return 1 }
+        schema := s.InputSchema()
+        if schema == nil { schema = map[string]interface{}{"type": "object"} }
+        data, _ := json.MarshalIndent(schema, "", "  ")
+        fmt.Println(string(data))
+    default:
+        fmt.Fprintf(os.Stderr, "skills: unknown command %q (want list or schema)\n", cmd)
+        return 2
+    }
+    return 0
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+    "fmt"
+    "os"
+
+    "github.com/pradord/llm/pkg/config"
+    "github.com/pradord/llm/pkg/conversation"
+    "github.com/pradord/llm/pkg/envelope"
+)
+
+// storeKey returns the encryption key configured in the config file or env
+func storeKey(configPath string) (*envelope.Key, error) {
+    cfg, err := config.Load(configPath)
+    if err != nil { return nil, err }
+    key, err := cfg.Persistence.Key()
+    if err != nil { return nil, fmt.Errorf("encryption key: %w", err) }
+    return key, nil
+}
+
+// openThreadStore opens the thread directory used by the subcommands, with the
+// search index registered as a listener; commands that write call Save on it
+func openThreadStore(dir, configPath string) (*conversation.FileStore, *conversation.SearchIndex, error) {
+    key, err := storeKey(configPath)
+    if err != nil { return nil, nil, err }
+    store, err := conversation.NewFileStoreWithConfig(dir, conversation.FileStoreConfig{Key: key})
+    if err != nil { return nil, nil, fmt.Errorf("open threads: %w", err) }
+    idx, err := openSearchIndex(dir, key)
+    if err != nil { return nil, nil, err }
+    store.AddListener(idx)
+    return store, idx, nil
+}
+
+// openSearchIndex loads the CLI search index for a thread directory; an
+// unreadable index is dropped and rebuilt by the next Sync
+func openSearchIndex(dir string, key *envelope.Key) (*conversation.SearchIndex, error) {
+    path := conversation.SearchIndexPath(dir)
+    idx, err := conversation.OpenSearchIndexWithKey(path, key)
+    if err == nil { return idx, nil }
+    _ = os.Remove(path)
+    if idx, err = conversation.OpenSearchIndexWithKey(path, key); err != nil { return nil, fmt.Errorf("open search index: %w", err) }
+    return idx, nil
+}
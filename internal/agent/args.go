@@ -0,0 +1,75 @@
+package agent
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strings"
+
+    "github.com/pradord/llm/internal/jsonschema"
+    "github.com/pradord/llm/internal/tools"
+)
+
+// ArgumentError is returned to the model, as the tool result, when a call's
+// arguments do not match the tool's parameters, so it can correct the call
+type ArgumentError struct {
+    Tool     string
+    Problems []jsonschema.Problem
+}
+
+func (e *ArgumentError) Error() string {
+    parts := make([]string, len(e.Problems))
+    for i, p := range e.Problems { parts[i] = p.String() }
+    return fmt.Sprintf("invalid arguments for %s: %s", e.Tool, strings.Join(parts, "; "))
+}
+
+// ToolResult is the structured error message for the model
+func (e *ArgumentError) ToolResult() string {
+    out, _ := json.Marshal(map[string]interface{}{
+        "error":    "invalid_arguments",
+        "tool":     e.Tool,
+        "problems": e.Problems,
+        "hint":     "Fix the arguments to match the tool's parameters and call it again.",
+    })
+    return string(out)
+}
+
+// ExecuteTool runs t the way the agent loop does: arguments are checked
+// against its parameters first, and an *ArgumentError is returned, without
+// running the tool, when they do not match
+func ExecuteTool(ctx context.Context, t tools.Tool, raw string) (string, error) {
+    args, err := toolArgs(t, raw)
+    if err != nil { return "", err }
+    return t.Execute(ctx, args)
+}
+
+// toolArgs decodes model-supplied arguments, fills in the defaults of
+// t.Parameters() and validates them against it. Tools whose parameters are
+// not a JSON Schema object get the decoded arguments unchecked.
+func toolArgs(t tools.Tool, raw string) (json.RawMessage, error) {
+    if strings.TrimSpace(raw) == "" { raw = "{}" }
+    var args interface{}
+    if err := json.Unmarshal([]byte(raw), &args); err != nil {
+        return nil, &ArgumentError{Tool: t.Name(), Problems: []jsonschema.Problem{{Message: "arguments are not valid JSON: " + err.Error()}}}
+    }
+    if schema := paramSchema(t); schema != nil {
+        args = jsonschema.ApplyDefaults(schema, args)
+        if err := jsonschema.Validate(schema, args); err != nil {
+            var ve *jsonschema.ValidationError
+            if errors.As(err, &ve) { return nil, &ArgumentError{Tool: t.Name(), Problems: ve.Problems} }
+            return nil, err
+        }
+    }
+    return json.Marshal(args)
+}
+
+// paramSchema returns a tool's parameters as decoded JSON, whatever Go types
+// the tool declares them with
+func paramSchema(t tools.Tool) map[string]interface{} {
+    b, err := json.Marshal(t.Parameters())
+    if err != nil { return nil }
+    var schema map[string]interface{}
+    if json.Unmarshal(b, &schema) != nil || len(schema) == 0 { return nil }
+    return schema
+}
@@ -0,0 +1,137 @@
+package agent
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/pradord/llm/internal/llm"
+)
+
+// ChatRequest is one model call of a run
+type ChatRequest struct {
+    Messages    []map[string]interface{}
+    Tools       []llm.ToolFunction
+    Model       llm.Model // empty for the client's default
+    Temperature float64
+    Schema      map[string]interface{} // JSON Schema the answer must match, sent as response_format json_schema; nil for free text
+}
+
+// ChatResponse is a model's reply
+type ChatResponse struct {
+    Content   string
+    ToolCalls []llm.ToolCall
+    Usage     *Usage // nil when the provider reports none
+}
+
+// Usage is the token count a provider reports for one reply
+type Usage struct {
+    PromptTokens     int `json:"prompt_tokens"`
+    CompletionTokens int `json:"completion_tokens"`
+    TotalTokens      int `json:"total_tokens"`
+}
+
+// CompletionClient is a ChatClient that takes whole requests, so replies can be
+// constrained to a JSON Schema natively and the provider's token usage comes
+// back. APIClient is one.
+type CompletionClient interface {
+    ChatClient
+    Complete(ctx context.Context, req *ChatRequest) (*ChatResponse, error)
+}
+
+// Complete sends req through client, natively when it is a CompletionClient.
+// Other clients get ChatWithTools: the schema then reaches the model only
+// through the prompt, and Usage is nil.
+func Complete(ctx context.Context, client ChatClient, req *ChatRequest) (*ChatResponse, error) {
+    if cc, ok := client.(CompletionClient); ok { return cc.Complete(ctx, req) }
+    var opts []llm.Option
+    if req.Model != "" { opts = append(opts, llm.WithModel(req.Model)) }
+    opts = append(opts, llm.WithTemperature(req.Temperature))
+    content, calls, err := client.ChatWithTools(ctx, req.Messages, req.Tools, opts...)
+    if err != nil { return nil, err }
+    return &ChatResponse{Content: content, ToolCalls: calls}, nil
+}
+
+// APIConfig configures an APIClient; the fields are those of the llm config section
+type APIConfig struct {
+    APIKey         string
+    BaseURL        string // default https://openrouter.ai/api/v1
+    DefaultModel   llm.Model
+    TimeoutSeconds int // default 60
+    MaxRetries     int // retries of rate-limited and failed requests
+}
+
+// APIClient calls an OpenAI-compatible chat completions endpoint directly for
+// what *llm.Client does not expose: response_format json_schema and token
+// usage. Other calls go to the wrapped *llm.Client.
+type APIClient struct {
+    *llm.Client
+    cfg  APIConfig
+    http *http.Client
+}
+
+func NewAPIClient(client *llm.Client, cfg APIConfig) *APIClient {
+    if cfg.BaseURL == "" { cfg.BaseURL = "https://openrouter.ai/api/v1" }
+    if cfg.TimeoutSeconds <= 0 { cfg.TimeoutSeconds = 60 }
+    return &APIClient{Client: client, cfg: cfg, http: &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second}}
+}
+
+func (c *APIClient) Complete(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+    model := req.Model
+    if model == "" { model = c.cfg.DefaultModel }
+    body := map[string]interface{}{"model": model, "messages": req.Messages, "temperature": req.Temperature}
+    if len(req.Tools) > 0 { body["tools"] = req.Tools }
+    if req.Schema != nil {
+        body["response_format"] = map[string]interface{}{
+            "type":        "json_schema",
+            "json_schema": map[string]interface{}{"name": "answer", "schema": req.Schema},
+        }
+    }
+    data, err := json.Marshal(body)
+    if err != nil { return nil, err }
+    for attempt := 0; ; attempt++ {
+        resp, retry, err := c.post(ctx, data)
+        if err == nil || !retry || attempt >= c.cfg.MaxRetries { return resp, err }
+        select {
+        case <-time.After(time.Duration(1<<attempt) * 500 * time.Millisecond):
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        }
+    }
+}
+
+// post makes one request; retry reports whether a failure may pass on its own
+func (c *APIClient) post(ctx context.Context, data []byte) (*ChatResponse, bool, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.cfg.BaseURL, "/")+"/chat/completions", bytes.NewReader(data))
+    if err != nil { return nil, false, err }
+    req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := c.http.Do(req)
+    if err != nil { return nil, ctx.Err() == nil, fmt.Errorf("chat completions: %w", err) }
+    defer resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+        return nil, retry, fmt.Errorf("chat completions: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+    }
+    var out struct {
+        Choices []struct {
+            Message struct {
+                Content   *string        `json:"content"`
+                ToolCalls []llm.ToolCall `json:"tool_calls"`
+            } `json:"message"`
+        } `json:"choices"`
+        Usage *Usage `json:"usage"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil { return nil, false, fmt.Errorf("chat completions: invalid response: %w", err) }
+    if len(out.Choices) == 0 { return nil, false, fmt.Errorf("chat completions: no choices returned") }
+    msg := out.Choices[0].Message
+    r := &ChatResponse{ToolCalls: msg.ToolCalls, Usage: out.Usage}
+    if msg.Content != nil { r.Content = *msg.Content }
+    return r, false, nil
+}
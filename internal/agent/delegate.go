@@ -0,0 +1,134 @@
+package agent
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "sync"
+
+    "github.com/pradord/llm/internal/llm"
+    "github.com/pradord/llm/internal/skills"
+    "github.com/pradord/llm/internal/tools"
+)
+
+// Delegation: a skill calls each of its RequiredSkills through a skill_<name>
+// tool. The sub-skill runs its own loop with its own model and tools and its
+// final answer becomes the tool result. All runs started from one top-level run
+// share a step budget, and nesting is limited by the executor's max depth. When
+// the top-level run came from RunSkill, sub-skills are resolved from the same
+// RunSetup and their tools are limited to those of the run that delegates.
+
+const (
+    defaultMaxDepth        = 2 // a skill may delegate, and its delegates may delegate once more
+    defaultStepBudgetRatio = 4 // the shared step budget is this many times the top-level max steps
+)
+
+type runState struct {
+    depth   int
+    mu      *sync.Mutex
+    steps   *int            // model calls left across the whole delegation tree
+    setup   *RunSetup       // nil when the run did not come from RunSkill
+    allowed map[string]bool // tools of the current run, which bound its delegates'
+}
+
+type runStateKey struct{}
+
+func stateFrom(ctx context.Context) (runState, bool) {
+    s, ok := ctx.Value(runStateKey{}).(runState)
+    return s, ok
+}
+
+// SetDelegationLimits bounds delegation: maxDepth levels of sub-skills and
+// totalSteps model calls across a top-level run and all its sub-runs. Zero
+// keeps the defaults (depth 2, four times the run's max steps).
+func (e *Executor) SetDelegationLimits(maxDepth, totalSteps int) {
+    e.maxDepth, e.totalSteps = maxDepth, totalSteps
+}
+
+// withRunState returns ctx carrying the shared delegation state, creating it for
+// a top-level run
+func (e *Executor) withRunState(ctx context.Context, maxSteps int) context.Context {
+    if _, ok := stateFrom(ctx); ok { return ctx }
+    total := e.totalSteps
+    if total <= 0 { total = defaultStepBudgetRatio * maxSteps }
+    return context.WithValue(ctx, runStateKey{}, runState{mu: &sync.Mutex{}, steps: &total})
+}
+
+func (e *Executor) depthLimit() int {
+    if e.maxDepth > 0 { return e.maxDepth }
+    return defaultMaxDepth
+}
+
+// takeStep spends one step of the shared budget
+func takeStep(ctx context.Context) bool {
+    s, ok := stateFrom(ctx)
+    if !ok { return true }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if *s.steps <= 0 { return false }
+    *s.steps--
+    return true
+}
+
+// DelegationTools returns a skill_<name> tool for each of skill's RequiredSkills
+// found in reg
+func (e *Executor) DelegationTools(reg *skills.SkillRegistry, skill *skills.Skill) []tools.Tool {
+    var out []tools.Tool
+    for _, name := range skill.RequiredSkills {
+        if sub, ok := reg.Get(name); ok { out = append(out, &SkillTool{exec: e, reg: reg, skill: sub}) }
+    }
+    return out
+}
+
+// SkillTool runs a skill as a sub-agent
+type SkillTool struct {
+    exec  *Executor
+    reg   *skills.SkillRegistry
+    skill *skills.Skill
+}
+
+func (t *SkillTool) Name() string { return "skill_" + t.skill.Name }
+
+func (t *SkillTool) Description() string {
+    return fmt.Sprintf("Delegate a self-contained task to the %s skill (%s). It works on its own and returns its final answer.", t.skill.Name, strings.TrimSuffix(t.skill.Description, "."))
+}
+
+func (t *SkillTool) Parameters() interface{} {
+    return map[string]interface{}{
+        "type": "object",
+        "properties": map[string]interface{}{
+            "task": map[string]interface{}{"type": "string", "description": "Complete instructions for the task, including any context the skill needs"},
+        },
+        "required": []string{"task"},
+    }
+}
+
+func (t *SkillTool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+    var p struct{ Task string `json:"task"` }
+    if err := json.Unmarshal(args, &p); err != nil { return "", err }
+    if strings.TrimSpace(p.Task) == "" { return "", fmt.Errorf("task is required") }
+    state, _ := stateFrom(ctx)
+    maxDepth := t.exec.depthLimit()
+    if state.depth >= maxDepth { return "", fmt.Errorf("delegation depth limit (%d) reached; do the task yourself", maxDepth) }
+    if state.mu != nil {
+        state.depth++
+        ctx = context.WithValue(ctx, runStateKey{}, state)
+    }
+
+    var res *Result
+    var err error
+    if state.setup != nil {
+        res, err = t.exec.runSkill(ctx, state.setup, t.reg, t.skill, p.Task, state.allowed)
+    } else {
+        var toolList []tools.Tool
+        if toolList, err = t.reg.GetTools(t.skill.Name); err != nil { return "", err }
+        if state.depth < maxDepth { toolList = append(toolList, t.exec.DelegationTools(t.reg, t.skill)...) }
+        res, err = t.exec.RunWithResult(ctx, t.skill, p.Task, toolList)
+    }
+    if err != nil { return "", fmt.Errorf("skill %s: %w", t.skill.Name, err) }
+    return res.Output, nil
+}
+
+func (t *SkillTool) RequiredModel() llm.Model  { return t.skill.DefaultModel }
+func (t *SkillTool) ModelType() llm.ModelType { return llm.ModelTypeText }
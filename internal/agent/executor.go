@@ -3,6 +3,7 @@ package agent
 import (
     "context"
     "encoding/json"
+    "errors"
     "fmt"
     "strings"
     "time"
@@ -15,16 +16,38 @@ import (
 // Message represents a turn in the agent conversation
 // We keep a light wrapper but operate primarily on OpenAI-compatible messages
 
+// ChatClient is the part of *llm.Client the loop uses. Other implementations,
+// such as scripted clients in evals or an APIClient, can be passed to
+// NewExecutorWithClient; CompletionClients are sent whole requests.
+type ChatClient interface {
+    ChatWithTools(ctx context.Context, messages []map[string]interface{}, tools []llm.ToolFunction, opts ...llm.Option) (string, []llm.ToolCall, error)
+}
+
+// Built-in run defaults, used by NewExecutor and DefaultsLayer
+const (
+    defaultMaxSteps    = 6
+    defaultTemperature = 0.2
+    defaultMaxChars    = 24000
+)
+
 // Executor coordinates a multi-step tool-using loop with an LLM
 type Executor struct {
-    client       *llm.Client
+    client       ChatClient
     maxSteps     int
     temperature  float64
     maxChars     int // soft budget on total characters across messages
+    maxDepth     int // delegation nesting limit; see SetDelegationLimits
+    totalSteps   int // step budget shared with delegated runs
+    resources    *ResourceLoader
 }
 
 func NewExecutor(client *llm.Client) *Executor {
-    return &Executor{client: client, maxSteps: 6, temperature: 0.2, maxChars: 24000}
+    return NewExecutorWithClient(client)
+}
+
+// NewExecutorWithClient is NewExecutor for any ChatClient
+func NewExecutorWithClient(client ChatClient) *Executor {
+    return &Executor{client: client, maxSteps: defaultMaxSteps, temperature: defaultTemperature, maxChars: defaultMaxChars, resources: NewResourceLoader(nil)}
 }
 
 func NewExecutorWithConfig(client *llm.Client, maxSteps int, maxChars int, temperature float64) *Executor {
@@ -35,29 +58,115 @@ func NewExecutorWithConfig(client *llm.Client, maxSteps int, maxChars int, tempe
     return e
 }
 
+// Result is the outcome of an agent run together with its full transcript
+type Result struct {
+    Output           string
+    Messages         []map[string]interface{} // OpenAI-style messages including the system prompt
+    Preamble         int                      // leading Messages (system prompt, few-shot examples) that are not part of the conversation
+    Model            llm.Model
+    Steps            int
+    Value            interface{} // decoded JSON when the skill has an OutputSchema
+    Retries          int         // re-prompts needed to get valid structured output
+    InvalidToolCalls int         // tool calls rejected because their arguments did not match the tool's parameters
+    Usage            map[int]*Usage // provider token counts of assistant replies, by index in Messages; empty unless the client is a CompletionClient
+}
+
 // Run executes a skill with iterative tool-calling
 // Protocol: model can respond with final text, or JSON {"tool":"name","args":{...}}
 func (e *Executor) Run(ctx context.Context, skill *skills.Skill, userPrompt string, toolList []tools.Tool) (string, error) {
-    // System context with tool descriptions
+    res, err := e.RunWithResult(ctx, skill, userPrompt, toolList)
+    if err != nil { return "", err }
+    return res.Output, nil
+}
+
+// RunWithResult is Run but also returns the transcript so callers can persist it
+func (e *Executor) RunWithResult(ctx context.Context, skill *skills.Skill, userPrompt string, toolList []tools.Tool) (*Result, error) {
+    // Build message array per OpenAI schema
+    messages := e.preamble(ctx, skill, toolList)
+    p := e.params(skill)
+    p.preamble = len(messages)
+    messages = append(messages, map[string]interface{}{"role": "user", "content": userPrompt})
+    return e.loop(ctx, p, messages, toolList)
+}
+
+// RunResolved is RunWithResult with model, temperature and budgets taken from
+// resolved settings (see Resolve) instead of the executor and skill. The caller
+// picks toolList from r.Tools.
+func (e *Executor) RunResolved(ctx context.Context, skill *skills.Skill, userPrompt string, toolList []tools.Tool, r *Resolved) (*Result, error) {
+    messages := e.preamble(ctx, skill, toolList)
+    p := e.params(skill)
+    p.preamble = len(messages)
+    messages = append(messages, map[string]interface{}{"role": "user", "content": userPrompt})
+    if r.Model != "" { p.model = r.Model }
+    if r.MaxSteps > 0 { p.maxSteps = r.MaxSteps }
+    if r.MaxChars > 0 { p.maxChars = r.MaxChars }
+    p.temperature = r.Temperature
+    return e.loop(ctx, p, messages, toolList)
+}
+
+// runParams are the settings of one run
+type runParams struct {
+    model       llm.Model
+    temperature float64
+    maxSteps    int
+    maxChars    int
+    schema      map[string]interface{} // skill.OutputSchema
+    retries     int                    // re-prompts after invalid structured output
+    preamble    int                    // see Result.Preamble
+}
+
+func (e *Executor) params(skill *skills.Skill) runParams {
+    p := runParams{model: skill.DefaultModel, temperature: e.temperature, maxSteps: e.maxSteps, maxChars: e.maxChars, schema: skill.OutputSchema, retries: skill.OutputRetries}
+    if p.retries == 0 { p.retries = 2 } else if p.retries < 0 { p.retries = 0 }
+    return p
+}
+
+// Resume continues a run from a stored transcript, e.g. conversation.Thread.OpenAIMessages().
+// The skill's system prompt and examples are prepended when history does not start with one.
+// History should end with a user or tool message for the model to answer.
+func (e *Executor) Resume(ctx context.Context, skill *skills.Skill, history []map[string]interface{}, toolList []tools.Tool) (*Result, error) {
+    var messages []map[string]interface{}
+    if len(history) == 0 || history[0]["role"] != "system" {
+        messages = e.preamble(ctx, skill, toolList)
+    }
+    p := e.params(skill)
+    p.preamble = len(messages)
+    messages = append(messages, history...)
+    return e.loop(ctx, p, messages, toolList)
+}
+
+// systemPrompt builds the system context with tool descriptions
+func (e *Executor) systemPrompt(skill *skills.Skill, toolList []tools.Tool) string {
     var b strings.Builder
     b.WriteString(skill.SystemPrompt)
-    b.WriteString("\n\nYou can use tools to complete the task. If you choose to use a tool, respond ONLY with JSON in the form {\"tool\":\"name\",\"args\":{...}}. Otherwise, reply with the final answer in plain text.\n")
+    b.WriteString("\n\nYou can use tools to complete the task. If you choose to use a tool, respond ONLY with JSON in the form {\"tool\":\"name\",\"args\":{...}}.")
+    if skill.OutputSchema != nil {
+        b.WriteString("\n" + outputInstructions(skill.OutputSchema))
+    } else {
+        b.WriteString(" Otherwise, reply with the final answer in plain text.\n")
+    }
     b.WriteString("\nAvailable tools:\n")
     for _, t := range toolList {
         paramsJSON, _ := json.Marshal(t.Parameters())
         fmt.Fprintf(&b, "- %s: %s\n  parameters: %s\n", t.Name(), t.Description(), string(paramsJSON))
     }
+    return b.String()
+}
 
-    // Build message array per OpenAI schema
-    messages := []map[string]interface{}{
-        {"role": "system", "content": b.String()},
-        {"role": "user", "content": userPrompt},
+func (e *Executor) loop(ctx context.Context, p runParams, messages []map[string]interface{}, toolList []tools.Tool) (*Result, error) {
+    res := &Result{Model: p.model, Preamble: p.preamble}
+    finish := func(out string) (*Result, error) {
+        res.Output = out
+        res.Messages = messages
+        return res, nil
     }
-
-    for step := 0; step < e.maxSteps; step++ {
+    ctx = e.withRunState(ctx, p.maxSteps)
+    for step := 0; step < p.maxSteps; step++ {
+        if !takeStep(ctx) { return finish("Shared step budget exhausted before completion.") }
+        res.Steps = step + 1
         // Enforce soft character budget
-        if e.totalChars(messages) > e.maxChars {
-            return "Context budget exceeded before completion.", nil
+        if e.totalChars(messages) > p.maxChars {
+            return finish("Context budget exceeded before completion.")
         }
         // Build tool schemas
         var toolSchemas []llm.ToolFunction
@@ -71,14 +180,28 @@ func (e *Executor) Run(ctx context.Context, skill *skills.Skill, userPrompt stri
                 },
             })
         }
-        content, calls, err := e.client.ChatWithTools(ctx, messages, toolSchemas, llm.WithModel(skill.DefaultModel), llm.WithTemperature(e.temperature))
-        if err != nil { return "", err }
+        reply, err := Complete(ctx, e.client, &ChatRequest{Messages: messages, Tools: toolSchemas, Model: p.model, Temperature: p.temperature, Schema: p.schema})
+        if err != nil { return nil, err }
+        content, calls := reply.Content, reply.ToolCalls
 
         if len(calls) == 0 {
             // Final answer
-            if content == "" { return "", fmt.Errorf("model returned empty response") }
+            if content == "" { return nil, fmt.Errorf("model returned empty response") }
             messages = append(messages, map[string]interface{}{"role": "assistant", "content": content})
-            return content, nil
+            res.addUsage(len(messages)-1, reply.Usage)
+            if p.schema != nil {
+                // Structured output: validate and ask for a correction on failure
+                out, value, err := decodeOutput(content, p.schema)
+                if err != nil {
+                    if res.Retries >= p.retries { return nil, &OutputError{Output: content, Err: err} }
+                    res.Retries++
+                    messages = append(messages, map[string]interface{}{"role": "user", "content": retryPrompt(err)})
+                    continue
+                }
+                res.Value = value
+                content = out
+            }
+            return finish(content)
         }
         // Append assistant message with tool_calls (content may be empty)
         // Convert toolCalls to schema array
@@ -98,6 +221,7 @@ func (e *Executor) Run(ctx context.Context, skill *skills.Skill, userPrompt stri
             "content":    content,
             "tool_calls": toolCallsAny,
         })
+        res.addUsage(len(messages)-1, reply.Usage)
 
         // Execute tool calls in order
         for _, c := range calls {
@@ -109,12 +233,17 @@ func (e *Executor) Run(ctx context.Context, skill *skills.Skill, userPrompt stri
                 messages = append(messages, map[string]interface{}{"role": "tool", "tool_call_id": c.ID, "content": fmt.Sprintf("tool not found: %s", c.Function.Name)})
                 continue
             }
-            // Parse args JSON from string
-            var argMap map[string]interface{}
-            _ = json.Unmarshal([]byte(c.Function.Arguments), &argMap)
-            argsBuf, _ := json.Marshal(argMap)
-            toolOut, err := selected.Execute(ctx, argsBuf)
-            if err != nil { toolOut = fmt.Sprintf("tool error: %v", err) }
+            // Check the arguments against the tool's parameters; the model gets
+            // the problems back to correct the call
+            toolOut, err := ExecuteTool(ctx, selected, c.Function.Arguments)
+            var argErr *ArgumentError
+            switch {
+            case errors.As(err, &argErr):
+                res.InvalidToolCalls++
+                toolOut = argErr.ToolResult()
+            case err != nil:
+                toolOut = fmt.Sprintf("tool error: %v", err)
+            }
             // Append tool result with tool_call_id per spec
             messages = append(messages, map[string]interface{}{
                 "role":         "tool",
@@ -125,7 +254,13 @@ func (e *Executor) Run(ctx context.Context, skill *skills.Skill, userPrompt stri
         }
         // Loop for next step
     }
-    return "Max steps reached without final answer.", nil
+    return finish("Max steps reached without final answer.")
+}
+
+func (r *Result) addUsage(i int, u *Usage) {
+    if u == nil { return }
+    if r.Usage == nil { r.Usage = map[int]*Usage{} }
+    r.Usage[i] = u
 }
 
 func (e *Executor) totalChars(msgs []map[string]interface{}) int {
@@ -142,7 +277,10 @@ func (e *Executor) Chat(ctx context.Context, messages []map[string]interface{},
     return content, err
 }
 
-// GetClient returns the underlying LLM client
+// GetClient returns the underlying LLM client, or nil when the executor was
+// created with another ChatClient
 func (e *Executor) GetClient() *llm.Client {
-    return e.client
+    if a, ok := e.client.(*APIClient); ok { return a.Client }
+    c, _ := e.client.(*llm.Client)
+    return c
 }
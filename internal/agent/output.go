@@ -0,0 +1,81 @@
+package agent
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "strings"
+
+    "github.com/pradord/llm/internal/jsonschema"
+    "github.com/pradord/llm/internal/skills"
+    "github.com/pradord/llm/internal/tools"
+)
+
+// OutputError is returned when a skill with an OutputSchema keeps answering
+// with JSON that does not match it
+type OutputError struct {
+    Output string // last answer
+    Err    error  // why it was rejected; a *jsonschema.ValidationError or a JSON syntax error
+}
+
+func (e *OutputError) Error() string { return "structured output: " + e.Err.Error() }
+func (e *OutputError) Unwrap() error { return e.Err }
+
+func outputInstructions(schema map[string]interface{}) string {
+    s, _ := json.MarshalIndent(schema, "", "  ")
+    return "\nYour final answer must be a single JSON value, with no other text or code fences, that matches this JSON Schema:\n" + string(s) + "\n"
+}
+
+func retryPrompt(err error) string {
+    return "Your answer was rejected: " + err.Error() + ".\nReply again with only the corrected JSON."
+}
+
+// decodeOutput extracts the JSON value from an answer, tolerating code fences
+// and surrounding prose, and validates it
+func decodeOutput(content string, schema map[string]interface{}) (string, interface{}, error) {
+    text := extractJSON(content)
+    v, err := jsonschema.ValidateJSON(schema, []byte(text))
+    if err != nil { return "", nil, err }
+    return text, v, nil
+}
+
+func extractJSON(s string) string {
+    s = strings.TrimSpace(s)
+    if strings.HasPrefix(s, "```") {
+        s = s[3:]
+        // drop the info string, e.g. json or JSON, unless the value starts on the fence line
+        if i := strings.IndexAny(s, "\n{["); i >= 0 && s[i] == '\n' {
+            s = s[i+1:]
+        } else if len(s) >= 4 && strings.EqualFold(s[:4], "json") {
+            s = s[4:]
+        }
+        if i := strings.LastIndex(s, "```"); i >= 0 { s = s[:i] }
+        return strings.TrimSpace(s)
+    }
+    if json.Valid([]byte(s)) { return s }
+    // fall back to the outermost object or array
+    for _, pair := range [][2]string{{"{", "}"}, {"[", "]"}} {
+        i, j := strings.Index(s, pair[0]), strings.LastIndex(s, pair[1])
+        if i >= 0 && j > i && json.Valid([]byte(s[i:j+1])) { return s[i : j+1] }
+    }
+    return s
+}
+
+// RunTyped runs a skill and decodes its structured answer into T. When the skill
+// has no OutputSchema, one is derived from T (see jsonschema.For).
+func RunTyped[T any](ctx context.Context, e *Executor, skill *skills.Skill, userPrompt string, toolList []tools.Tool) (T, *Result, error) {
+    var out T
+    if skill.OutputSchema == nil {
+        s := *skill
+        s.OutputSchema = jsonschema.For(reflect.TypeOf((*T)(nil)).Elem())
+        skill = &s
+    }
+    res, err := e.RunWithResult(ctx, skill, userPrompt, toolList)
+    if err != nil { return out, res, err }
+    if res.Value == nil && strings.TrimSpace(res.Output) != "null" {
+        return out, res, fmt.Errorf("skill %s ended without structured output: %s", skill.Name, res.Output)
+    }
+    if err := json.Unmarshal([]byte(res.Output), &out); err != nil { return out, res, fmt.Errorf("decode %T: %w", out, err) }
+    return out, res, nil
+}
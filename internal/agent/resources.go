@@ -0,0 +1,151 @@
+package agent
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+    "unicode/utf8"
+
+    "github.com/pradord/llm/internal/skills"
+    "github.com/pradord/llm/internal/tools"
+)
+
+// ResourceLoader turns Skill.Resources into a reference section of the system
+// prompt. A resource is a file, a glob (dir/*.md, dir/**/*.go) or an http(s)
+// URL read through the fetch_url tool. Contents are cached: files until they
+// change, URLs for TTL.
+type ResourceLoader struct {
+    Fetcher  tools.Tool    // fetch_url; URL resources are skipped without it
+    MaxBytes int           // per resource (default 16 KiB)
+    MaxTotal int           // for all resources of a skill (default 64 KiB)
+    TTL      time.Duration // URL cache lifetime (default 10m)
+
+    mu    sync.Mutex
+    cache map[string]cachedResource
+}
+
+type cachedResource struct {
+    text    string
+    modTime time.Time // files
+    size    int64
+    fetched time.Time // URLs
+}
+
+func NewResourceLoader(fetcher tools.Tool) *ResourceLoader {
+    return &ResourceLoader{Fetcher: fetcher}
+}
+
+// SetResourceLoader replaces the loader used for Skill.Resources
+func (e *Executor) SetResourceLoader(l *ResourceLoader) { e.resources = l }
+
+// Section renders the resources of skill, or "" when it has none
+func (l *ResourceLoader) Section(ctx context.Context, skill *skills.Skill) string {
+    if len(skill.Resources) == 0 { return "" }
+    maxBytes, maxTotal := l.MaxBytes, l.MaxTotal
+    if maxBytes <= 0 { maxBytes = 16 << 10 }
+    if maxTotal <= 0 { maxTotal = 64 << 10 }
+
+    var b strings.Builder
+    b.WriteString("\n\nReference material:\n")
+    total, omitted := 0, 0
+    for _, res := range skill.Resources {
+        items, err := l.expand(res)
+        if err != nil { fmt.Fprintf(&b, "\n--- %s (unavailable: %v)\n", res, err); continue }
+        for _, name := range items {
+            if total >= maxTotal { omitted++; continue }
+            text, err := l.load(ctx, name)
+            if err != nil { fmt.Fprintf(&b, "\n--- %s (unavailable: %v)\n", name, err); continue }
+            limit := maxBytes
+            if rest := maxTotal - total; rest < limit { limit = rest }
+            text = clip(text, limit)
+            total += len(text)
+            fmt.Fprintf(&b, "\n--- %s\n%s\n", name, strings.TrimRight(text, "\n"))
+        }
+    }
+    if omitted > 0 { fmt.Fprintf(&b, "\n(%d more resources omitted to stay within the size limit)\n", omitted) }
+    return b.String()
+}
+
+func isURL(s string) bool { return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") }
+
+// expand resolves globs to file names; URLs and plain paths pass through
+func (l *ResourceLoader) expand(res string) ([]string, error) {
+    if isURL(res) || !strings.ContainsAny(res, "*?[") { return []string{res}, nil }
+    var out []string
+    if root, rest, ok := strings.Cut(filepath.ToSlash(res), "/**/"); ok {
+        err := filepath.WalkDir(filepath.FromSlash(root), func(path string, d fs.DirEntry, err error) error {
+            if err != nil { return err }
+            if d.IsDir() { return nil }
+            if m, _ := filepath.Match(rest, d.Name()); m { out = append(out, path) }
+            return nil
+        })
+        if err != nil { return nil, err }
+    } else {
+        matches, err := filepath.Glob(res)
+        if err != nil { return nil, err }
+        out = matches
+    }
+    if len(out) == 0 { return nil, fmt.Errorf("no files match") }
+    return out, nil
+}
+
+func (l *ResourceLoader) load(ctx context.Context, name string) (string, error) {
+    l.mu.Lock()
+    if l.cache == nil { l.cache = map[string]cachedResource{} }
+    c, ok := l.cache[name]
+    l.mu.Unlock()
+
+    var fresh cachedResource
+    if isURL(name) {
+        ttl := l.TTL
+        if ttl <= 0 { ttl = 10 * time.Minute }
+        if ok && time.Since(c.fetched) < ttl { return c.text, nil }
+        if l.Fetcher == nil { return "", fmt.Errorf("no fetch_url tool configured") }
+        args, _ := json.Marshal(map[string]string{"url": name})
+        text, err := l.Fetcher.Execute(ctx, args)
+        if err != nil { return "", err }
+        fresh = cachedResource{text: text, fetched: time.Now()}
+    } else {
+        info, err := os.Stat(name)
+        if err != nil { return "", err }
+        if ok && c.modTime.Equal(info.ModTime()) && c.size == info.Size() { return c.text, nil }
+        data, err := os.ReadFile(name)
+        if err != nil { return "", err }
+        if !utf8.Valid(data) { return "", fmt.Errorf("not a text file") }
+        fresh = cachedResource{text: string(data), modTime: info.ModTime(), size: info.Size()}
+    }
+    l.mu.Lock()
+    l.cache[name] = fresh
+    l.mu.Unlock()
+    return fresh.text, nil
+}
+
+// clip cuts s to at most n bytes on a rune boundary
+func clip(s string, n int) string {
+    if len(s) <= n { return s }
+    const note = "\n[truncated]"
+    n -= len(note)
+    if n < 0 { n = 0 }
+    for n > 0 && !utf8.RuneStart(s[n]) { n-- }
+    return s[:n] + note
+}
+
+// preamble is the start of every run: the system prompt followed by the
+// skill's few-shot examples as user/assistant turns
+func (e *Executor) preamble(ctx context.Context, skill *skills.Skill, toolList []tools.Tool) []map[string]interface{} {
+    system := e.systemPrompt(skill, toolList)
+    if e.resources != nil { system += e.resources.Section(ctx, skill) }
+    out := []map[string]interface{}{{"role": "system", "content": system}}
+    for _, ex := range skill.FewShot {
+        out = append(out,
+            map[string]interface{}{"role": "user", "content": ex.Input},
+            map[string]interface{}{"role": "assistant", "content": ex.Output})
+    }
+    return out
+}
@@ -0,0 +1,154 @@
+package agent
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/pradord/llm/internal/config"
+    "github.com/pradord/llm/internal/llm"
+    "github.com/pradord/llm/internal/project"
+    "github.com/pradord/llm/internal/skills"
+)
+
+// Run settings are resolved from layers, lowest first:
+//
+//   defaults < config < project < skill < call
+//
+// A layer sets only the values it has; zero values (nil Temperature, nil Tools)
+// leave the value of the layers below. Tools are an allow-list that can only
+// narrow: every layer that lists tools removes those not in its list.
+
+const (
+    LayerDefaults = "defaults"
+    LayerConfig   = "config"
+    LayerProject  = "project"
+    LayerSkill    = "skill"
+    LayerCall     = "call"
+)
+
+// Settings is one layer's view of the run settings
+type Settings struct {
+    Model       llm.Model
+    Temperature *float64
+    MaxSteps    int
+    MaxChars    int      // soft budget on total characters across messages
+    Tools       []string // allowed tools; nil allows whatever lower layers allow
+}
+
+type Layer struct {
+    Name string // e.g. LayerProject, or a more specific "project acme"
+    Settings
+}
+
+// Resolved holds the effective settings and where each came from
+type Resolved struct {
+    Model       llm.Model
+    Temperature float64
+    MaxSteps    int
+    MaxChars    int
+    Tools       []string // nil when no layer restricts tools
+    trace       map[string][]step
+}
+
+type step struct{ layer, value string }
+
+// Explanation describes one effective setting
+type Explanation struct {
+    Setting    string   `json:"setting"`
+    Value      string   `json:"value"`
+    Source     string   `json:"source"`               // name of the layer that set the value
+    Overridden []string `json:"overridden,omitempty"` // "layer: value" of lower layers, lowest first
+}
+
+var settingNames = []string{"model", "temperature", "max_steps", "max_chars", "tools"}
+
+// DefaultsLayer holds the built-in defaults, the same NewExecutor uses
+func DefaultsLayer() Layer {
+    t := defaultTemperature
+    return Layer{Name: LayerDefaults, Settings: Settings{Model: llm.ModelGPT4oMini, Temperature: &t, MaxSteps: defaultMaxSteps, MaxChars: defaultMaxChars}}
+}
+
+// ConfigLayer takes the model from the llm section and the rest from the agent section
+func ConfigLayer(c *config.Config) Layer {
+    s := Settings{Model: c.LLM.DefaultModel, MaxSteps: c.Agent.MaxSteps, MaxChars: c.Agent.MaxChars}
+    if c.Agent.Temperature > 0 { t := c.Agent.Temperature; s.Temperature = &t }
+    return Layer{Name: LayerConfig, Settings: s}
+}
+
+// ProjectLayer is empty for a nil project
+func ProjectLayer(p *project.Project) Layer {
+    if p == nil { return Layer{Name: LayerProject} }
+    return Layer{Name: LayerProject + " " + p.ID, Settings: Settings{
+        Model: llm.Model(p.DefaultModel), Temperature: p.Temperature, MaxSteps: p.MaxSteps, MaxChars: p.MaxChars, Tools: p.Tools,
+    }}
+}
+
+func SkillLayer(s *skills.Skill) Layer {
+    return Layer{Name: LayerSkill + " " + s.Name, Settings: Settings{
+        Model: s.DefaultModel, Temperature: s.Temperature, MaxSteps: s.MaxSteps, MaxChars: s.MaxChars, Tools: s.Tools,
+    }}
+}
+
+// Resolve merges layers given lowest first
+func Resolve(layers ...Layer) *Resolved {
+    r := &Resolved{trace: map[string][]step{}}
+    set := func(name, layer, value string) { r.trace[name] = append(r.trace[name], step{layer, value}) }
+    for _, l := range layers {
+        if l.Model != "" { r.Model = l.Model; set("model", l.Name, string(l.Model)) }
+        if l.Temperature != nil { r.Temperature = *l.Temperature; set("temperature", l.Name, fmt.Sprint(*l.Temperature)) }
+        if l.MaxSteps > 0 { r.MaxSteps = l.MaxSteps; set("max_steps", l.Name, fmt.Sprint(l.MaxSteps)) }
+        if l.MaxChars > 0 { r.MaxChars = l.MaxChars; set("max_chars", l.Name, fmt.Sprint(l.MaxChars)) }
+        if l.Tools != nil {
+            if r.Tools == nil {
+                r.Tools = append([]string{}, l.Tools...)
+            } else {
+                r.Tools = intersect(r.Tools, l.Tools)
+            }
+            set("tools", l.Name, formatTools(r.Tools))
+        }
+    }
+    return r
+}
+
+func intersect(have, allowed []string) []string {
+    ok := map[string]bool{}
+    for _, t := range allowed { ok[t] = true }
+    out := []string{}
+    for _, t := range have {
+        if ok[t] { out = append(out, t) }
+    }
+    return out
+}
+
+func formatTools(ts []string) string {
+    if ts == nil { return "(any)" }
+    return "[" + strings.Join(ts, ", ") + "]"
+}
+
+// Explain lists every setting with its effective value, the layer it came
+// from and the values it replaced
+func (r *Resolved) Explain() []Explanation {
+    out := make([]Explanation, 0, len(settingNames))
+    for _, name := range settingNames {
+        steps := r.trace[name]
+        e := Explanation{Setting: name, Value: formatTools(r.Tools), Source: LayerDefaults} // only tools can be left unset
+        if len(steps) > 0 {
+            last := steps[len(steps)-1]
+            e.Value, e.Source = last.value, last.layer
+            for _, s := range steps[:len(steps)-1] { e.Overridden = append(e.Overridden, s.layer+": "+s.value) }
+        }
+        out = append(out, e)
+    }
+    return out
+}
+
+// String renders Explain as an aligned table
+func (r *Resolved) String() string {
+    var b strings.Builder
+    for _, e := range r.Explain() {
+        fmt.Fprintf(&b, "%-12s %-32s from %s", e.Setting, e.Value, e.Source)
+        if len(e.Overridden) > 0 { fmt.Fprintf(&b, " (overrides %s)", strings.Join(e.Overridden, "; ")) }
+        b.WriteString("\n")
+    }
+    return b.String()
+}
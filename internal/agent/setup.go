@@ -0,0 +1,105 @@
+package agent
+
+import (
+    "context"
+
+    "github.com/pradord/llm/internal/skills"
+    "github.com/pradord/llm/internal/tools"
+)
+
+// DefaultTools are the tools a run may use when no layer restricts tools
+var DefaultTools = []string{"web_search"}
+
+// EffectiveTools is the tool allow-list of a run with settings r: r.Tools, or
+// DefaultTools when no layer restricts tools
+func EffectiveTools(r *Resolved) []string {
+    if r.Tools == nil { return append([]string(nil), DefaultTools...) }
+    return append([]string(nil), r.Tools...)
+}
+
+// RunSetup is what a run takes from outside its skill: the settings layers
+// around the skill's, the project system prompt and the tools to pick from.
+// Top-level runs, routed chain steps and delegated sub-skills are all built
+// from one RunSetup, so a project's settings, prompt and allow-list apply to
+// each of them.
+type RunSetup struct {
+    Layers []Layer // below the skill layer, lowest first: defaults, config, project
+    Call   []Layer // above the skill layer, e.g. command-line overrides
+    Prompt func(skillPrompt string) (string, error) // frames a skill's system prompt, e.g. with the project's; nil keeps it
+    Tools  *tools.ToolRegistry
+    Extra  []string // tools every run gets besides its allow-list, e.g. search_knowledge
+}
+
+// Settings resolves the settings of a run of skill, or outside any skill when
+// skill is nil
+func (s *RunSetup) Settings(skill *skills.Skill) *Resolved {
+    layers := append([]Layer(nil), s.Layers...)
+    if skill != nil { layers = append(layers, SkillLayer(skill)) }
+    return Resolve(append(layers, s.Call...)...)
+}
+
+// Skill returns a copy of skill with its system prompt framed by Prompt
+func (s *RunSetup) Skill(skill *skills.Skill) (*skills.Skill, error) {
+    sk := *skill
+    if s.Prompt == nil { return &sk, nil }
+    prompt, err := s.Prompt(sk.SystemPrompt)
+    if err != nil { return nil, err }
+    sk.SystemPrompt = prompt
+    return &sk, nil
+}
+
+// ToolNames are the tools a run with settings r may use: its allow-list plus
+// Extra
+func (s *RunSetup) ToolNames(r *Resolved) []string {
+    names := EffectiveTools(r)
+    for _, n := range s.Extra {
+        if !contains(names, n) { names = append(names, n) }
+    }
+    return names
+}
+
+// toolList looks up ToolNames in the registry, skipping tools it does not have
+// and, when bound is not nil, tools it does not include
+func (s *RunSetup) toolList(r *Resolved, bound map[string]bool) []tools.Tool {
+    var out []tools.Tool
+    if s.Tools == nil { return out }
+    for _, name := range s.ToolNames(r) {
+        if bound != nil && !bound[name] { continue }
+        if t, ok := s.Tools.Get(name); ok { out = append(out, t) }
+    }
+    return out
+}
+
+// RunSkill runs skill as setup describes: settings resolved around the skill's
+// layer, the system prompt framed by setup.Prompt, the tools those settings
+// allow and a skill_<name> tool for each of its RequiredSkills in reg. Skills it
+// delegates to are built from the same setup and only get tools this run has.
+func (e *Executor) RunSkill(ctx context.Context, setup *RunSetup, reg *skills.SkillRegistry, skill *skills.Skill, userPrompt string) (*Result, error) {
+    return e.runSkill(ctx, setup, reg, skill, userPrompt, nil)
+}
+
+func (e *Executor) runSkill(ctx context.Context, setup *RunSetup, reg *skills.SkillRegistry, skill *skills.Skill, userPrompt string, bound map[string]bool) (*Result, error) {
+    sk, err := setup.Skill(skill)
+    if err != nil { return nil, err }
+    r := setup.Settings(skill)
+    toolList := setup.toolList(r, bound)
+
+    maxSteps := r.MaxSteps
+    if maxSteps <= 0 { maxSteps = e.maxSteps }
+    ctx = e.withRunState(ctx, maxSteps)
+    state, _ := stateFrom(ctx)
+    state.setup = setup
+    state.allowed = map[string]bool{}
+    for _, t := range toolList { state.allowed[t.Name()] = true }
+    ctx = context.WithValue(ctx, runStateKey{}, state)
+
+    if state.depth < e.depthLimit() { toolList = append(toolList, e.DelegationTools(reg, skill)...) }
+    return e.RunResolved(ctx, sk, userPrompt, toolList, r)
+}
+
+func contains(list []string, s string) bool {
+    for _, v := range list {
+        if v == s { return true }
+    }
+    return false
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/pradord/llm/internal/envelope"
 	"github.com/pradord/llm/internal/llm"
 	"gopkg.in/yaml.v3"
 )
@@ -18,6 +19,7 @@ type Config struct {
     Capabilities CapabilityConfig `json:"capabilities" yaml:"capabilities"`
     Auth  AuthConfig  `json:"auth" yaml:"auth"`
     Persistence PersistenceConfig `json:"persistence" yaml:"persistence"`
+    MCP   MCPConfig   `json:"mcp" yaml:"mcp"`
     UseRealLLM bool `json:"use_real_llm" yaml:"use_real_llm"` // Toggle between mock and real OpenRouter calls
 }
 
@@ -65,6 +67,42 @@ type PersistenceConfig struct {
     SupabaseURL string `json:"supabase_url" yaml:"supabase_url"`
     SupabaseKey string `json:"supabase_key" yaml:"supabase_key"`
     VectorTable string `json:"vector_table" yaml:"vector_table"`
+    EmbeddingModel string `json:"embedding_model,omitempty" yaml:"embedding_model,omitempty"` // for project knowledge bases (default openai/text-embedding-ada-002)
+    KnowledgeDir   string `json:"knowledge_dir,omitempty" yaml:"knowledge_dir,omitempty"`     // where knowledge indexes are kept (default .llm_knowledge)
+    // Encryption at rest for threads and projects: a base64/hex 32-byte key, or a
+    // file holding one. Leave both empty to store plaintext.
+    EncryptionKey     string `json:"encryption_key,omitempty" yaml:"encryption_key,omitempty"`
+    EncryptionKeyFile string `json:"encryption_key_file,omitempty" yaml:"encryption_key_file,omitempty"`
+}
+
+// MCPConfig lists the Model Context Protocol servers whose tools are
+// registered alongside the built-in ones
+type MCPConfig struct {
+    Servers []MCPServerConfig `json:"servers" yaml:"servers"`
+}
+
+// MCPServerConfig is one MCP server, run as a subprocess (stdio) or reached
+// over streamable HTTP. Its tools are registered as <name>_<tool>.
+type MCPServerConfig struct {
+    Name           string            `json:"name" yaml:"name"`
+    Command        string            `json:"command,omitempty" yaml:"command,omitempty"` // stdio: program to run
+    Args           []string          `json:"args,omitempty" yaml:"args,omitempty"`
+    Env            map[string]string `json:"env,omitempty" yaml:"env,omitempty"`         // added to the environment; ${VAR} is expanded
+    URL            string            `json:"url,omitempty" yaml:"url,omitempty"`         // streamable HTTP endpoint
+    Headers        map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"` // e.g. Authorization; ${VAR} is expanded
+    Tools          []string          `json:"tools,omitempty" yaml:"tools,omitempty"`     // only these tools (default: all)
+    TimeoutSeconds int               `json:"timeout_seconds,omitempty" yaml:"timeout_seconds,omitempty"` // per request (default 60)
+}
+
+// Key returns the configured encryption key, or nil when encryption is off
+func (p PersistenceConfig) Key() (*envelope.Key, error) {
+    switch {
+    case p.EncryptionKey != "":
+        return envelope.ParseKey(p.EncryptionKey)
+    case p.EncryptionKeyFile != "":
+        return envelope.LoadKeyFile(p.EncryptionKeyFile)
+    }
+    return nil, nil
 }
 
 // DefaultConfig returns a config with sensible defaults
@@ -86,7 +124,7 @@ func DefaultConfig() *Config {
         },
         Capabilities: CapabilityConfig{},
         Auth: AuthConfig{Enabled: false, JWKSURL: ""},
-        Persistence: PersistenceConfig{Repo: "file", Vectors: "memory", VectorTable: "embeddings"},
+        Persistence: PersistenceConfig{Repo: "file", Vectors: "memory", VectorTable: "embeddings", KnowledgeDir: ".llm_knowledge"},
         UseRealLLM: false, // Default to mock responses
     }
 }
@@ -152,6 +190,18 @@ func (c *Config) ApplyEnv() *Config {
         c.Tools.ImageAPIKey = imageKey
     }
 
+    // Encryption key for stored threads and projects
+    if key := os.Getenv("LLM_ENCRYPTION_KEY"); key != "" {
+        c.Persistence.EncryptionKey = key
+    }
+    if keyFile := os.Getenv("LLM_ENCRYPTION_KEY_FILE"); keyFile != "" {
+        c.Persistence.EncryptionKeyFile = keyFile
+    }
+
+    if model := os.Getenv("LLM_EMBEDDING_MODEL"); model != "" {
+        c.Persistence.EmbeddingModel = model
+    }
+
     // Toggle real LLM vs mock
     if useReal := os.Getenv("USE_REAL_LLM"); useReal == "true" || useReal == "1" {
         c.UseRealLLM = true
@@ -0,0 +1,36 @@
+package conversation
+
+import (
+    "errors"
+    "fmt"
+)
+
+// ErrConflict matches (via errors.Is) any error caused by a concurrent modification
+var ErrConflict = errors.New("concurrent modification")
+
+// ConflictError is returned by UpdateThread when the thread changed since the
+// caller read it. Reload with GetThread, reapply the change and retry.
+type ConflictError struct {
+    ThreadID string
+    Expected int64 // version the caller read
+    Actual   int64 // version currently stored
+}
+
+func (e *ConflictError) Error() string {
+    return fmt.Sprintf("thread %s was modified concurrently (have version %d, stored version %d)", e.ThreadID, e.Expected, e.Actual)
+}
+
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+// IsConflict reports whether err is a concurrent modification error
+func IsConflict(err error) bool { return errors.Is(err, ErrConflict) }
+
+// RetryOnConflict calls fn up to attempts times while it fails with a conflict.
+// fn should re-read the thread on every call.
+func RetryOnConflict(attempts int, fn func() error) error {
+    var err error
+    for i := 0; i < attempts; i++ {
+        if err = fn(); !IsConflict(err) { return err }
+    }
+    return err
+}
@@ -0,0 +1,198 @@
+package conversation
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "html/template"
+    "io"
+    "strings"
+    "time"
+)
+
+// Exporters render the active branch of a thread for sharing. JSONL keeps the
+// whole message tree in the store's log format so it can be imported again.
+
+// WriteMarkdown renders the active branch as Markdown, including tool calls and
+// tool results
+func WriteMarkdown(w io.Writer, t *Thread) error {
+    var b strings.Builder
+    title := t.Title
+    if title == "" { title = "Untitled thread" }
+    fmt.Fprintf(&b, "# %s\n\n", title)
+    var meta []string
+    if t.ProjectID != "" { meta = append(meta, "project `"+t.ProjectID+"`") }
+    if !t.CreatedAt.IsZero() { meta = append(meta, "created "+t.CreatedAt.Format("2006-01-02 15:04")) }
+    meta = append(meta, fmt.Sprintf("thread `%s`", t.ID))
+    fmt.Fprintf(&b, "_%s_\n\n", strings.Join(meta, " · "))
+    if t.Summary != "" { fmt.Fprintf(&b, "> %s\n\n", strings.ReplaceAll(t.Summary, "\n", "\n> ")) }
+
+    for _, m := range t.ActivePath() {
+        b.WriteString("---\n\n")
+        fmt.Fprintf(&b, "**%s**", speaker(m))
+        if !m.CreatedAt.IsZero() { fmt.Fprintf(&b, " · %s", m.CreatedAt.Format("2006-01-02 15:04")) }
+        b.WriteString("\n\n")
+        if m.Role == "tool" {
+            b.WriteString(fence(m.Content, guessLang(m.Content)))
+        } else if m.Content != "" {
+            b.WriteString(m.Content)
+            b.WriteString("\n\n")
+        }
+        for _, a := range m.Attachments {
+            switch {
+            case a.Type == "image_url" && a.URL != "":
+                fmt.Fprintf(&b, "![%s](%s)\n\n", a.Name, a.URL)
+            default:
+                fmt.Fprintf(&b, "_Attachment: %s_\n\n", attachmentLabel(a))
+            }
+        }
+        for _, c := range m.ToolCalls {
+            fmt.Fprintf(&b, "Tool call `%s`:\n\n", c.Name)
+            b.WriteString(fence(prettyJSON(c.Arguments), "json"))
+        }
+    }
+    _, err := io.WriteString(w, b.String())
+    return err
+}
+
+func speaker(m Message) string {
+    switch m.Role {
+    case "user":
+        return "User"
+    case "assistant":
+        if m.Model != "" { return "Assistant (" + m.Model + ")" }
+        return "Assistant"
+    case "system":
+        return "System"
+    case "tool":
+        if m.ToolName != "" { return "Tool result: " + m.ToolName }
+        return "Tool result"
+    }
+    return m.Role
+}
+
+func attachmentLabel(a Attachment) string {
+    switch {
+    case a.Name != "":
+        return a.Name
+    case a.URL != "" && !strings.HasPrefix(a.URL, "data:"):
+        return a.URL
+    case a.MIMEType != "":
+        return a.Type + " (" + a.MIMEType + ")"
+    }
+    return a.Type
+}
+
+// fence wraps s in a code fence long enough not to clash with fences inside s
+func fence(s, lang string) string {
+    ticks := "```"
+    for strings.Contains(s, ticks) { ticks += "`" }
+    return ticks + lang + "\n" + strings.TrimRight(s, "\n") + "\n" + ticks + "\n\n"
+}
+
+func prettyJSON(s string) string {
+    var buf bytes.Buffer
+    if json.Indent(&buf, []byte(s), "", "  ") != nil { return s }
+    return buf.String()
+}
+
+func guessLang(s string) string {
+    if json.Valid([]byte(strings.TrimSpace(s))) { return "json" }
+    return ""
+}
+
+// WriteHTML renders the active branch as a single self-contained HTML page
+func WriteHTML(w io.Writer, t *Thread) error {
+    type call struct{ Name, Arguments string }
+    type att struct {
+        Label string
+        Image template.URL
+    }
+    type msg struct {
+        Role, Speaker, Content, Time string
+        Calls                        []call
+        Attachments                  []att
+    }
+    data := struct {
+        Title, ProjectID, ID, Summary, Created, Exported string
+        Messages                                         []msg
+    }{
+        Title: t.Title, ProjectID: t.ProjectID, ID: t.ID, Summary: t.Summary,
+        Exported: time.Now().Format("2006-01-02 15:04"),
+    }
+    if data.Title == "" { data.Title = "Untitled thread" }
+    if !t.CreatedAt.IsZero() { data.Created = t.CreatedAt.Format("2006-01-02 15:04") }
+    for _, m := range t.ActivePath() {
+        v := msg{Role: m.Role, Speaker: speaker(m), Content: m.Content}
+        if !m.CreatedAt.IsZero() { v.Time = m.CreatedAt.Format("2006-01-02 15:04") }
+        for _, c := range m.ToolCalls { v.Calls = append(v.Calls, call{c.Name, prettyJSON(c.Arguments)}) }
+        for _, a := range m.Attachments {
+            item := att{Label: attachmentLabel(a)}
+            // only images are inlined; data: URLs keep the page self-contained
+            if a.Type == "image_url" && (strings.HasPrefix(a.URL, "data:image/") || strings.HasPrefix(a.URL, "https://") || strings.HasPrefix(a.URL, "http://")) {
+                item.Image = template.URL(a.URL)
+            }
+            v.Attachments = append(v.Attachments, item)
+        }
+        data.Messages = append(data.Messages, v)
+    }
+    return htmlTemplate.Execute(w, data)
+}
+
+var htmlTemplate = template.Must(template.New("thread").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",Roboto,sans-serif;max-width:860px;margin:2rem auto;padding:0 1rem;color:#1f2328;background:#fff}
+header{border-bottom:1px solid #d0d7de;margin-bottom:1.5rem}
+.meta{color:#656d76;font-size:.9rem}
+.summary{border-left:4px solid #d0d7de;padding:.25rem 1rem;color:#57606a}
+.msg{border:1px solid #d0d7de;border-radius:8px;padding:.75rem 1rem;margin:1rem 0}
+.msg.user{background:#f6f8fa}
+.msg.tool,.msg.system{background:#fffbe6}
+.who{font-weight:600;margin-bottom:.5rem}
+.who time{font-weight:400;color:#656d76;font-size:.85rem;margin-left:.5rem}
+.content{white-space:pre-wrap;word-wrap:break-word}
+pre{background:#f6f8fa;border-radius:6px;padding:.75rem;overflow-x:auto;font-size:.85rem}
+details{margin-top:.5rem}
+img{max-width:100%;border-radius:6px;margin-top:.5rem}
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<p class="meta">{{if .ProjectID}}Project {{.ProjectID}} · {{end}}{{if .Created}}Created {{.Created}} · {{end}}Exported {{.Exported}} · {{.ID}}</p>
+{{if .Summary}}<p class="summary">{{.Summary}}</p>{{end}}
+</header>
+<main>
+{{range .Messages}}<section class="msg {{.Role}}">
+<div class="who">{{.Speaker}}{{if .Time}}<time>{{.Time}}</time>{{end}}</div>
+{{if eq .Role "tool"}}<pre>{{.Content}}</pre>{{else if .Content}}<div class="content">{{.Content}}</div>{{end}}
+{{range .Attachments}}{{if .Image}}<img src="{{.Image}}" alt="{{.Label}}">{{else}}<p class="meta">Attachment: {{.Label}}</p>{{end}}
+{{end}}{{range .Calls}}<details open><summary>Tool call <code>{{.Name}}</code></summary><pre>{{.Arguments}}</pre></details>
+{{end}}</section>
+{{end}}</main>
+</body>
+</html>
+`))
+
+// WriteJSONL writes the whole thread, all branches included, in the store's log
+// format: one record per message followed by a header record
+func WriteJSONL(w io.Writer, t *Thread) error {
+    data, err := encodeRecords(snapshotRecords(t))
+    if err != nil { return err }
+    _, err = w.Write(data)
+    return err
+}
+
+// ReadJSONL reads a thread written by WriteJSONL (or a thread log file)
+func ReadJSONL(r io.Reader) (*Thread, error) {
+    var info replayInfo
+    t, err := replay(r, nil, &info)
+    if err != nil { return nil, err }
+    t.normalize()
+    return t, nil
+}
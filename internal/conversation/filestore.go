@@ -3,119 +3,459 @@ package conversation
 import (
     "crypto/rand"
     "encoding/hex"
-    "encoding/json"
     "fmt"
     "os"
     "path/filepath"
+    "reflect"
     "sync"
     "time"
+
+    "github.com/pradord/llm/internal/envelope"
+    "github.com/pradord/llm/internal/filelock"
 )
 
-// FileStore persists threads as JSON files under a directory
+// FileStore persists each thread as an append-only JSONL log (<id>.jsonl) under a
+// directory plus a metadata index used by ListThreads. Threads written by older
+// versions as a single <id>.json document are read as-is and migrated to a log on
+// their next write.
+//
+// Every operation holds an advisory lock on <dir>/.lock (shared for reads,
+// exclusive for writes) so several processes can share the directory, and every
+// write bumps Thread.Version so UpdateThread can detect lost updates.
+//
+// Files are created owner-only (0600 in a 0700 directory). With
+// FileStoreConfig.Key logs and the index are envelope-encrypted; plaintext logs
+// stay readable and are encrypted on their next write.
 type FileStore struct {
-    dir string
-    mu  sync.Mutex
+    dir   string
+    cfg   FileStoreConfig
+    mu    sync.Mutex
+    cache map[string]*cachedLog
+    index *metaIndex
+    listeners []Listener
+}
+
+// FileStoreConfig tunes log compaction and durability
+type FileStoreConfig struct {
+    CompactAfter int  // rewrite a log once it holds this many superseded records (default 256)
+    SyncWrites   bool // fsync logs and index after every write
+    LockTimeout  time.Duration // how long to wait for the directory lock (default 5s)
+    Key          *envelope.Key // encrypt thread logs and the index at rest; nil stores plaintext
+}
+
+// cachedLog is a replayed thread together with the file state it was read from
+type cachedLog struct {
+    t    *Thread
+    info replayInfo
 }
 
 func NewFileStore(dir string) (*FileStore, error) {
+    return NewFileStoreWithConfig(dir, FileStoreConfig{})
+}
+
+func NewFileStoreWithConfig(dir string, cfg FileStoreConfig) (*FileStore, error) {
     if dir == "" { dir = ".llm_threads" }
-    if err := os.MkdirAll(dir, 0o755); err != nil { return nil, err }
-    return &FileStore{dir: dir}, nil
+    if cfg.CompactAfter <= 0 { cfg.CompactAfter = 256 }
+    if cfg.LockTimeout <= 0 { cfg.LockTimeout = 5 * time.Second }
+    if err := os.MkdirAll(dir, 0o700); err != nil { return nil, err }
+    if err := restrictPerms(dir); err != nil { return nil, err }
+    return &FileStore{dir: dir, cfg: cfg, cache: map[string]*cachedLog{}}, nil
 }
 
-func (fs *FileStore) path(id string) string { return filepath.Join(fs.dir, id+".json") }
+// restrictPerms makes dir and everything under it private to the owner:
+// stores written by older versions left logs, the index and the search index
+// world-readable
+func restrictPerms(dir string) error {
+    return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+        if err != nil { return err }
+        if d.Type()&os.ModeSymlink != 0 { return nil }
+        info, err := d.Info()
+        if err != nil { return err }
+        want := os.FileMode(0o600)
+        if d.IsDir() { want = 0o700 }
+        if info.Mode().Perm()&^want == 0 { return nil }
+        return os.Chmod(path, info.Mode().Perm()&want)
+    })
+}
 
-func (fs *FileStore) CreateThread(title string) (*Thread, error) {
+func (fs *FileStore) logPath(id string) string    { return filepath.Join(fs.dir, id+".jsonl") }
+func (fs *FileStore) legacyPath(id string) string { return filepath.Join(fs.dir, id+".json") }
+func (fs *FileStore) indexPath() string           { return filepath.Join(fs.dir, indexFile) }
+
+// AddListener registers l to be notified after every successful write
+func (fs *FileStore) AddListener(l Listener) {
     fs.mu.Lock(); defer fs.mu.Unlock()
-    id := newID()
-    now := time.Now()
-    t := &Thread{
-        ID: id,
-        Title: title,
-        CreatedAt: now,
-        UpdatedAt: now,
-        Metadata: make(map[string]interface{}),
-        Messages: []Message{},
+    fs.listeners = append(fs.listeners, l)
+}
+
+// notifyLocked tells listeners what records were just written for t
+func (fs *FileStore) notifyLocked(t *Thread, recs []logRecord) {
+    if len(fs.listeners) == 0 { return }
+    var msgs []Message
+    changed := recs == nil
+    for _, r := range recs {
+        if r.Op == opMessage && r.Message != nil { msgs = append(msgs, *r.Message) } else { changed = true }
+    }
+    snapshot := t.clone()
+    for _, l := range fs.listeners {
+        if len(msgs) > 0 { l.MessagesAppended(snapshot, msgs) }
+        if changed { l.ThreadChanged(snapshot) }
     }
-    if err := fs.save(t); err != nil { return nil, err }
-    return t, nil
+}
+
+// lock serializes access within the process and, through the lock file, with
+// other processes using the same directory
+func (fs *FileStore) lock(exclusive bool) (func(), error) {
+    fs.mu.Lock()
+    l, err := filelock.Acquire(filepath.Join(fs.dir, ".lock"), exclusive, fs.cfg.LockTimeout)
+    if err != nil {
+        fs.mu.Unlock()
+        return nil, fmt.Errorf("thread store: %w", err)
+    }
+    return func() { _ = l.Release(); fs.mu.Unlock() }, nil
+}
+
+func (fs *FileStore) CreateThread(title string) (*Thread, error) {
+    return fs.create("", title)
 }
 
 // CreateThreadForProject creates a thread scoped to a project
 func (fs *FileStore) CreateThreadForProject(projectID, title string) (*Thread, error) {
-    fs.mu.Lock(); defer fs.mu.Unlock()
-    id := newID()
+    return fs.create(projectID, title)
+}
+
+func (fs *FileStore) create(projectID, title string) (*Thread, error) {
+    unlock, err := fs.lock(true)
+    if err != nil { return nil, err }
+    defer unlock()
     now := time.Now()
     t := &Thread{
-        ID: id,
+        ID: newID(),
         ProjectID: projectID,
         Title: title,
         CreatedAt: now,
         UpdatedAt: now,
         Metadata: make(map[string]interface{}),
         Messages: []Message{},
+        Version: 1,
     }
-    if err := fs.save(t); err != nil { return nil, err }
-    return t, nil
+    if err := fs.rewriteLocked(t); err != nil { return nil, err }
+    fs.notifyLocked(t, nil)
+    return t.clone(), nil
+}
+
+// ImportThread writes t as a new thread in a single step, keeping its messages,
+// branches and timestamps; it implements Importer
+func (fs *FileStore) ImportThread(t *Thread) (*Thread, error) {
+    unlock, err := fs.lock(true)
+    if err != nil { return nil, err }
+    defer unlock()
+    out := prepareImport(t, time.Now())
+    out.ID = newID()
+    if err := fs.rewriteLocked(out); err != nil { return nil, err }
+    fs.notifyLocked(out, nil)
+    return out.clone(), nil
 }
 
 func (fs *FileStore) GetThread(id string) (*Thread, error) {
-    data, err := os.ReadFile(fs.path(id))
+    unlock, err := fs.lock(false)
     if err != nil { return nil, err }
-    var t Thread
-    if err := json.Unmarshal(data, &t); err != nil { return nil, err }
-    return &t, nil
+    defer unlock()
+    c, err := fs.loadLocked(id, false)
+    if err != nil { return nil, err }
+    return c.t.clone(), nil
 }
 
+// ListThreads returns thread headers from the metadata index without reading any
+// thread log; Messages is nil and MessageCount is set. Use GetThread for messages.
 func (fs *FileStore) ListThreads() ([]*Thread, error) {
-    entries, err := os.ReadDir(fs.dir)
+    unlock, err := fs.lock(false)
+    if err != nil { return nil, err }
+    defer unlock()
+    idx, err := fs.indexLocked(false)
     if err != nil { return nil, err }
     var out []*Thread
-    for _, e := range entries {
-        if e.IsDir() { continue }
-        if filepath.Ext(e.Name()) != ".json" { continue }
-        data, err := os.ReadFile(filepath.Join(fs.dir, e.Name()))
-        if err != nil { continue }
-        var t Thread
-        if json.Unmarshal(data, &t) == nil { out = append(out, &t) }
-    }
+    for _, h := range idx.sorted() { out = append(out, h.thread()) }
     return out, nil
 }
 
 func (fs *FileStore) AppendMessage(threadID string, msg Message) (*Thread, error) {
-    fs.mu.Lock(); defer fs.mu.Unlock()
-    t, err := fs.GetThread(threadID)
-    if err != nil { return nil, err }
-    if msg.ID == "" { msg.ID = newID() }
-    if msg.CreatedAt.IsZero() { msg.CreatedAt = time.Now() }
-    t.Messages = append(t.Messages, msg)
-    t.UpdatedAt = time.Now()
-    if err := fs.save(t); err != nil { return nil, err }
-    return t, nil
+    return fs.AppendMessages(threadID, []Message{msg})
+}
+
+// AppendMessages appends a run transcript in order, each message replying to the
+// previous one, with a single write
+func (fs *FileStore) AppendMessages(threadID string, msgs []Message) (*Thread, error) {
+    return fs.mutate(threadID, func(t *Thread, now time.Time) ([]logRecord, error) {
+        recs := make([]logRecord, 0, len(msgs))
+        for _, msg := range msgs {
+            if msg.ParentID == "" { msg.ParentID = t.ActiveLeafID }
+            added, err := t.addMessage(msg)
+            if err != nil { return nil, err }
+            recs = append(recs, logRecord{Op: opMessage, At: now, Message: &added})
+        }
+        return recs, nil
+    })
 }
 
 func (fs *FileStore) UpdateSummary(threadID string, summary string) error {
-    fs.mu.Lock(); defer fs.mu.Unlock()
+    _, err := fs.mutate(threadID, func(t *Thread, now time.Time) ([]logRecord, error) {
+        t.Summary = summary
+        return []logRecord{{Op: opThread, At: now, Thread: headerOf(t)}}, nil
+    })
+    return err
+}
+
+// UpdateThread saves the entire thread (used for metadata updates). It fails with
+// a *ConflictError when t.Version is not the stored version, i.e. someone wrote
+// the thread after t was read. Header-only changes are appended; changed
+// messages rewrite the log. On success t.Version is the new version; an unknown
+// thread is not created.
+func (fs *FileStore) UpdateThread(t *Thread) error {
+    unlock, err := fs.lock(true)
+    if err != nil { return err }
+    defer unlock()
+    c, err := fs.loadLocked(t.ID, true)
+    if err != nil { return err }
+    if c.t.Version != t.Version {
+        return &ConflictError{ThreadID: t.ID, Expected: t.Version, Actual: c.t.Version}
+    }
+    // t is left as it was if the write fails
+    next := t.clone()
+    next.Version++
+    next.UpdatedAt = time.Now()
+    next.MessageCount = len(next.Messages)
+    if !sameMessages(c.t, next) {
+        err = fs.rewriteLocked(next.clone())
+    } else {
+        c.t = next.clone()
+        err = fs.appendLocked(c, []logRecord{{Op: opThread, At: next.UpdatedAt, V: next.Version, Thread: headerOf(next)}})
+    }
+    if err != nil {
+        delete(fs.cache, t.ID) // the cached thread may be ahead of the log
+        return err
+    }
+    t.Version, t.UpdatedAt, t.MessageCount = next.Version, next.UpdatedAt, next.MessageCount
+    fs.notifyLocked(next, nil)
+    return nil
+}
+
+// EditMessage creates a sibling of a user message with new content and activates it.
+// Replies to the original stay reachable on the previous branch.
+func (fs *FileStore) EditMessage(threadID, messageID, content string) (*Thread, error) {
+    return fs.mutate(threadID, func(t *Thread, now time.Time) ([]logRecord, error) {
+        orig, ok := t.Message(messageID)
+        if !ok { return nil, fmt.Errorf("message not found: %s", messageID) }
+        if orig.Role != "user" { return nil, fmt.Errorf("only user messages can be edited, %s is %s", messageID, orig.Role) }
+        added, err := t.addSibling(messageID, Message{Role: "user", Content: content})
+        if err != nil { return nil, err }
+        return []logRecord{{Op: opMessage, At: now, Message: &added}}, nil
+    })
+}
+
+// RegenerateMessage stores msg as an alternative reply to the same parent as an
+// assistant message and activates it. The caller produces the new content.
+func (fs *FileStore) RegenerateMessage(threadID, messageID string, msg Message) (*Thread, error) {
+    return fs.mutate(threadID, func(t *Thread, now time.Time) ([]logRecord, error) {
+        orig, ok := t.Message(messageID)
+        if !ok { return nil, fmt.Errorf("message not found: %s", messageID) }
+        if orig.Role != "assistant" { return nil, fmt.Errorf("only assistant messages can be regenerated, %s is %s", messageID, orig.Role) }
+        msg.Role = "assistant"
+        added, err := t.addSibling(messageID, msg)
+        if err != nil { return nil, err }
+        return []logRecord{{Op: opMessage, At: now, Message: &added}}, nil
+    })
+}
+
+// SwitchBranch activates the branch through messageID, continuing to its newest leaf
+func (fs *FileStore) SwitchBranch(threadID, messageID string) (*Thread, error) {
+    return fs.mutate(threadID, func(t *Thread, now time.Time) ([]logRecord, error) {
+        if err := t.switchBranch(messageID); err != nil { return nil, err }
+        return []logRecord{{Op: opActive, At: now, LeafID: t.ActiveLeafID}}, nil
+    })
+}
+
+// ListBranches returns every root-to-leaf path of a thread
+func (fs *FileStore) ListBranches(threadID string) ([]Branch, error) {
     t, err := fs.GetThread(threadID)
+    if err != nil { return nil, err }
+    return t.Branches(), nil
+}
+
+// Compact rewrites a thread log as its messages plus a single header record.
+// Logs are also compacted automatically after CompactAfter superseded records.
+func (fs *FileStore) Compact(threadID string) error {
+    unlock, err := fs.lock(true)
+    if err != nil { return err }
+    defer unlock()
+    c, err := fs.loadLocked(threadID, true)
     if err != nil { return err }
-    t.Summary = summary
-    t.UpdatedAt = time.Now()
-    return fs.save(t)
+    return fs.rewriteLocked(c.t)
 }
 
-// UpdateThread saves the entire thread (used for metadata updates)
-func (fs *FileStore) UpdateThread(t *Thread) error {
-    fs.mu.Lock(); defer fs.mu.Unlock()
-    t.UpdatedAt = time.Now()
-    return fs.save(t)
+// mutate loads a thread, lets fn change it and describe the change as records,
+// then appends those records and refreshes the index
+func (fs *FileStore) mutate(id string, fn func(t *Thread, now time.Time) ([]logRecord, error)) (*Thread, error) {
+    unlock, err := fs.lock(true)
+    if err != nil { return nil, err }
+    defer unlock()
+    c, err := fs.loadLocked(id, true)
+    if err != nil { return nil, err }
+    now := time.Now()
+    c.t.Version++
+    c.t.UpdatedAt = now
+    recs, err := fn(c.t, now)
+    if err != nil {
+        delete(fs.cache, id) // fn may have partially changed the cached thread
+        return nil, err
+    }
+    c.t.MessageCount = len(c.t.Messages)
+    for i := range recs { recs[i].V = c.t.Version }
+    if err := fs.appendLocked(c, recs); err != nil { return nil, err }
+    fs.notifyLocked(c.t, recs)
+    return c.t.clone(), nil
 }
 
-func (fs *FileStore) save(t *Thread) error {
-    data, err := json.MarshalIndent(t, "", "  ")
+// loadLocked returns the cached thread, replaying its log when the file changed
+// since it was cached. Legacy JSON threads are migrated when forWrite is set.
+func (fs *FileStore) loadLocked(id string, forWrite bool) (*cachedLog, error) {
+    st, err := os.Stat(fs.logPath(id))
+    if os.IsNotExist(err) {
+        delete(fs.cache, id)
+        t, lerr := readLegacy(fs.legacyPath(id))
+        if lerr != nil { return nil, lerr }
+        if !forWrite { return &cachedLog{t: t}, nil }
+        if err := fs.rewriteLocked(t); err != nil { return nil, err }
+        _ = os.Remove(fs.legacyPath(id))
+        return fs.cache[id], nil
+    }
+    if err != nil { return nil, err }
+    c, ok := fs.cache[id]
+    if !ok || !c.info.unchanged(st) {
+        t, info, err := replayLog(fs.logPath(id), fs.cfg.Key)
+        if err != nil { return nil, err }
+        if t.ID == "" { t.ID = id }
+        t.normalize()
+        c = &cachedLog{t: t, info: info}
+        fs.cache[id] = c
+    }
+    if forWrite && fs.cfg.Key != nil && c.info.dataKey == nil {
+        // encryption was enabled after this log was written
+        if err := fs.rewriteLocked(c.t); err != nil { return nil, err }
+        return fs.cache[id], nil
+    }
+    return c, nil
+}
+
+// appendLocked writes records to a cached thread's log, compacting it when
+// enough records are superseded, and records the new header in the index
+func (fs *FileStore) appendLocked(c *cachedLog, recs []logRecord) error {
+    if c.info.records-(len(c.t.Messages)+1) >= fs.cfg.CompactAfter {
+        return fs.rewriteLocked(c.t)
+    }
+    data, err := encodeRecords(recs)
     if err != nil { return err }
-    tmp := fs.path(t.ID)+".tmp"
-    if err := os.WriteFile(tmp, data, 0o644); err != nil { return err }
-    return os.Rename(tmp, fs.path(t.ID))
+    if data, err = sealLines(data, c.info.dataKey); err != nil { return err }
+    path := fs.logPath(c.t.ID)
+    if err := appendFile(path, data, c.info.validEnd, fs.cfg.SyncWrites); err != nil {
+        delete(fs.cache, c.t.ID)
+        return err
+    }
+    st, err := os.Stat(path)
+    if err != nil { delete(fs.cache, c.t.ID); return err }
+    c.info.records += len(recs)
+    c.info.validEnd, c.info.file = st.Size(), st
+    return fs.indexPutLocked(headerOf(c.t))
+}
+
+// rewriteLocked replaces a thread log with its compacted form
+func (fs *FileStore) rewriteLocked(t *Thread) error {
+    recs := snapshotRecords(t)
+    data, err := encodeRecords(recs)
+    if err != nil { return err }
+    data, dk, err := newFileData(data, fs.cfg.Key)
+    if err != nil { return err }
+    path := fs.logPath(t.ID)
+    if err := writeFileAtomic(path, data, fs.cfg.SyncWrites); err != nil { return err }
+    st, err := os.Stat(path)
+    if err != nil { return err }
+    fs.cache[t.ID] = &cachedLog{t: t, info: replayInfo{records: len(recs), validEnd: st.Size(), file: st, dataKey: dk}}
+    return fs.indexPutLocked(headerOf(t))
+}
+
+// indexLocked returns the metadata index, rebuilding it from the thread files
+// when it does not exist yet. The rebuilt index is only saved when the caller
+// holds the exclusive lock (persist).
+func (fs *FileStore) indexLocked(persist bool) (*metaIndex, error) {
+    path := fs.indexPath()
+    st, err := os.Stat(path)
+    if os.IsNotExist(err) {
+        idx, err := rebuildMetaIndex(fs.dir, fs.cfg.Key)
+        if err != nil { return nil, err }
+        if !persist { return idx, nil }
+        if err := fs.writeIndexLocked(idx); err != nil { return nil, err }
+        return fs.index, nil
+    }
+    if err != nil { return nil, err }
+    if fs.index != nil && fs.index.file != nil && os.SameFile(fs.index.file, st) && fs.index.file.Size() == st.Size() && fs.index.file.ModTime().Equal(st.ModTime()) {
+        return fs.index, nil
+    }
+    idx, err := loadMetaIndex(path, fs.cfg.Key)
+    if err != nil { return nil, err }
+    fs.index = idx
+    return idx, nil
+}
+
+func (fs *FileStore) indexPutLocked(h *threadHeader) error {
+    idx, err := fs.indexLocked(true)
+    if err != nil { return err }
+    if h.Deleted { delete(idx.headers, h.ID) } else { idx.headers[h.ID] = h }
+    idx.lines++
+    if idx.needsCompaction() || (fs.cfg.Key != nil && idx.dataKey == nil) { return fs.writeIndexLocked(idx) }
+    data, err := encodeHeaders(h)
+    if err != nil { return err }
+    if data, err = sealLines(data, idx.dataKey); err != nil { return err }
+    if err := appendFile(fs.indexPath(), data, idx.validEnd, fs.cfg.SyncWrites); err != nil {
+        fs.index = nil
+        return err
+    }
+    return fs.statIndexLocked(idx)
+}
+
+func (fs *FileStore) writeIndexLocked(idx *metaIndex) error {
+    data, err := idx.encode()
+    if err != nil { return err }
+    data, dk, err := newFileData(data, fs.cfg.Key)
+    if err != nil { return err }
+    if err := writeFileAtomic(fs.indexPath(), data, fs.cfg.SyncWrites); err != nil { return err }
+    idx.lines, idx.dataKey = len(idx.headers), dk
+    return fs.statIndexLocked(idx)
+}
+
+func (fs *FileStore) statIndexLocked(idx *metaIndex) error {
+    st, err := os.Stat(fs.indexPath())
+    if err != nil { fs.index = nil; return err }
+    idx.validEnd, idx.file = st.Size(), st
+    fs.index = idx
+    return nil
+}
+
+// sameMessages reports whether two versions of a thread hold identical messages
+func sameMessages(a, b *Thread) bool {
+    if len(a.Messages) != len(b.Messages) { return false }
+    return len(a.Messages) == 0 || reflect.DeepEqual(a.Messages, b.Messages)
+}
+
+// clone copies a thread so callers cannot modify cached state
+func (t *Thread) clone() *Thread {
+    c := *t
+    c.Messages = append([]Message{}, t.Messages...)
+    if t.Metadata != nil {
+        c.Metadata = make(map[string]interface{}, len(t.Metadata))
+        for k, v := range t.Metadata { c.Metadata[k] = v }
+    }
+    c.MessageCount = len(c.Messages)
+    return &c
 }
 
 func newID() string {
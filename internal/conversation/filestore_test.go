@@ -0,0 +1,229 @@
+package conversation
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "os"
+    "testing"
+    "time"
+
+    "github.com/pradord/llm/internal/envelope"
+)
+
+func testKey(t testing.TB) *envelope.Key {
+    t.Helper()
+    k, err := envelope.NewKey(bytes.Repeat([]byte{7}, 32))
+    if err != nil { t.Fatal(err) }
+    return k
+}
+
+func appendN(t testing.TB, fs *FileStore, id string, n int) {
+    t.Helper()
+    for i := 0; i < n; i++ {
+        if _, err := fs.AppendMessage(id, Message{Role: "user", Content: fmt.Sprintf("message %d", i)}); err != nil { t.Fatal(err) }
+    }
+}
+
+// A crash mid-write leaves a line without its newline: replay must ignore it and
+// the next append must cut it off rather than glue a record onto it
+func TestFileStoreTornTail(t *testing.T) {
+    for _, key := range []*envelope.Key{nil, testKey(t)} {
+        dir := t.TempDir()
+        fs, err := NewFileStoreWithConfig(dir, FileStoreConfig{Key: key})
+        if err != nil { t.Fatal(err) }
+        th, err := fs.CreateThread("torn")
+        if err != nil { t.Fatal(err) }
+        appendN(t, fs, th.ID, 3)
+
+        f, err := os.OpenFile(fs.logPath(th.ID), os.O_WRONLY|os.O_APPEND, 0)
+        if err != nil { t.Fatal(err) }
+        if _, err := f.WriteString(`{"op":"message","at":"2024-01-01T00:00:00Z","message":{"id":"torn","ro`); err != nil { t.Fatal(err) }
+        f.Close()
+
+        // a fresh store has no cached state and must replay the file
+        fs2, err := NewFileStoreWithConfig(dir, FileStoreConfig{Key: key})
+        if err != nil { t.Fatal(err) }
+        got, err := fs2.GetThread(th.ID)
+        if err != nil { t.Fatalf("replay with torn tail: %v", err) }
+        if len(got.Messages) != 3 { t.Fatalf("got %d messages after replay, want 3", len(got.Messages)) }
+
+        if _, err := fs2.AppendMessage(th.ID, Message{Role: "assistant", Content: "after crash"}); err != nil { t.Fatal(err) }
+        data, err := os.ReadFile(fs.logPath(th.ID))
+        if err != nil { t.Fatal(err) }
+        if bytes.Contains(data, []byte(`"id":"torn"`)) { t.Fatal("torn record was not truncated") }
+        again, _, err := replayLog(fs.logPath(th.ID), key)
+        if err != nil { t.Fatal(err) }
+        if len(again.Messages) != 4 || again.Messages[3].Content != "after crash" {
+            t.Fatalf("after append: %d messages, last %+v", len(again.Messages), again.Messages[len(again.Messages)-1])
+        }
+    }
+}
+
+// Compaction rewrites the log as messages plus one header; the thread read back
+// must be the one written, branches and header changes included
+func TestFileStoreCompactRoundTrip(t *testing.T) {
+    for _, key := range []*envelope.Key{nil, testKey(t)} {
+        dir := t.TempDir()
+        fs, err := NewFileStoreWithConfig(dir, FileStoreConfig{Key: key})
+        if err != nil { t.Fatal(err) }
+        th, err := fs.CreateThreadForProject("p1", "compact")
+        if err != nil { t.Fatal(err) }
+        first, err := fs.AppendMessages(th.ID, []Message{{Role: "user", Content: "q"}, {Role: "assistant", Content: "a",
+            ToolCalls: []ToolCall{{ID: "c1", Type: "function", Name: "calculator", Arguments: `{"x":1}`}}}})
+        if err != nil { t.Fatal(err) }
+        if _, err := fs.EditMessage(th.ID, first.Messages[0].ID, "q, edited"); err != nil { t.Fatal(err) }
+        if _, err := fs.SwitchBranch(th.ID, first.Messages[1].ID); err != nil { t.Fatal(err) }
+        for i := 0; i < 5; i++ {
+            if err := fs.UpdateSummary(th.ID, fmt.Sprintf("summary %d", i)); err != nil { t.Fatal(err) }
+        }
+        cur, err := fs.GetThread(th.ID)
+        if err != nil { t.Fatal(err) }
+        cur.Metadata["rating"] = "good"
+        if err := fs.UpdateThread(cur); err != nil { t.Fatal(err) }
+
+        want, err := fs.GetThread(th.ID)
+        if err != nil { t.Fatal(err) }
+        if err := fs.Compact(th.ID); err != nil { t.Fatal(err) }
+        _, info, err := replayLog(fs.logPath(th.ID), key)
+        if err != nil { t.Fatal(err) }
+        if info.records != len(want.Messages)+1 { t.Fatalf("compacted log has %d records, want %d", info.records, len(want.Messages)+1) }
+
+        fs2, err := NewFileStoreWithConfig(dir, FileStoreConfig{Key: key})
+        if err != nil { t.Fatal(err) }
+        got, err := fs2.GetThread(th.ID)
+        if err != nil { t.Fatal(err) }
+        g, _ := json.Marshal(got)
+        w, _ := json.Marshal(want)
+        if !bytes.Equal(g, w) { t.Fatalf("round trip changed the thread:\ngot  %s\nwant %s", g, w) }
+    }
+}
+
+// ListThreads must answer from the index alone: emptying a log does not change
+// the listing
+func TestFileStoreListThreadsUsesIndex(t *testing.T) {
+    fs, err := NewFileStore(t.TempDir())
+    if err != nil { t.Fatal(err) }
+    var ids []string
+    for i := 0; i < 3; i++ {
+        th, err := fs.CreateThread(fmt.Sprintf("t%d", i))
+        if err != nil { t.Fatal(err) }
+        appendN(t, fs, th.ID, i+1)
+        ids = append(ids, th.ID)
+    }
+    if err := os.Truncate(fs.logPath(ids[2]), 0); err != nil { t.Fatal(err) }
+    list, err := fs.ListThreads()
+    if err != nil { t.Fatal(err) }
+    if len(list) != 3 { t.Fatalf("listed %d threads, want 3", len(list)) }
+    for _, th := range list {
+        if th.Messages != nil { t.Errorf("thread %s: ListThreads returned messages", th.ID) }
+        if th.ID == ids[2] && th.MessageCount != 3 { t.Errorf("thread %s: MessageCount %d, want 3 from the index", th.ID, th.MessageCount) }
+    }
+}
+
+// An imported thread keeps the source's timestamps and is written in one step
+func TestFileStoreImportKeepsTimestamps(t *testing.T) {
+    fs, err := NewFileStore(t.TempDir())
+    if err != nil { t.Fatal(err) }
+    created := time.Date(2023, 5, 1, 9, 0, 0, 0, time.UTC)
+    updated := created.Add(48 * time.Hour)
+    src := &Thread{Title: "imported", CreatedAt: created, UpdatedAt: updated,
+        Messages: []Message{{ID: "m1", Role: "user", Content: "hi"}, {ID: "m2", Role: "assistant", Content: "hello"}}}
+    got, err := ImportThread(fs, src)
+    if err != nil { t.Fatal(err) }
+    _, info, err := replayLog(fs.logPath(got.ID), nil)
+    if err != nil { t.Fatal(err) }
+    if info.records != len(src.Messages)+1 { t.Fatalf("import wrote %d records, want a single snapshot of %d", info.records, len(src.Messages)+1) }
+    list, err := fs.ListThreads()
+    if err != nil { t.Fatal(err) }
+    if len(list) != 1 || !list[0].CreatedAt.Equal(created) || !list[0].UpdatedAt.Equal(updated) || list[0].MessageCount != 2 {
+        t.Fatalf("listed %+v, want created %v, updated %v and 2 messages", list[0], created, updated)
+    }
+}
+
+// Editing a message in place keeps the count, but the search index must still
+// drop the old text
+func TestSearchIndexReindexesEditedMessages(t *testing.T) {
+    fs, err := NewFileStore(t.TempDir())
+    if err != nil { t.Fatal(err) }
+    idx := NewSearchIndex()
+    fs.AddListener(idx)
+    th, err := fs.CreateThread("edits")
+    if err != nil { t.Fatal(err) }
+    if _, err := fs.AppendMessage(th.ID, Message{Role: "user", Content: "original wording"}); err != nil { t.Fatal(err) }
+    th, err = fs.GetThread(th.ID)
+    if err != nil { t.Fatal(err) }
+    th.Messages[0].Content = "revised wording"
+    if err := fs.UpdateThread(th); err != nil { t.Fatal(err) }
+    if got := idx.Search("original", SearchOptions{}); len(got) != 0 { t.Fatalf("old text still found: %+v", got) }
+    if got := idx.Search("revised", SearchOptions{}); len(got) != 1 { t.Fatalf("new text found %d times, want 1", len(got)) }
+}
+
+// UpdateThread does not create threads, and leaves t alone when it fails
+func TestFileStoreUpdateMissingThread(t *testing.T) {
+    fs, err := NewFileStore(t.TempDir())
+    if err != nil { t.Fatal(err) }
+    th := &Thread{ID: "missing", Title: "ghost", Version: 3}
+    if err := fs.UpdateThread(th); !os.IsNotExist(err) { t.Fatalf("got %v, want not found", err) }
+    if th.Version != 3 || !th.UpdatedAt.IsZero() { t.Fatalf("failed update changed t: %+v", th) }
+    if list, err := fs.ListThreads(); err != nil || len(list) != 0 { t.Fatalf("listed %d threads (%v), want none", len(list), err) }
+}
+
+// Stores written before files were made private are tightened on open
+func TestFileStoreRestrictsPermissions(t *testing.T) {
+    dir := t.TempDir()
+    fs, err := NewFileStore(dir)
+    if err != nil { t.Fatal(err) }
+    th, err := fs.CreateThread("old")
+    if err != nil { t.Fatal(err) }
+    for path, mode := range map[string]os.FileMode{dir: 0o755, fs.logPath(th.ID): 0o644, fs.indexPath(): 0o644} {
+        if err := os.Chmod(path, mode); err != nil { t.Fatal(err) }
+    }
+    if _, err := NewFileStore(dir); err != nil { t.Fatal(err) }
+    for path, want := range map[string]os.FileMode{dir: 0o700, fs.logPath(th.ID): 0o600, fs.indexPath(): 0o600} {
+        st, err := os.Stat(path)
+        if err != nil { t.Fatal(err) }
+        if st.Mode().Perm() != want { t.Errorf("%s: mode %v, want %v", path, st.Mode().Perm(), want) }
+    }
+}
+
+// fillThread writes n messages in batches, as agent transcripts are written
+func fillThread(b *testing.B, fs *FileStore, n int) string {
+    b.Helper()
+    th, err := fs.CreateThread("bench")
+    if err != nil { b.Fatal(err) }
+    batch := make([]Message, 100)
+    for i := 0; i < n; i += len(batch) {
+        for j := range batch { batch[j] = Message{Role: "user", Content: fmt.Sprintf("message %d of a long thread", i+j)} }
+        if _, err := fs.AppendMessages(th.ID, batch); err != nil { b.Fatal(err) }
+    }
+    return th.ID
+}
+
+// BenchmarkAppendMessage10k appends to a thread that already holds 10k messages
+func BenchmarkAppendMessage10k(b *testing.B) {
+    fs, err := NewFileStore(b.TempDir())
+    if err != nil { b.Fatal(err) }
+    id := fillThread(b, fs, 10000)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        if _, err := fs.AppendMessage(id, Message{Role: "assistant", Content: "reply"}); err != nil { b.Fatal(err) }
+    }
+}
+
+// BenchmarkListThreads lists threads of 10k messages each from a fresh store,
+// so nothing is cached
+func BenchmarkListThreads(b *testing.B) {
+    dir := b.TempDir()
+    fs, err := NewFileStore(dir)
+    if err != nil { b.Fatal(err) }
+    for i := 0; i < 10; i++ { fillThread(b, fs, 10000) }
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        fresh, err := NewFileStore(dir)
+        if err != nil { b.Fatal(err) }
+        list, err := fresh.ListThreads()
+        if err != nil { b.Fatal(err) }
+        if len(list) != 10 { b.Fatalf("listed %d threads", len(list)) }
+    }
+}
@@ -0,0 +1,429 @@
+package conversation
+
+import (
+    "archive/zip"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "math"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+)
+
+// Importers turn conversation exports from other chat products into Threads.
+// Both ChatGPT and Claude ship a zip with a conversations.json at its root; the
+// parsers accept that file's contents, ImportFile accepts the zip or the json.
+
+const (
+    SourceChatGPT = "chatgpt"
+    SourceClaude  = "claude"
+    SourceJSONL   = "jsonl"
+)
+
+// ImportResult reports what an import stored
+type ImportResult struct {
+    Imported int       `json:"imported"`
+    Skipped  int       `json:"skipped"` // already imported earlier
+    Threads  []*Thread `json:"-"`
+}
+
+// ImportFile parses an export from source (chatgpt, claude or jsonl) and stores
+// every conversation as a thread of projectID
+func ImportFile(store Store, source, path, projectID string) (*ImportResult, error) {
+    var threads []*Thread
+    switch source {
+    case SourceChatGPT, SourceClaude:
+        data, err := readConversationsJSON(path)
+        if err != nil { return nil, err }
+        if source == SourceChatGPT { threads, err = ParseChatGPTExport(data) } else { threads, err = ParseClaudeExport(data) }
+        if err != nil { return nil, err }
+    case SourceJSONL:
+        f, err := os.Open(path)
+        if err != nil { return nil, err }
+        defer f.Close()
+        t, err := ReadJSONL(f)
+        if err != nil { return nil, fmt.Errorf("%s: %w", path, err) }
+        if t.Metadata == nil { t.Metadata = map[string]interface{}{} }
+        if _, ok := t.Metadata["import_id"]; !ok && t.ID != "" {
+            t.Metadata["import_source"], t.Metadata["import_id"] = SourceJSONL, t.ID
+        }
+        threads = []*Thread{t}
+    default:
+        return nil, fmt.Errorf("unknown import source %q (want chatgpt, claude or jsonl)", source)
+    }
+    for _, t := range threads {
+        if projectID != "" { t.ProjectID = projectID }
+    }
+    return ImportThreads(store, threads)
+}
+
+// ImportThreads stores threads, skipping those whose import_source/import_id
+// metadata matches a thread imported before
+func ImportThreads(store Store, threads []*Thread) (*ImportResult, error) {
+    existing, err := store.ListThreads()
+    if err != nil { return nil, err }
+    seen := map[string]bool{}
+    for _, t := range existing {
+        if key := importKey(t); key != "" { seen[key] = true }
+    }
+    res := &ImportResult{}
+    for _, t := range threads {
+        key := importKey(t)
+        if key != "" && seen[key] {
+            res.Skipped++
+            continue
+        }
+        stored, err := ImportThread(store, t)
+        if err != nil { return res, fmt.Errorf("import %q: %w", t.Title, err) }
+        if key != "" { seen[key] = true }
+        res.Imported++
+        res.Threads = append(res.Threads, stored)
+    }
+    return res, nil
+}
+
+func importKey(t *Thread) string {
+    src, _ := t.Metadata["import_source"].(string)
+    id, _ := t.Metadata["import_id"].(string)
+    if src == "" || id == "" { return "" }
+    return src + "/" + id
+}
+
+// ImportThread stores t as a new thread, keeping its messages, branches, metadata
+// and timestamps. The stored thread gets a new ID. Stores that are not an
+// Importer get a create followed by an update, which stamps the update time.
+func ImportThread(store Store, t *Thread) (*Thread, error) {
+    if im, ok := store.(Importer); ok { return im.ImportThread(t) }
+    created, err := store.CreateThreadForProject(t.ProjectID, t.Title)
+    if err != nil { return nil, err }
+    out := prepareImport(t, created.CreatedAt)
+    out.ID, out.Version = created.ID, created.Version
+    if err := store.UpdateThread(out); err != nil { return nil, err }
+    return out, nil
+}
+
+// prepareImport copies t for storage as a new thread, filling in what an export
+// may leave out
+func prepareImport(t *Thread, now time.Time) *Thread {
+    out := t.clone()
+    out.Version = 1
+    if out.CreatedAt.IsZero() { out.CreatedAt = now }
+    if out.UpdatedAt.IsZero() { out.UpdatedAt = out.CreatedAt }
+    if out.Metadata == nil { out.Metadata = map[string]interface{}{} }
+    if out.Messages == nil { out.Messages = []Message{} }
+    out.normalize()
+    out.MessageCount = len(out.Messages)
+    return out
+}
+
+// readConversationsJSON returns conversations.json from an export zip, or the
+// file itself when path is not a zip
+func readConversationsJSON(path string) ([]byte, error) {
+    data, err := os.ReadFile(path)
+    if err != nil { return nil, err }
+    if !bytes.HasPrefix(data, []byte("PK\x03\x04")) { return data, nil }
+    zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+    if err != nil { return nil, err }
+    for _, f := range zr.File {
+        if filepath.Base(f.Name) != "conversations.json" { continue }
+        rc, err := f.Open()
+        if err != nil { return nil, err }
+        defer rc.Close()
+        return io.ReadAll(rc)
+    }
+    return nil, fmt.Errorf("%s: no conversations.json in archive", path)
+}
+
+// ChatGPT export: each conversation is a tree of nodes keyed by ID in "mapping",
+// current_node is the leaf of the branch that was shown last.
+
+type chatgptConversation struct {
+    ID               string                 `json:"id"`
+    ConversationID   string                 `json:"conversation_id"`
+    Title            string                 `json:"title"`
+    CreateTime       float64                `json:"create_time"`
+    UpdateTime       float64                `json:"update_time"`
+    CurrentNode      string                 `json:"current_node"`
+    DefaultModelSlug string                 `json:"default_model_slug"`
+    Mapping          map[string]chatgptNode `json:"mapping"`
+}
+
+type chatgptNode struct {
+    ID       string          `json:"id"`
+    Message  *chatgptMessage `json:"message"`
+    Parent   string          `json:"parent"`
+    Children []string        `json:"children"`
+}
+
+type chatgptMessage struct {
+    ID     string `json:"id"`
+    Author struct {
+        Role string `json:"role"`
+        Name string `json:"name"`
+    } `json:"author"`
+    CreateTime float64 `json:"create_time"`
+    Content    struct {
+        ContentType string            `json:"content_type"`
+        Parts       []json.RawMessage `json:"parts"`
+        Text        string            `json:"text"`
+        Result      string            `json:"result"`
+    } `json:"content"`
+    Recipient string `json:"recipient"`
+    Metadata  struct {
+        ModelSlug string `json:"model_slug"`
+        Hidden    bool   `json:"is_visually_hidden_from_conversation"`
+    } `json:"metadata"`
+}
+
+// ParseChatGPTExport converts a ChatGPT conversations.json. Branches created by
+// editing or regenerating in ChatGPT become branches of the thread.
+func ParseChatGPTExport(data []byte) ([]*Thread, error) {
+    var convs []chatgptConversation
+    if err := json.Unmarshal(data, &convs); err != nil { return nil, fmt.Errorf("chatgpt export: %w", err) }
+    out := make([]*Thread, 0, len(convs))
+    for _, c := range convs { out = append(out, c.thread()) }
+    return out, nil
+}
+
+func (c chatgptConversation) thread() *Thread {
+    id := c.ConversationID
+    if id == "" { id = c.ID }
+    t := &Thread{
+        Title: c.Title, CreatedAt: unixTime(c.CreateTime), UpdatedAt: unixTime(c.UpdateTime),
+        Metadata: map[string]interface{}{"import_source": SourceChatGPT, "import_id": id},
+        Messages: []Message{},
+    }
+    // kept maps a node to the nearest node at or above it that became a message
+    kept := map[string]string{}
+    var walk func(nodeID, parent string)
+    walk = func(nodeID, parent string) {
+        n, ok := c.Mapping[nodeID]
+        if !ok { return }
+        if m, ok := c.message(n, parent, t); ok {
+            t.Messages = append(t.Messages, m)
+            parent = m.ID
+        }
+        kept[nodeID] = parent
+        children := append([]string(nil), n.Children...)
+        sort.SliceStable(children, func(i, j int) bool { return c.created(children[i]) < c.created(children[j]) })
+        for _, ch := range children { walk(ch, parent) }
+    }
+    var roots []string
+    for id, n := range c.Mapping {
+        if _, ok := c.Mapping[n.Parent]; !ok || n.Parent == "" { roots = append(roots, id) }
+    }
+    sort.Strings(roots)
+    for _, r := range roots { walk(r, "") }
+    t.ActiveLeafID = kept[c.CurrentNode]
+    if t.ActiveLeafID == "" && len(t.Messages) > 0 { t.ActiveLeafID = t.latestLeaf(t.Messages[0].ID) }
+    return t
+}
+
+func (c chatgptConversation) created(nodeID string) float64 {
+    if n, ok := c.Mapping[nodeID]; ok && n.Message != nil { return n.Message.CreateTime }
+    return 0
+}
+
+// message converts a node; hidden system prompts and empty nodes are dropped
+func (c chatgptConversation) message(n chatgptNode, parent string, t *Thread) (Message, bool) {
+    src := n.Message
+    if src == nil || src.Metadata.Hidden { return Message{}, false }
+    m := Message{ID: src.ID, ParentID: parent, Role: src.Author.Role, CreatedAt: unixTime(src.CreateTime)}
+    if m.ID == "" { m.ID = n.ID }
+    if m.CreatedAt.IsZero() { m.CreatedAt = t.CreatedAt }
+    text, atts := src.text()
+    m.Content, m.Attachments = text, atts
+    switch m.Role {
+    case "assistant":
+        m.Model = src.Metadata.ModelSlug
+        if m.Model == "" { m.Model = c.DefaultModelSlug }
+        if src.Recipient != "" && src.Recipient != "all" {
+            // a call to a built-in tool (python, browser, dalle, ...)
+            args := strings.TrimSpace(text)
+            if !json.Valid([]byte(args)) {
+                b, _ := json.Marshal(map[string]string{"input": text})
+                args = string(b)
+            }
+            m.ToolCalls = []ToolCall{{ID: m.ID, Type: "function", Name: src.Recipient, Arguments: args}}
+            m.Content = ""
+        }
+    case "tool":
+        m.ToolName = src.Author.Name
+        if p, ok := t.Message(parent); ok && len(p.ToolCalls) > 0 { m.ToolCallID = p.ToolCalls[0].ID }
+    case "user":
+    default:
+        if strings.TrimSpace(m.Content) == "" { return m, false }
+    }
+    if strings.TrimSpace(m.Content) == "" && len(m.Attachments) == 0 && len(m.ToolCalls) == 0 && m.Role != "tool" {
+        return m, false
+    }
+    return m, true
+}
+
+func (m *chatgptMessage) text() (string, []Attachment) {
+    var texts []string
+    var atts []Attachment
+    for _, raw := range m.Content.Parts {
+        var s string
+        if json.Unmarshal(raw, &s) == nil {
+            if s != "" { texts = append(texts, s) }
+            continue
+        }
+        var p struct {
+            ContentType  string `json:"content_type"`
+            AssetPointer string `json:"asset_pointer"`
+            Text         string `json:"text"`
+        }
+        if json.Unmarshal(raw, &p) != nil { continue }
+        switch {
+        case p.AssetPointer != "":
+            atts = append(atts, Attachment{Type: "image_url", URL: p.AssetPointer})
+        case p.Text != "":
+            texts = append(texts, p.Text)
+        }
+    }
+    if len(texts) == 0 {
+        if m.Content.Text != "" { texts = append(texts, m.Content.Text) } else if m.Content.Result != "" { texts = append(texts, m.Content.Result) }
+    }
+    return strings.Join(texts, "\n"), atts
+}
+
+func unixTime(sec float64) time.Time {
+    if sec <= 0 { return time.Time{} }
+    whole, frac := math.Modf(sec)
+    return time.Unix(int64(whole), int64(frac*1e9)).UTC()
+}
+
+// Claude export: each conversation lists chat_messages in order; newer exports
+// link edits and retries through parent_message_uuid.
+
+type claudeConversation struct {
+    UUID         string          `json:"uuid"`
+    Name         string          `json:"name"`
+    Summary      string          `json:"summary"`
+    CreatedAt    time.Time       `json:"created_at"`
+    UpdatedAt    time.Time       `json:"updated_at"`
+    ChatMessages []claudeMessage `json:"chat_messages"`
+}
+
+type claudeMessage struct {
+    UUID        string          `json:"uuid"`
+    Text        string          `json:"text"`
+    Sender      string          `json:"sender"`
+    CreatedAt   time.Time       `json:"created_at"`
+    Content     []claudeContent `json:"content"`
+    Parent      string          `json:"parent_message_uuid"`
+    Attachments []struct {
+        FileName         string `json:"file_name"`
+        FileType         string `json:"file_type"`
+        ExtractedContent string `json:"extracted_content"`
+    } `json:"attachments"`
+    Files []struct {
+        FileName string `json:"file_name"`
+    } `json:"files"`
+}
+
+type claudeContent struct {
+    Type    string          `json:"type"`
+    Text    string          `json:"text"`
+    ID      string          `json:"id"`
+    Name    string          `json:"name"`
+    Input     json.RawMessage `json:"input"`
+    Content   json.RawMessage `json:"content"`
+    ToolUseID string          `json:"tool_use_id"`
+}
+
+// ParseClaudeExport converts a Claude conversations.json. Tool use inside an
+// assistant turn becomes assistant tool calls followed by tool messages.
+func ParseClaudeExport(data []byte) ([]*Thread, error) {
+    var convs []claudeConversation
+    if err := json.Unmarshal(data, &convs); err != nil { return nil, fmt.Errorf("claude export: %w", err) }
+    out := make([]*Thread, 0, len(convs))
+    for _, c := range convs { out = append(out, c.thread()) }
+    return out, nil
+}
+
+func (c claudeConversation) thread() *Thread {
+    t := &Thread{
+        Title: c.Name, Summary: c.Summary, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt,
+        Metadata: map[string]interface{}{"import_source": SourceClaude, "import_id": c.UUID},
+        Messages: []Message{},
+    }
+    last := map[string]string{} // chat message uuid -> ID of the last Message it produced
+    prev := ""
+    for _, cm := range c.ChatMessages {
+        parent := prev
+        if id, ok := last[cm.Parent]; ok { parent = id }
+        for _, m := range cm.messages() {
+            m.ParentID = parent
+            t.Messages = append(t.Messages, m)
+            parent = m.ID
+        }
+        if parent != "" { last[cm.UUID], prev = parent, parent }
+    }
+    t.ActiveLeafID = prev
+    return t
+}
+
+func (cm claudeMessage) messages() []Message {
+    role := "assistant"
+    if cm.Sender == "human" { role = "user" }
+    var out []Message
+    cur := Message{Role: role, CreatedAt: cm.CreatedAt}
+    var texts []string
+    callNames := map[string]string{}
+    flush := func() {
+        cur.Content = strings.Join(texts, "\n\n")
+        texts = nil
+        if cur.Content != "" || len(cur.ToolCalls) > 0 || len(cur.Attachments) > 0 { out = append(out, cur) }
+        cur = Message{Role: role, CreatedAt: cm.CreatedAt}
+    }
+    if len(cm.Content) == 0 && cm.Text != "" { texts = append(texts, cm.Text) }
+    for _, part := range cm.Content {
+        switch part.Type {
+        case "text":
+            if part.Text != "" { texts = append(texts, part.Text) }
+        case "tool_use":
+            args := string(part.Input)
+            if args == "" || args == "null" { args = "{}" }
+            cur.ToolCalls = append(cur.ToolCalls, ToolCall{ID: part.ID, Type: "function", Name: part.Name, Arguments: args})
+            callNames[part.ID] = part.Name
+        case "tool_result":
+            flush()
+            name := part.Name
+            if name == "" { name = callNames[part.ToolUseID] }
+            out = append(out, Message{Role: "tool", ToolName: name, ToolCallID: part.ToolUseID, Content: claudeResultText(part.Content), CreatedAt: cm.CreatedAt})
+        }
+    }
+    for _, a := range cm.Attachments {
+        if a.ExtractedContent != "" {
+            texts = append(texts, fmt.Sprintf("[Attachment: %s]\n%s", a.FileName, a.ExtractedContent))
+        } else {
+            cur.Attachments = append(cur.Attachments, Attachment{Type: "file", Name: a.FileName, MIMEType: a.FileType})
+        }
+    }
+    for _, f := range cm.Files { cur.Attachments = append(cur.Attachments, Attachment{Type: "file", Name: f.FileName}) }
+    flush()
+    for i := range out {
+        out[i].ID = cm.UUID
+        if i > 0 { out[i].ID = fmt.Sprintf("%s-%d", cm.UUID, i) }
+    }
+    return out
+}
+
+// claudeResultText flattens tool_result content, a string or a list of blocks
+func claudeResultText(raw json.RawMessage) string {
+    var s string
+    if json.Unmarshal(raw, &s) == nil { return s }
+    var blocks []claudeContent
+    if json.Unmarshal(raw, &blocks) != nil { return string(raw) }
+    var texts []string
+    for _, b := range blocks {
+        if b.Text != "" { texts = append(texts, b.Text) }
+    }
+    return strings.Join(texts, "\n")
+}
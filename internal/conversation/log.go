@@ -0,0 +1,248 @@
+package conversation
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "errors"
+    "io"
+    "os"
+    "time"
+
+    "github.com/pradord/llm/internal/envelope"
+)
+
+// Thread logs are JSONL files with one record per line. Writes only ever append,
+// so a crash can at worst leave a torn last line, which replay ignores and the
+// next writer truncates. Replaying the records in order rebuilds the thread:
+//   thread  - header snapshot (title, summary, metadata, active leaf, ...)
+//   message - a new message, which also becomes the active leaf
+//   active  - the active leaf moved (branch switch)
+// Compaction rewrites a log as its messages followed by one header record.
+//
+// With an encryption key every file starts with an envelope header holding its
+// wrapped data key and each following line is sealed on its own, so encrypted
+// logs remain append-only.
+
+const (
+    opThread  = "thread"
+    opMessage = "message"
+    opActive  = "active"
+)
+
+type logRecord struct {
+    Op      string        `json:"op"`
+    At      time.Time     `json:"at"`
+    V       int64         `json:"v,omitempty"` // thread version after this record
+    Thread  *threadHeader `json:"thread,omitempty"`
+    Message *Message      `json:"message,omitempty"`
+    LeafID  string        `json:"leaf_id,omitempty"`
+}
+
+// threadHeader is a Thread without its messages; it is also the index entry
+type threadHeader struct {
+    ID           string                 `json:"id"`
+    ProjectID    string                 `json:"project_id"`
+    UserID       string                 `json:"user_id,omitempty"`
+    Title        string                 `json:"title"`
+    CreatedAt    time.Time              `json:"created_at"`
+    UpdatedAt    time.Time              `json:"updated_at"`
+    Summary      string                 `json:"summary"`
+    Metadata     map[string]interface{} `json:"metadata,omitempty"`
+    ActiveLeafID string                 `json:"active_leaf_id,omitempty"`
+    MessageCount int                    `json:"message_count"`
+    Version      int64                  `json:"version,omitempty"`
+    Deleted      bool                   `json:"deleted,omitempty"` // index tombstone
+}
+
+func headerOf(t *Thread) *threadHeader {
+    return &threadHeader{
+        ID: t.ID, ProjectID: t.ProjectID, UserID: t.UserID, Title: t.Title,
+        CreatedAt: t.CreatedAt, UpdatedAt: t.UpdatedAt, Summary: t.Summary,
+        Metadata: t.Metadata, ActiveLeafID: t.ActiveLeafID, MessageCount: len(t.Messages),
+        Version: t.Version,
+    }
+}
+
+func (h *threadHeader) applyTo(t *Thread) {
+    t.ID, t.ProjectID, t.UserID, t.Title = h.ID, h.ProjectID, h.UserID, h.Title
+    t.CreatedAt, t.UpdatedAt, t.Summary = h.CreatedAt, h.UpdatedAt, h.Summary
+    t.Metadata, t.ActiveLeafID, t.Version = h.Metadata, h.ActiveLeafID, h.Version
+}
+
+// thread returns a message-less Thread for listings
+func (h *threadHeader) thread() *Thread {
+    t := &Thread{}
+    h.applyTo(t)
+    t.MessageCount = h.MessageCount
+    return t
+}
+
+// replayInfo describes a log after replay
+type replayInfo struct {
+    records  int   // valid records read
+    validEnd int64 // offset just past the last valid record; less than the file size when the tail is torn
+    file     os.FileInfo
+    dataKey  *envelope.DataKey // nil for plaintext logs
+}
+
+// unchanged reports whether st describes the same file contents that were replayed
+func (ri replayInfo) unchanged(st os.FileInfo) bool {
+    return ri.file != nil && os.SameFile(ri.file, st) && ri.file.Size() == st.Size() && ri.file.ModTime().Equal(st.ModTime())
+}
+
+// replayLog rebuilds a thread from its log file
+func replayLog(path string, key *envelope.Key) (*Thread, replayInfo, error) {
+    var info replayInfo
+    f, err := os.Open(path)
+    if err != nil { return nil, info, err }
+    defer f.Close()
+    st, err := f.Stat()
+    if err != nil { return nil, info, err }
+    info.file = st
+    t, err := replay(f, key, &info)
+    return t, info, err
+}
+
+// replay applies the records read from r to a new thread
+func replay(r io.Reader, key *envelope.Key, info *replayInfo) (*Thread, error) {
+    t := &Thread{Messages: []Message{}}
+    var err error
+    info.validEnd, info.dataKey, err = readLines(r, key, func(line []byte) {
+        var rec logRecord
+        if json.Unmarshal(line, &rec) == nil {
+            t.apply(&rec)
+            info.records++
+        }
+    })
+    if err != nil { return nil, err }
+    if info.records == 0 { return nil, errors.New("empty thread log") }
+    return t, nil
+}
+
+// readLines calls fn with every complete line of a JSONL file, decrypted when the
+// file starts with an envelope header. It returns the offset after the last
+// complete line and the file's data key.
+func readLines(r io.Reader, key *envelope.Key, fn func(line []byte)) (int64, *envelope.DataKey, error) {
+    br := bufio.NewReaderSize(r, 64*1024)
+    var off int64
+    var dk *envelope.DataKey
+    for first := true; ; first = false {
+        line, err := br.ReadBytes('\n')
+        if len(line) > 0 && line[len(line)-1] == '\n' {
+            off += int64(len(line))
+            line = bytes.TrimSpace(line)
+            switch {
+            case first && envelope.IsHeader(line):
+                var herr error
+                if dk, herr = key.OpenHeader(line); herr != nil { return off, nil, herr }
+            case dk != nil:
+                // a complete but corrupt line is skipped rather than failing the file
+                if plain, derr := dk.OpenLine(line); derr == nil { fn(plain) }
+            default:
+                fn(line)
+            }
+        }
+        if err == io.EOF { break } // a line without newline is a torn write
+        if err != nil { return off, dk, err }
+    }
+    return off, dk, nil
+}
+
+// sealLines encrypts JSONL data line by line; a nil data key leaves it plain
+func sealLines(data []byte, dk *envelope.DataKey) ([]byte, error) {
+    if dk == nil { return data, nil }
+    var buf bytes.Buffer
+    for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+        line = bytes.TrimSuffix(line, []byte("\n"))
+        if len(line) == 0 { continue }
+        sealed, err := dk.SealLine(line)
+        if err != nil { return nil, err }
+        buf.Write(sealed)
+        buf.WriteByte('\n')
+    }
+    return buf.Bytes(), nil
+}
+
+// newFileData prepares the full contents of a new JSONL file: with a key it gets
+// a fresh data key whose header becomes the first line
+func newFileData(data []byte, key *envelope.Key) ([]byte, *envelope.DataKey, error) {
+    if key == nil { return data, nil, nil }
+    dk, err := key.NewDataKey()
+    if err != nil { return nil, nil, err }
+    sealed, err := sealLines(data, dk)
+    if err != nil { return nil, nil, err }
+    out := make([]byte, 0, len(dk.Header())+1+len(sealed))
+    out = append(append(out, dk.Header()...), '\n')
+    return append(out, sealed...), dk, nil
+}
+
+func (t *Thread) apply(rec *logRecord) {
+    switch rec.Op {
+    case opThread:
+        if rec.Thread != nil { rec.Thread.applyTo(t) }
+    case opMessage:
+        if rec.Message != nil {
+            t.Messages = append(t.Messages, *rec.Message)
+            t.ActiveLeafID = rec.Message.ID
+            if rec.At.After(t.UpdatedAt) { t.UpdatedAt = rec.At }
+        }
+    case opActive:
+        t.ActiveLeafID = rec.LeafID
+        if rec.At.After(t.UpdatedAt) { t.UpdatedAt = rec.At }
+    }
+    if rec.V > t.Version { t.Version = rec.V }
+    t.MessageCount = len(t.Messages)
+}
+
+// encodeRecords renders records as JSONL
+func encodeRecords(recs []logRecord) ([]byte, error) {
+    var buf bytes.Buffer
+    for i := range recs {
+        line, err := json.Marshal(&recs[i])
+        if err != nil { return nil, err }
+        buf.Write(line)
+        buf.WriteByte('\n')
+    }
+    return buf.Bytes(), nil
+}
+
+// snapshotRecords is the compacted form of a thread
+func snapshotRecords(t *Thread) []logRecord {
+    recs := make([]logRecord, 0, len(t.Messages)+1)
+    for i := range t.Messages {
+        m := t.Messages[i]
+        recs = append(recs, logRecord{Op: opMessage, At: m.CreatedAt, V: t.Version, Message: &m})
+    }
+    return append(recs, logRecord{Op: opThread, At: t.UpdatedAt, V: t.Version, Thread: headerOf(t)})
+}
+
+// appendFile writes data at the end of path in a single write, first cutting off
+// a torn tail left by a crashed writer
+func appendFile(path string, data []byte, validEnd int64, sync bool) error {
+    f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o600)
+    if err != nil { return err }
+    defer f.Close()
+    st, err := f.Stat()
+    if err != nil { return err }
+    if validEnd < st.Size() {
+        if err := f.Truncate(validEnd); err != nil { return err }
+    }
+    if _, err := f.Seek(validEnd, io.SeekStart); err != nil { return err }
+    if _, err := f.Write(data); err != nil { return err }
+    if sync { return f.Sync() }
+    return nil
+}
+
+// writeFileAtomic replaces path via a temp file and rename
+func writeFileAtomic(path string, data []byte, sync bool) error {
+    tmp := path + ".tmp"
+    f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+    if err != nil { return err }
+    if _, err := f.Write(data); err != nil { f.Close(); return err }
+    if sync {
+        if err := f.Sync(); err != nil { f.Close(); return err }
+    }
+    if err := f.Close(); err != nil { return err }
+    return os.Rename(tmp, path)
+}
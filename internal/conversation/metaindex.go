@@ -0,0 +1,108 @@
+package conversation
+
+import (
+    "bytes"
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "github.com/pradord/llm/internal/envelope"
+)
+
+// The metadata index is a JSONL file of thread headers; the last line for an ID
+// wins and a deleted header is a tombstone. ListThreads reads only this file.
+// It is rewritten once stale lines outnumber live threads.
+
+const indexFile = "_index.jsonl"
+
+type metaIndex struct {
+    headers  map[string]*threadHeader
+    lines    int
+    validEnd int64
+    file     os.FileInfo
+    dataKey  *envelope.DataKey // nil for a plaintext index
+}
+
+func loadMetaIndex(path string, key *envelope.Key) (*metaIndex, error) {
+    idx := &metaIndex{headers: map[string]*threadHeader{}}
+    f, err := os.Open(path)
+    if err != nil { return nil, err }
+    defer f.Close()
+    st, err := f.Stat()
+    if err != nil { return nil, err }
+    idx.file = st
+    idx.validEnd, idx.dataKey, err = readLines(f, key, func(line []byte) {
+        var h threadHeader
+        if json.Unmarshal(line, &h) == nil && h.ID != "" {
+            idx.lines++
+            if h.Deleted { delete(idx.headers, h.ID) } else { idx.headers[h.ID] = &h }
+        }
+    })
+    if err != nil { return nil, err }
+    return idx, nil
+}
+
+func (idx *metaIndex) needsCompaction() bool {
+    return idx.lines > 2*len(idx.headers)+64
+}
+
+// sorted returns live headers ordered by ID
+func (idx *metaIndex) sorted() []*threadHeader {
+    out := make([]*threadHeader, 0, len(idx.headers))
+    for _, h := range idx.headers { out = append(out, h) }
+    sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+    return out
+}
+
+func (idx *metaIndex) encode() ([]byte, error) {
+    return encodeHeaders(idx.sorted()...)
+}
+
+func encodeHeaders(hs ...*threadHeader) ([]byte, error) {
+    var buf bytes.Buffer
+    for _, h := range hs {
+        line, err := json.Marshal(h)
+        if err != nil { return nil, err }
+        buf.Write(line)
+        buf.WriteByte('\n')
+    }
+    return buf.Bytes(), nil
+}
+
+// rebuildMetaIndex scans every thread log (and legacy .json file) in dir
+func rebuildMetaIndex(dir string, key *envelope.Key) (*metaIndex, error) {
+    idx := &metaIndex{headers: map[string]*threadHeader{}}
+    entries, err := os.ReadDir(dir)
+    if err != nil { return nil, err }
+    for _, e := range entries {
+        name := e.Name()
+        if e.IsDir() || strings.HasPrefix(name, "_") { continue }
+        var t *Thread
+        switch {
+        case strings.HasSuffix(name, ".jsonl"):
+            t, _, err = replayLog(filepath.Join(dir, name), key)
+        case strings.HasSuffix(name, ".json"):
+            if _, dup := idx.headers[strings.TrimSuffix(name, ".json")]; dup { continue }
+            t, err = readLegacy(filepath.Join(dir, name))
+        default:
+            continue
+        }
+        if err != nil || t == nil || t.ID == "" { continue }
+        idx.headers[t.ID] = headerOf(t)
+        idx.lines++
+    }
+    return idx, nil
+}
+
+// readLegacy reads a thread written as a single JSON document
+func readLegacy(path string) (*Thread, error) {
+    data, err := os.ReadFile(path)
+    if err != nil { return nil, err }
+    var t Thread
+    if err := json.Unmarshal(data, &t); err != nil { return nil, err }
+    t.normalize()
+    t.MessageCount = len(t.Messages)
+    return &t, nil
+}
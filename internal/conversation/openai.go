@@ -0,0 +1,180 @@
+package conversation
+
+import (
+    "fmt"
+    "strings"
+)
+
+// OpenAI-compatible message conversion. agent.Executor works on
+// []map[string]interface{} in the chat completions schema; these helpers turn a
+// transcript into stored Messages and back without losing tool turns.
+
+// OpenAIMessages returns the active branch as chat completion messages, ready to
+// resume an agent run with the exact array that was stored
+func (t *Thread) OpenAIMessages() []map[string]interface{} {
+    return ToOpenAI(t.ActivePath())
+}
+
+// ToOpenAI converts stored messages to chat completion messages
+func ToOpenAI(msgs []Message) []map[string]interface{} {
+    out := make([]map[string]interface{}, 0, len(msgs))
+    for _, m := range msgs { out = append(out, m.OpenAI()) }
+    return out
+}
+
+// OpenAI converts a single message to the chat completions schema. Messages with
+// attachments use content parts with the text part first.
+func (m Message) OpenAI() map[string]interface{} {
+    out := map[string]interface{}{"role": m.Role}
+    if len(m.Attachments) == 0 {
+        out["content"] = m.Content
+    } else {
+        parts := []map[string]interface{}{}
+        if m.Content != "" { parts = append(parts, map[string]interface{}{"type": "text", "text": m.Content}) }
+        for _, a := range m.Attachments { parts = append(parts, a.part()) }
+        out["content"] = parts
+    }
+    if m.Name != "" { out["name"] = m.Name }
+    if m.ToolCallID != "" { out["tool_call_id"] = m.ToolCallID }
+    if len(m.ToolCalls) > 0 {
+        calls := make([]map[string]interface{}, 0, len(m.ToolCalls))
+        for _, c := range m.ToolCalls {
+            typ := c.Type
+            if typ == "" { typ = "function" }
+            calls = append(calls, map[string]interface{}{
+                "id":   c.ID,
+                "type": typ,
+                "function": map[string]interface{}{
+                    "name":      c.Name,
+                    "arguments": c.Arguments,
+                },
+            })
+        }
+        out["tool_calls"] = calls
+    }
+    return out
+}
+
+func (a Attachment) part() map[string]interface{} {
+    switch a.Type {
+    case "input_audio":
+        return map[string]interface{}{"type": a.Type, "input_audio": map[string]interface{}{"data": a.Data, "format": a.MIMEType}}
+    case "file":
+        f := map[string]interface{}{}
+        if a.Name != "" { f["filename"] = a.Name }
+        if a.Data != "" { f["file_data"] = a.Data }
+        if a.URL != "" { f["file_url"] = a.URL }
+        return map[string]interface{}{"type": a.Type, "file": f}
+    default:
+        img := map[string]interface{}{"url": a.URL}
+        if a.Detail != "" { img["detail"] = a.Detail }
+        return map[string]interface{}{"type": "image_url", "image_url": img}
+    }
+}
+
+// MessagesFromOpenAI converts an agent transcript into Messages. model is recorded
+// on assistant messages; tool results get ToolName from the call they answer.
+func MessagesFromOpenAI(msgs []map[string]interface{}, model string) ([]Message, error) {
+    out := make([]Message, 0, len(msgs))
+    callNames := map[string]string{}
+    for i, raw := range msgs {
+        m, err := messageFromOpenAI(raw)
+        if err != nil { return nil, fmt.Errorf("message %d: %w", i, err) }
+        for _, c := range m.ToolCalls { callNames[c.ID] = c.Name }
+        if m.Role == "tool" && m.ToolName == "" { m.ToolName = callNames[m.ToolCallID] }
+        if m.Role == "assistant" && m.Model == "" { m.Model = model }
+        out = append(out, m)
+    }
+    return out, nil
+}
+
+func messageFromOpenAI(raw map[string]interface{}) (Message, error) {
+    var m Message
+    role, _ := raw["role"].(string)
+    if role == "" { return m, fmt.Errorf("missing role") }
+    m.Role = role
+    m.Name, _ = raw["name"].(string)
+    m.ToolCallID, _ = raw["tool_call_id"].(string)
+    switch c := raw["content"].(type) {
+    case nil:
+    case string:
+        m.Content = c
+    case []map[string]interface{}:
+        m.Content, m.Attachments = fromParts(toAnySlice(c))
+    case []interface{}:
+        m.Content, m.Attachments = fromParts(c)
+    default:
+        return m, fmt.Errorf("unsupported content type %T", c)
+    }
+    switch calls := raw["tool_calls"].(type) {
+    case nil:
+    case []map[string]interface{}:
+        m.ToolCalls = fromToolCalls(toAnySlice(calls))
+    case []interface{}:
+        m.ToolCalls = fromToolCalls(calls)
+    default:
+        return m, fmt.Errorf("unsupported tool_calls type %T", calls)
+    }
+    return m, nil
+}
+
+func toAnySlice(in []map[string]interface{}) []interface{} {
+    out := make([]interface{}, len(in))
+    for i := range in { out[i] = in[i] }
+    return out
+}
+
+func fromToolCalls(in []interface{}) []ToolCall {
+    var out []ToolCall
+    for _, item := range in {
+        c, ok := item.(map[string]interface{})
+        if !ok { continue }
+        tc := ToolCall{}
+        tc.ID, _ = c["id"].(string)
+        tc.Type, _ = c["type"].(string)
+        if fn, ok := c["function"].(map[string]interface{}); ok {
+            tc.Name, _ = fn["name"].(string)
+            tc.Arguments, _ = fn["arguments"].(string)
+        }
+        out = append(out, tc)
+    }
+    return out
+}
+
+func fromParts(parts []interface{}) (string, []Attachment) {
+    var texts []string
+    var atts []Attachment
+    for _, item := range parts {
+        p, ok := item.(map[string]interface{})
+        if !ok { continue }
+        typ, _ := p["type"].(string)
+        switch typ {
+        case "text":
+            s, _ := p["text"].(string)
+            texts = append(texts, s)
+        case "image_url":
+            a := Attachment{Type: "image_url"}
+            if img, ok := p["image_url"].(map[string]interface{}); ok {
+                a.URL, _ = img["url"].(string)
+                a.Detail, _ = img["detail"].(string)
+            }
+            atts = append(atts, a)
+        case "input_audio":
+            a := Attachment{Type: typ}
+            if au, ok := p["input_audio"].(map[string]interface{}); ok {
+                a.Data, _ = au["data"].(string)
+                a.MIMEType, _ = au["format"].(string)
+            }
+            atts = append(atts, a)
+        case "file":
+            a := Attachment{Type: typ}
+            if f, ok := p["file"].(map[string]interface{}); ok {
+                a.Name, _ = f["filename"].(string)
+                a.Data, _ = f["file_data"].(string)
+                a.URL, _ = f["file_url"].(string)
+            }
+            atts = append(atts, a)
+        }
+    }
+    return strings.Join(texts, "\n"), atts
+}
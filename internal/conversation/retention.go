@@ -0,0 +1,188 @@
+package conversation
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+)
+
+// Deletion, archiving and retention. Archived threads move to <dir>/archive and
+// drop out of ListThreads and search; RestoreThread brings them back. Deleting
+// rewrites the index so no title or metadata of the thread is left behind.
+
+const archiveDir = "archive"
+
+const (
+    RetentionArchive = "archive"
+    RetentionDelete  = "delete"
+)
+
+// RetentionPolicy limits how long and how many threads of one project are kept
+type RetentionPolicy struct {
+    ProjectID  string        // threads of this project; empty matches threads without a project
+    MaxAge     time.Duration // expire threads not updated for longer than this; 0 = no limit
+    MaxThreads int           // keep only the most recently updated threads; 0 = no limit
+    Action     string        // RetentionArchive (default) or RetentionDelete
+    DryRun     bool          // report what would be expired without changing anything
+}
+
+// RetentionReport lists the threads a policy expired
+type RetentionReport struct {
+    Archived []string `json:"archived,omitempty"`
+    Deleted  []string `json:"deleted,omitempty"`
+}
+
+func (fs *FileStore) archivePath(id string) string { return filepath.Join(fs.dir, archiveDir, id+".jsonl") }
+
+// DeleteThread permanently removes a thread
+func (fs *FileStore) DeleteThread(id string) error {
+    unlock, err := fs.lock(true)
+    if err != nil { return err }
+    defer unlock()
+    idx, err := fs.indexLocked(true)
+    if err != nil { return err }
+    if err := fs.deleteLocked(idx, id); err != nil { return err }
+    return fs.writeIndexLocked(idx)
+}
+
+// ArchiveThread moves a thread out of the live store
+func (fs *FileStore) ArchiveThread(id string) error {
+    unlock, err := fs.lock(true)
+    if err != nil { return err }
+    defer unlock()
+    idx, err := fs.indexLocked(true)
+    if err != nil { return err }
+    if err := fs.archiveLocked(idx, id); err != nil { return err }
+    return fs.writeIndexLocked(idx)
+}
+
+// RestoreThread moves an archived thread back into the live store
+func (fs *FileStore) RestoreThread(id string) (*Thread, error) {
+    unlock, err := fs.lock(true)
+    if err != nil { return nil, err }
+    defer unlock()
+    if _, err := os.Stat(fs.logPath(id)); err == nil { return nil, fmt.Errorf("thread %s already exists", id) }
+    if err := os.Rename(fs.archivePath(id), fs.logPath(id)); err != nil { return nil, err }
+    c, err := fs.loadLocked(id, true)
+    if err != nil { return nil, err }
+    if err := fs.indexPutLocked(headerOf(c.t)); err != nil { return nil, err }
+    fs.notifyLocked(c.t, nil)
+    return c.t.clone(), nil
+}
+
+// ListArchived returns archived threads without their messages
+func (fs *FileStore) ListArchived() ([]*Thread, error) {
+    unlock, err := fs.lock(false)
+    if err != nil { return nil, err }
+    defer unlock()
+    var out []*Thread
+    err = fs.eachArchivedLocked(func(id string, t *Thread) error {
+        out = append(out, headerOf(t).thread())
+        return nil
+    })
+    return out, err
+}
+
+// ApplyRetention archives or deletes the threads of p.ProjectID that are older
+// than p.MaxAge or beyond the p.MaxThreads most recently updated
+func (fs *FileStore) ApplyRetention(p RetentionPolicy) (*RetentionReport, error) {
+    if p.Action == "" { p.Action = RetentionArchive }
+    if p.Action != RetentionArchive && p.Action != RetentionDelete {
+        return nil, fmt.Errorf("unknown retention action %q (want archive or delete)", p.Action)
+    }
+    unlock, err := fs.lock(true)
+    if err != nil { return nil, err }
+    defer unlock()
+    idx, err := fs.indexLocked(true)
+    if err != nil { return nil, err }
+
+    var hs []*threadHeader
+    for _, h := range idx.headers {
+        if h.ProjectID == p.ProjectID { hs = append(hs, h) }
+    }
+    sort.Slice(hs, func(i, j int) bool { return hs[i].UpdatedAt.After(hs[j].UpdatedAt) })
+    cutoff := time.Now().Add(-p.MaxAge)
+    report := &RetentionReport{}
+    for i, h := range hs {
+        expired := (p.MaxAge > 0 && h.UpdatedAt.Before(cutoff)) || (p.MaxThreads > 0 && i >= p.MaxThreads)
+        if !expired { continue }
+        if p.Action == RetentionDelete {
+            report.Deleted = append(report.Deleted, h.ID)
+        } else {
+            report.Archived = append(report.Archived, h.ID)
+        }
+        if p.DryRun { continue }
+        if p.Action == RetentionDelete { err = fs.deleteLocked(idx, h.ID) } else { err = fs.archiveLocked(idx, h.ID) }
+        if err != nil { break }
+    }
+    if p.DryRun || len(report.Archived)+len(report.Deleted) == 0 { return report, err }
+    if werr := fs.writeIndexLocked(idx); err == nil { err = werr }
+    return report, err
+}
+
+// PurgeUser permanently deletes every live and archived thread owned by userID,
+// e.g. for a data deletion request, and returns how many were removed
+func (fs *FileStore) PurgeUser(userID string) (int, error) {
+    if userID == "" { return 0, fmt.Errorf("purge: user ID is required") }
+    unlock, err := fs.lock(true)
+    if err != nil { return 0, err }
+    defer unlock()
+    idx, err := fs.indexLocked(true)
+    if err != nil { return 0, err }
+    n := 0
+    for id, h := range idx.headers {
+        if h.UserID != userID { continue }
+        if err := fs.deleteLocked(idx, id); err != nil { return n, err }
+        n++
+    }
+    err = fs.eachArchivedLocked(func(id string, t *Thread) error {
+        if t.UserID != userID { return nil }
+        if err := os.Remove(fs.archivePath(id)); err != nil { return err }
+        n++
+        return nil
+    })
+    if err != nil { return n, err }
+    return n, fs.writeIndexLocked(idx)
+}
+
+// deleteLocked removes a thread's files; the caller rewrites the index
+func (fs *FileStore) deleteLocked(idx *metaIndex, id string) error {
+    found := false
+    for _, path := range []string{fs.logPath(id), fs.legacyPath(id)} {
+        err := os.Remove(path)
+        if err == nil { found = true } else if !os.IsNotExist(err) { return err }
+    }
+    if !found { return fmt.Errorf("thread %s: %w", id, os.ErrNotExist) }
+    delete(fs.cache, id)
+    delete(idx.headers, id)
+    for _, l := range fs.listeners { l.ThreadDeleted(id) }
+    return nil
+}
+
+// archiveLocked moves a thread's log to the archive; the caller rewrites the index
+func (fs *FileStore) archiveLocked(idx *metaIndex, id string) error {
+    if _, err := fs.loadLocked(id, true); err != nil { return err } // migrates legacy threads to a log
+    if err := os.MkdirAll(filepath.Join(fs.dir, archiveDir), 0o700); err != nil { return err }
+    if err := os.Rename(fs.logPath(id), fs.archivePath(id)); err != nil { return err }
+    delete(fs.cache, id)
+    delete(idx.headers, id)
+    for _, l := range fs.listeners { l.ThreadDeleted(id) }
+    return nil
+}
+
+func (fs *FileStore) eachArchivedLocked(fn func(id string, t *Thread) error) error {
+    entries, err := os.ReadDir(filepath.Join(fs.dir, archiveDir))
+    if os.IsNotExist(err) { return nil }
+    if err != nil { return err }
+    for _, e := range entries {
+        if e.IsDir() || !strings.HasSuffix(e.Name(), ".jsonl") { continue }
+        id := strings.TrimSuffix(e.Name(), ".jsonl")
+        t, _, err := replayLog(fs.archivePath(id), fs.cfg.Key)
+        if err != nil { return fmt.Errorf("archived thread %s: %w", id, err) }
+        if err := fn(id, t); err != nil { return err }
+    }
+    return nil
+}
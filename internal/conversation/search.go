@@ -0,0 +1,397 @@
+package conversation
+
+import (
+    "encoding/json"
+    "math"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+    "unicode"
+
+    "github.com/pradord/llm/internal/envelope"
+)
+
+// SearchIndex is an in-memory inverted index over thread titles, summaries and
+// message content, ranked with BM25. Register it on a FileStore with AddListener
+// to keep it current, call Sync to catch up with writes made elsewhere, and Save
+// to persist it next to the threads.
+type SearchIndex struct {
+    mu       sync.RWMutex
+    path     string
+    key      *envelope.Key
+    docs     map[string]*searchDoc
+    postings map[string]map[string]int // term -> docID -> term frequency
+    totalLen int
+    threads  map[string]*indexedThread
+}
+
+// SearchOptions filters and shapes search results
+type SearchOptions struct {
+    ProjectID      string
+    Role           string    // only messages with this role; excludes title/summary hits
+    From, To       time.Time // inclusive bounds on message (or thread) creation time
+    Limit          int       // default 20
+    SnippetChars   int       // context around the first match, default 160
+    HighlightStart string    // default "**"
+    HighlightEnd   string    // default "**"
+}
+
+// SearchResult is one matching title, summary or message
+type SearchResult struct {
+    ThreadID    string    `json:"thread_id"`
+    ThreadTitle string    `json:"thread_title"`
+    ProjectID   string    `json:"project_id,omitempty"`
+    MessageID   string    `json:"message_id,omitempty"`
+    Field       string    `json:"field"` // title|summary|message
+    Role        string    `json:"role,omitempty"`
+    CreatedAt   time.Time `json:"created_at"`
+    Score       float64   `json:"score"`
+    Snippet     string    `json:"snippet"`
+}
+
+type searchDoc struct {
+    ID        string    `json:"id"`
+    ThreadID  string    `json:"thread_id"`
+    MessageID string    `json:"message_id,omitempty"`
+    Field     string    `json:"field"`
+    Role      string    `json:"role,omitempty"`
+    CreatedAt time.Time `json:"created_at"`
+    Text      string    `json:"text"`
+    length    int
+}
+
+type indexedThread struct {
+    Title     string   `json:"title"`
+    ProjectID string   `json:"project_id,omitempty"`
+    Version   int64    `json:"version"`
+    Messages  int      `json:"messages"`
+    Docs      []string `json:"docs"`
+}
+
+type searchFile struct {
+    Threads map[string]*indexedThread `json:"threads"`
+    Docs    []*searchDoc             `json:"docs"`
+}
+
+const (
+    bm25K1 = 1.2
+    bm25B  = 0.75
+)
+
+var fieldBoost = map[string]float64{"title": 2.0, "summary": 1.5, "message": 1.0}
+
+// NewSearchIndex returns an empty, unpersisted index
+func NewSearchIndex() *SearchIndex {
+    return &SearchIndex{
+        docs:     map[string]*searchDoc{},
+        postings: map[string]map[string]int{},
+        threads:  map[string]*indexedThread{},
+    }
+}
+
+// OpenSearchIndex loads an index saved at path, or returns an empty index that
+// Save will write there
+func OpenSearchIndex(path string) (*SearchIndex, error) {
+    return OpenSearchIndexWithKey(path, nil)
+}
+
+// OpenSearchIndexWithKey is OpenSearchIndex for an index encrypted at rest; use
+// the key of the FileStore it indexes, since it holds message text
+func OpenSearchIndexWithKey(path string, key *envelope.Key) (*SearchIndex, error) {
+    idx := NewSearchIndex()
+    idx.path, idx.key = path, key
+    data, err := os.ReadFile(path)
+    if os.IsNotExist(err) { return idx, nil }
+    if err != nil { return nil, err }
+    if data, err = key.Open(data); err != nil { return nil, err }
+    var f searchFile
+    if err := json.Unmarshal(data, &f); err != nil { return nil, err }
+    for id, th := range f.Threads { idx.threads[id] = th }
+    for _, d := range f.Docs { idx.addDoc(d) }
+    return idx, nil
+}
+
+// SearchIndexPath is where the CLI keeps the index for a thread directory
+func SearchIndexPath(threadsDir string) string {
+    return filepath.Join(threadsDir, "_search.json")
+}
+
+// Save writes the index to the path it was opened from
+func (idx *SearchIndex) Save() error {
+    if idx.path == "" { return nil }
+    idx.mu.RLock()
+    f := searchFile{Threads: idx.threads}
+    for _, d := range idx.docs { f.Docs = append(f.Docs, d) }
+    sort.Slice(f.Docs, func(i, j int) bool { return f.Docs[i].ID < f.Docs[j].ID })
+    data, err := json.Marshal(f)
+    idx.mu.RUnlock()
+    if err != nil { return err }
+    if data, err = idx.key.Seal(data); err != nil { return err }
+    return writeFileAtomic(idx.path, data, false)
+}
+
+// Sync brings the index up to date with a store: threads whose version changed
+// are re-indexed and threads that no longer exist are dropped
+func (idx *SearchIndex) Sync(store Store) error {
+    list, err := store.ListThreads()
+    if err != nil { return err }
+    live := make(map[string]bool, len(list))
+    for _, h := range list {
+        live[h.ID] = true
+        idx.mu.RLock()
+        cur, ok := idx.threads[h.ID]
+        idx.mu.RUnlock()
+        if ok && cur.Version == h.Version && cur.Messages == h.MessageCount { continue }
+        t, err := store.GetThread(h.ID)
+        if err != nil { continue }
+        idx.IndexThread(t)
+    }
+    idx.mu.Lock()
+    for id := range idx.threads {
+        if !live[id] { idx.removeThreadLocked(id) }
+    }
+    idx.mu.Unlock()
+    return nil
+}
+
+// IndexThread replaces everything indexed for t, including all branches
+func (idx *SearchIndex) IndexThread(t *Thread) {
+    idx.mu.Lock(); defer idx.mu.Unlock()
+    idx.removeThreadLocked(t.ID)
+    it := &indexedThread{Title: t.Title, ProjectID: t.ProjectID, Version: t.Version, Messages: len(t.Messages)}
+    idx.threads[t.ID] = it
+    idx.putThreadFieldsLocked(t, it)
+    for _, m := range t.Messages { idx.putMessageLocked(t.ID, it, m) }
+}
+
+// RemoveThread drops a thread from the index
+func (idx *SearchIndex) RemoveThread(id string) {
+    idx.mu.Lock(); defer idx.mu.Unlock()
+    idx.removeThreadLocked(id)
+}
+
+// ThreadChanged implements Listener
+func (idx *SearchIndex) ThreadChanged(t *Thread) {
+    idx.mu.Lock()
+    it, ok := idx.threads[t.ID]
+    if !ok || it.Messages != len(t.Messages) || t.Messages == nil || !idx.messagesIndexedLocked(t) {
+        idx.mu.Unlock()
+        idx.IndexThread(t)
+        return
+    }
+    defer idx.mu.Unlock()
+    it.Title, it.ProjectID, it.Version = t.Title, t.ProjectID, t.Version
+    idx.putThreadFieldsLocked(t, it)
+}
+
+// messagesIndexedLocked reports whether the index holds t's messages as they
+// are, so a rewrite that edits messages without adding any is re-indexed
+func (idx *SearchIndex) messagesIndexedLocked(t *Thread) bool {
+    for _, m := range t.Messages {
+        d, ok := idx.docs[t.ID+"/"+m.ID]
+        if strings.TrimSpace(m.Content) == "" {
+            if ok { return false }
+            continue
+        }
+        if !ok || d.Text != m.Content || d.Role != m.Role { return false }
+    }
+    return true
+}
+
+// MessagesAppended implements Listener
+func (idx *SearchIndex) MessagesAppended(t *Thread, msgs []Message) {
+    idx.mu.Lock()
+    it, ok := idx.threads[t.ID]
+    if !ok || it.Messages+len(msgs) != len(t.Messages) {
+        idx.mu.Unlock()
+        idx.IndexThread(t)
+        return
+    }
+    defer idx.mu.Unlock()
+    for _, m := range msgs { idx.putMessageLocked(t.ID, it, m) }
+    it.Messages, it.Version = len(t.Messages), t.Version
+}
+
+// ThreadDeleted implements Listener
+func (idx *SearchIndex) ThreadDeleted(id string) { idx.RemoveThread(id) }
+
+func (idx *SearchIndex) putThreadFieldsLocked(t *Thread, it *indexedThread) {
+    for _, f := range []struct{ field, text string }{{"title", t.Title}, {"summary", t.Summary}} {
+        id := t.ID + "/" + f.field
+        idx.removeDocLocked(id)
+        if strings.TrimSpace(f.text) == "" { continue }
+        idx.addDoc(&searchDoc{ID: id, ThreadID: t.ID, Field: f.field, CreatedAt: t.CreatedAt, Text: f.text})
+        it.Docs = appendUnique(it.Docs, id)
+    }
+}
+
+func (idx *SearchIndex) putMessageLocked(threadID string, it *indexedThread, m Message) {
+    if strings.TrimSpace(m.Content) == "" { return }
+    id := threadID + "/" + m.ID
+    existed := idx.removeDocLocked(id)
+    idx.addDoc(&searchDoc{ID: id, ThreadID: threadID, MessageID: m.ID, Field: "message", Role: m.Role, CreatedAt: m.CreatedAt, Text: m.Content})
+    if !existed { it.Docs = append(it.Docs, id) }
+}
+
+func (idx *SearchIndex) addDoc(d *searchDoc) {
+    terms := tokenize(d.Text)
+    d.length = len(terms)
+    idx.docs[d.ID] = d
+    idx.totalLen += d.length
+    for _, term := range terms {
+        p := idx.postings[term]
+        if p == nil { p = map[string]int{}; idx.postings[term] = p }
+        p[d.ID]++
+    }
+}
+
+func (idx *SearchIndex) removeDocLocked(id string) bool {
+    d, ok := idx.docs[id]
+    if !ok { return false }
+    for _, term := range tokenize(d.Text) {
+        if p := idx.postings[term]; p != nil {
+            delete(p, id)
+            if len(p) == 0 { delete(idx.postings, term) }
+        }
+    }
+    idx.totalLen -= d.length
+    delete(idx.docs, id)
+    return true
+}
+
+func (idx *SearchIndex) removeThreadLocked(id string) {
+    it, ok := idx.threads[id]
+    if !ok { return }
+    for _, d := range it.Docs { idx.removeDocLocked(d) }
+    delete(idx.threads, id)
+}
+
+// Search ranks matching documents with BM25 and returns highlighted snippets
+func (idx *SearchIndex) Search(query string, opts SearchOptions) []SearchResult {
+    if opts.Limit <= 0 { opts.Limit = 20 }
+    if opts.SnippetChars <= 0 { opts.SnippetChars = 160 }
+    if opts.HighlightStart == "" && opts.HighlightEnd == "" { opts.HighlightStart, opts.HighlightEnd = "**", "**" }
+    terms := uniqueTerms(tokenize(query))
+    if len(terms) == 0 { return nil }
+
+    idx.mu.RLock(); defer idx.mu.RUnlock()
+    n := float64(len(idx.docs))
+    if n == 0 { return nil }
+    avg := float64(idx.totalLen) / n
+    scores := map[string]float64{}
+    for _, term := range terms {
+        p := idx.postings[term]
+        if len(p) == 0 { continue }
+        idf := math.Log(1 + (n-float64(len(p))+0.5)/(float64(len(p))+0.5))
+        for docID, tf := range p {
+            d := idx.docs[docID]
+            if !idx.matches(d, opts) { continue }
+            f := float64(tf)
+            scores[docID] += fieldBoost[d.Field] * idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*float64(d.length)/avg))
+        }
+    }
+    ids := make([]string, 0, len(scores))
+    for id := range scores { ids = append(ids, id) }
+    sort.Slice(ids, func(i, j int) bool {
+        if scores[ids[i]] != scores[ids[j]] { return scores[ids[i]] > scores[ids[j]] }
+        return idx.docs[ids[i]].CreatedAt.After(idx.docs[ids[j]].CreatedAt)
+    })
+    if len(ids) > opts.Limit { ids = ids[:opts.Limit] }
+    out := make([]SearchResult, 0, len(ids))
+    for _, id := range ids {
+        d := idx.docs[id]
+        th := idx.threads[d.ThreadID]
+        r := SearchResult{ThreadID: d.ThreadID, MessageID: d.MessageID, Field: d.Field, Role: d.Role, CreatedAt: d.CreatedAt, Score: scores[id]}
+        if th != nil { r.ThreadTitle, r.ProjectID = th.Title, th.ProjectID }
+        r.Snippet = snippet(d.Text, terms, opts.SnippetChars, opts.HighlightStart, opts.HighlightEnd)
+        out = append(out, r)
+    }
+    return out
+}
+
+func (idx *SearchIndex) matches(d *searchDoc, opts SearchOptions) bool {
+    if opts.ProjectID != "" {
+        th := idx.threads[d.ThreadID]
+        if th == nil || th.ProjectID != opts.ProjectID { return false }
+    }
+    if opts.Role != "" && d.Role != opts.Role { return false }
+    if !opts.From.IsZero() && d.CreatedAt.Before(opts.From) { return false }
+    if !opts.To.IsZero() && d.CreatedAt.After(opts.To) { return false }
+    return true
+}
+
+var stopWords = map[string]bool{
+    "a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+    "for": true, "from": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+    "that": true, "the": true, "this": true, "to": true, "was": true, "with": true,
+}
+
+// tokenize lowercases text and splits it into letter/digit runs without stop words
+func tokenize(text string) []string {
+    var out []string
+    for _, w := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+        if !stopWords[w] { out = append(out, w) }
+    }
+    return out
+}
+
+func isSeparator(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }
+
+func uniqueTerms(terms []string) []string {
+    seen := map[string]bool{}
+    var out []string
+    for _, t := range terms {
+        if !seen[t] { seen[t] = true; out = append(out, t) }
+    }
+    return out
+}
+
+func appendUnique(list []string, s string) []string {
+    for _, x := range list {
+        if x == s { return list }
+    }
+    return append(list, s)
+}
+
+// snippet cuts a window of about width runes around the first query term and
+// wraps every term occurrence inside it with the highlight markers
+func snippet(text string, terms []string, width int, pre, post string) string {
+    want := map[string]bool{}
+    for _, t := range terms { want[t] = true }
+    runes := []rune(text)
+    type span struct{ start, end int }
+    var hits []span
+    for i := 0; i < len(runes); {
+        if isSeparator(runes[i]) { i++; continue }
+        j := i
+        for j < len(runes) && !isSeparator(runes[j]) { j++ }
+        if want[strings.ToLower(string(runes[i:j]))] { hits = append(hits, span{i, j}) }
+        i = j
+    }
+    start, end := 0, len(runes)
+    if len(hits) > 0 && len(runes) > width {
+        start = hits[0].start - width/3
+        if start < 0 { start = 0 }
+        end = start + width
+        if end > len(runes) { end = len(runes); start = end - width; if start < 0 { start = 0 } }
+    } else if len(runes) > width {
+        end = width
+    }
+    var b strings.Builder
+    if start > 0 { b.WriteString("…") }
+    pos := start
+    for _, h := range hits {
+        if h.start < start || h.end > end { continue }
+        b.WriteString(string(runes[pos:h.start]))
+        b.WriteString(pre)
+        b.WriteString(string(runes[h.start:h.end]))
+        b.WriteString(post)
+        pos = h.end
+    }
+    b.WriteString(string(runes[pos:end]))
+    if end < len(runes) { b.WriteString("…") }
+    return strings.Join(strings.Fields(b.String()), " ")
+}
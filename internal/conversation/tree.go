@@ -0,0 +1,165 @@
+package conversation
+
+import (
+    "fmt"
+    "sort"
+    "time"
+)
+
+// Threads store every message ever written, linked through ParentID into a tree.
+// The active branch is the path from the root to ActiveLeafID; editing a user
+// message or regenerating an assistant reply adds a sibling and moves the leaf.
+
+// Branch describes one root-to-leaf path in a thread
+type Branch struct {
+    LeafID    string    `json:"leaf_id"`
+    Length    int       `json:"length"`
+    Preview   string    `json:"preview"` // content of the leaf message, truncated
+    UpdatedAt time.Time `json:"updated_at"`
+    Active    bool      `json:"active"`
+}
+
+// normalize upgrades threads written before branching existed. Those have no
+// active leaf, so their messages are chained in slice order and the last one
+// becomes active. Threads with an active leaf already carry explicit parents.
+func (t *Thread) normalize() {
+    if t.ActiveLeafID != "" || len(t.Messages) == 0 { return }
+    for i := 1; i < len(t.Messages); i++ {
+        if t.Messages[i].ParentID == "" { t.Messages[i].ParentID = t.Messages[i-1].ID }
+    }
+    t.ActiveLeafID = t.Messages[len(t.Messages)-1].ID
+}
+
+func (t *Thread) index() map[string]int {
+    idx := make(map[string]int, len(t.Messages))
+    for i, m := range t.Messages { idx[m.ID] = i }
+    return idx
+}
+
+// Message returns the message with the given ID
+func (t *Thread) Message(id string) (*Message, bool) {
+    for i := range t.Messages {
+        if t.Messages[i].ID == id { return &t.Messages[i], true }
+    }
+    return nil, false
+}
+
+// Children returns the direct replies to a message in creation order ("" for roots)
+func (t *Thread) Children(parentID string) []Message {
+    var out []Message
+    for _, m := range t.Messages {
+        if m.ParentID == parentID { out = append(out, m) }
+    }
+    return out
+}
+
+// Siblings returns all alternatives of a message (including itself) in creation order
+func (t *Thread) Siblings(id string) []Message {
+    m, ok := t.Message(id)
+    if !ok { return nil }
+    return t.Children(m.ParentID)
+}
+
+// PathTo returns the messages from the root down to id
+func (t *Thread) PathTo(id string) []Message {
+    idx := t.index()
+    var rev []Message
+    seen := map[string]bool{}
+    for cur := id; cur != "" && !seen[cur]; {
+        i, ok := idx[cur]
+        if !ok { break }
+        seen[cur] = true
+        rev = append(rev, t.Messages[i])
+        cur = t.Messages[i].ParentID
+    }
+    out := make([]Message, len(rev))
+    for i := range rev { out[len(rev)-1-i] = rev[i] }
+    return out
+}
+
+// ActivePath returns the messages on the active branch, oldest first.
+// This is the linear conversation callers should show or send to a model.
+func (t *Thread) ActivePath() []Message {
+    if t.ActiveLeafID == "" { return nil }
+    return t.PathTo(t.ActiveLeafID)
+}
+
+// Leaves returns the IDs of messages without children
+func (t *Thread) Leaves() []string {
+    hasChild := make(map[string]bool, len(t.Messages))
+    for _, m := range t.Messages {
+        if m.ParentID != "" { hasChild[m.ParentID] = true }
+    }
+    var out []string
+    for _, m := range t.Messages {
+        if !hasChild[m.ID] { out = append(out, m.ID) }
+    }
+    return out
+}
+
+// Branches lists every root-to-leaf path, most recently updated first
+func (t *Thread) Branches() []Branch {
+    var out []Branch
+    for _, leaf := range t.Leaves() {
+        path := t.PathTo(leaf)
+        if len(path) == 0 { continue }
+        last := path[len(path)-1]
+        out = append(out, Branch{
+            LeafID:    leaf,
+            Length:    len(path),
+            Preview:   truncate(last.Content, 80),
+            UpdatedAt: last.CreatedAt,
+            Active:    leaf == t.ActiveLeafID,
+        })
+    }
+    sort.SliceStable(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+    return out
+}
+
+// latestLeaf follows the newest child from id until reaching a leaf
+func (t *Thread) latestLeaf(id string) string {
+    newest := make(map[string]string, len(t.Messages))
+    for _, m := range t.Messages {
+        if m.ParentID != "" { newest[m.ParentID] = m.ID }
+    }
+    for {
+        next, ok := newest[id]
+        if !ok { return id }
+        id = next
+    }
+}
+
+// addMessage stores msg under msg.ParentID ("" for a root) and makes it active
+func (t *Thread) addMessage(msg Message) (Message, error) {
+    if msg.ID == "" { msg.ID = newID() }
+    if msg.CreatedAt.IsZero() { msg.CreatedAt = time.Now() }
+    if _, dup := t.Message(msg.ID); dup { return msg, fmt.Errorf("message %s already exists in thread %s", msg.ID, t.ID) }
+    if msg.ParentID != "" {
+        if _, ok := t.Message(msg.ParentID); !ok { return msg, fmt.Errorf("parent message not found: %s", msg.ParentID) }
+    }
+    t.Messages = append(t.Messages, msg)
+    t.ActiveLeafID = msg.ID
+    return msg, nil
+}
+
+// addSibling stores msg as an alternative to the message with id and makes it active
+func (t *Thread) addSibling(id string, msg Message) (Message, error) {
+    orig, ok := t.Message(id)
+    if !ok { return msg, fmt.Errorf("message not found: %s", id) }
+    msg.ParentID = orig.ParentID
+    if msg.Role == "" { msg.Role = orig.Role }
+    return t.addMessage(msg)
+}
+
+// switchBranch activates the branch containing id, descending to its newest leaf
+func (t *Thread) switchBranch(id string) error {
+    if _, ok := t.Message(id); !ok { return fmt.Errorf("message not found: %s", id) }
+    t.ActiveLeafID = t.latestLeaf(id)
+    return nil
+}
+
+func truncate(s string, n int) string {
+    r := []rune(s)
+    if len(r) <= n { return s }
+    return string(r[:n]) + "…"
+}
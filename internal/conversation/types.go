@@ -4,24 +4,67 @@ import (
     "time"
 )
 
-// Message represents a single chat message
+// Message represents a single chat message. Besides the text it keeps everything
+// needed to replay an agent run: tool calls requested by the assistant, the
+// tool_call_id a tool result answers, the model that produced it, token usage
+// and non-text attachments.
 type Message struct {
-    ID        string    `json:"id" yaml:"id"`
-    Role      string    `json:"role" yaml:"role"` // system|user|assistant|tool
-    Content   string    `json:"content" yaml:"content"`
-    CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+    ID          string                 `json:"id" yaml:"id"`
+    ParentID    string                 `json:"parent_id,omitempty" yaml:"parent_id,omitempty"` // previous message on this branch; empty for roots
+    Role        string                 `json:"role" yaml:"role"` // system|user|assistant|tool
+    Content     string                 `json:"content" yaml:"content"`
+    Name        string                 `json:"name,omitempty" yaml:"name,omitempty"` // OpenAI participant name, sent back as-is
+    ToolCalls   []ToolCall             `json:"tool_calls,omitempty" yaml:"tool_calls,omitempty"`
+    ToolCallID  string                 `json:"tool_call_id,omitempty" yaml:"tool_call_id,omitempty"`
+    ToolName    string                 `json:"tool_name,omitempty" yaml:"tool_name,omitempty"` // tool that produced a role=tool message
+    Model       string                 `json:"model,omitempty" yaml:"model,omitempty"`
+    Usage       *Usage                 `json:"usage,omitempty" yaml:"usage,omitempty"`
+    Attachments []Attachment           `json:"attachments,omitempty" yaml:"attachments,omitempty"`
+    Metadata    map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+    CreatedAt   time.Time              `json:"created_at" yaml:"created_at"`
 }
 
-// Thread is a chat-like conversation with ordered messages and optional summary
+// ToolCall is a function call requested by an assistant message
+type ToolCall struct {
+    ID        string `json:"id" yaml:"id"`
+    Type      string `json:"type" yaml:"type"` // always "function" today
+    Name      string `json:"name" yaml:"name"`
+    Arguments string `json:"arguments" yaml:"arguments"` // raw JSON as produced by the model
+}
+
+// Usage records token accounting for a model response
+type Usage struct {
+    PromptTokens     int `json:"prompt_tokens" yaml:"prompt_tokens"`
+    CompletionTokens int `json:"completion_tokens" yaml:"completion_tokens"`
+    TotalTokens      int `json:"total_tokens" yaml:"total_tokens"`
+}
+
+// Attachment is a non-text content part such as an image or file
+type Attachment struct {
+    Type     string `json:"type" yaml:"type"` // image_url|input_audio|file
+    URL      string `json:"url,omitempty" yaml:"url,omitempty"`
+    Detail   string `json:"detail,omitempty" yaml:"detail,omitempty"`
+    MIMEType string `json:"mime_type,omitempty" yaml:"mime_type,omitempty"`
+    Name     string `json:"name,omitempty" yaml:"name,omitempty"`
+    Data     string `json:"data,omitempty" yaml:"data,omitempty"` // base64 payload for inline audio/files
+}
+
+// Thread is a chat-like conversation with optional summary. Messages holds every
+// message in creation order; edits and regenerations form a tree via ParentID and
+// ActiveLeafID selects the branch returned by ActivePath.
 type Thread struct {
-    ID        string                 `json:"id" yaml:"id"`
-    ProjectID string                 `json:"project_id" yaml:"project_id"`
-    Title     string                 `json:"title" yaml:"title"`
-    CreatedAt time.Time              `json:"created_at" yaml:"created_at"`
-    UpdatedAt time.Time              `json:"updated_at" yaml:"updated_at"`
-    Summary   string                 `json:"summary" yaml:"summary"`
-    Metadata  map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
-    Messages  []Message              `json:"messages" yaml:"messages"`
+    ID           string                 `json:"id" yaml:"id"`
+    ProjectID    string                 `json:"project_id" yaml:"project_id"`
+    UserID       string                 `json:"user_id,omitempty" yaml:"user_id,omitempty"` // owner, used by PurgeUser
+    Title        string                 `json:"title" yaml:"title"`
+    CreatedAt    time.Time              `json:"created_at" yaml:"created_at"`
+    UpdatedAt    time.Time              `json:"updated_at" yaml:"updated_at"`
+    Summary      string                 `json:"summary" yaml:"summary"`
+    Metadata     map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+    Messages     []Message              `json:"messages" yaml:"messages"`
+    ActiveLeafID string                 `json:"active_leaf_id,omitempty" yaml:"active_leaf_id,omitempty"`
+    MessageCount int                    `json:"message_count,omitempty" yaml:"message_count,omitempty"` // set by stores; ListThreads omits Messages
+    Version      int64                  `json:"version,omitempty" yaml:"version,omitempty"` // incremented on every write; UpdateThread rejects stale versions
 }
 
 // Store defines persistence for threads
@@ -31,6 +74,29 @@ type Store interface {
     GetThread(id string) (*Thread, error)
     ListThreads() ([]*Thread, error)
     AppendMessage(threadID string, msg Message) (*Thread, error)
+    AppendMessages(threadID string, msgs []Message) (*Thread, error)
     UpdateSummary(threadID string, summary string) error
     UpdateThread(t *Thread) error
+    DeleteThread(id string) error
+
+    // Branching: edits and regenerations add siblings and activate them
+    EditMessage(threadID, messageID, content string) (*Thread, error)
+    RegenerateMessage(threadID, messageID string, msg Message) (*Thread, error)
+    SwitchBranch(threadID, messageID string) (*Thread, error)
+    ListBranches(threadID string) ([]Branch, error)
+}
+
+// Importer is implemented by stores that can write a whole thread at once,
+// keeping its timestamps. ImportThread uses it when the store provides it.
+type Importer interface {
+    ImportThread(t *Thread) (*Thread, error)
+}
+
+// Listener is notified after successful writes to a FileStore, e.g. to keep a
+// SearchIndex current. Callbacks run while the store is locked and must not call
+// back into the store.
+type Listener interface {
+    ThreadChanged(t *Thread)                    // created, header updated, branch switched or rewritten
+    MessagesAppended(t *Thread, msgs []Message) // t already contains msgs
+    ThreadDeleted(id string)                    // deleted, archived or purged
 }
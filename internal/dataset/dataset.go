@@ -0,0 +1,228 @@
+package dataset
+
+import (
+    "crypto/sha256"
+    "encoding/binary"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/pradord/llm/internal/conversation"
+)
+
+// Builder turns curated threads into fine-tuning datasets. Each selected thread
+// contributes its active branch as one example; examples always end with an
+// assistant turn.
+
+type Format string
+
+const (
+    FormatOpenAI    Format = "openai"    // chat fine-tune JSONL: {"messages": [...]} with tool_calls
+    FormatAnthropic Format = "anthropic" // {"system": ..., "messages": [...]} with tool_use/tool_result blocks
+)
+
+type Options struct {
+    ProjectID       string   // only threads of this project
+    Skill           string   // only threads run with this skill (thread or message metadata "skill")
+    MinRating       float64  // only threads whose metadata "rating" is at least this; 0 disables
+    ValidationSplit float64  // fraction of examples held out for validation (default 0.1, <0 disables)
+    Seed            string   // changes the deterministic split
+    KeepSecrets     bool     // skip redaction
+    ExtraRedactions []string // additional regular expressions to redact
+    DropSystem      bool     // leave system prompts out of examples
+    MinMessages     int      // skip examples shorter than this (default 2)
+}
+
+// Example is one training conversation
+type Example struct {
+    ThreadID string                 `json:"thread_id"`
+    Messages []conversation.Message `json:"messages"`
+}
+
+type Dataset struct {
+    Train      []Example `json:"-"`
+    Validation []Example `json:"-"`
+    Stats      Stats     `json:"stats"`
+}
+
+type Stats struct {
+    Threads    int        `json:"threads"`    // threads in the store
+    Selected   int        `json:"selected"`   // threads passing the filters
+    Duplicates int        `json:"duplicates"` // examples dropped as duplicates
+    Skipped    int        `json:"skipped"`    // threads without a usable example
+    Redactions int        `json:"redactions"`
+    Train      SplitStats `json:"train"`
+    Validation SplitStats `json:"validation"`
+}
+
+// SplitStats uses a rough estimate of 4 characters per token plus a few tokens
+// of framing per message; good enough to budget a fine-tuning job
+type SplitStats struct {
+    Examples  int `json:"examples"`
+    Messages  int `json:"messages"`
+    Tokens    int `json:"tokens"`
+    MinTokens int `json:"min_tokens"`
+    MaxTokens int `json:"max_tokens"`
+    AvgTokens int `json:"avg_tokens"`
+}
+
+// Build selects, cleans and splits threads from store
+func Build(store conversation.Store, opts Options) (*Dataset, error) {
+    if opts.ValidationSplit == 0 { opts.ValidationSplit = 0.1 }
+    if opts.MinMessages <= 0 { opts.MinMessages = 2 }
+    red, err := newRedactor(opts.ExtraRedactions)
+    if err != nil { return nil, err }
+
+    headers, err := store.ListThreads()
+    if err != nil { return nil, err }
+    sort.Slice(headers, func(i, j int) bool { return headers[i].ID < headers[j].ID })
+
+    ds := &Dataset{}
+    ds.Stats.Threads = len(headers)
+    seen := map[string]bool{}
+    for _, h := range headers {
+        if opts.ProjectID != "" && h.ProjectID != opts.ProjectID { continue }
+        if opts.MinRating != 0 {
+            r, ok := rating(h.Metadata)
+            if !ok || r < opts.MinRating { continue }
+        }
+        t, err := store.GetThread(h.ID)
+        if err != nil { return nil, fmt.Errorf("thread %s: %w", h.ID, err) }
+        if opts.Skill != "" && !usedSkill(t, opts.Skill) { continue }
+        ds.Stats.Selected++
+
+        msgs := example(t.ActivePath(), opts.DropSystem)
+        if len(msgs) < opts.MinMessages {
+            ds.Stats.Skipped++
+            continue
+        }
+        redactions := 0
+        if !opts.KeepSecrets { redactions = red.messages(msgs) }
+        key := fingerprint(msgs)
+        if seen[key] {
+            ds.Stats.Duplicates++
+            continue
+        }
+        seen[key] = true
+        ds.Stats.Redactions += redactions
+
+        ex := Example{ThreadID: t.ID, Messages: msgs}
+        if inValidation(t.ID, opts.Seed, opts.ValidationSplit) {
+            ds.Validation = append(ds.Validation, ex)
+        } else {
+            ds.Train = append(ds.Train, ex)
+        }
+    }
+    ds.Stats.Train = splitStats(ds.Train)
+    ds.Stats.Validation = splitStats(ds.Validation)
+    return ds, nil
+}
+
+// example copies the messages worth training on, ending with an assistant turn
+func example(path []conversation.Message, dropSystem bool) []conversation.Message {
+    out := make([]conversation.Message, 0, len(path))
+    for _, m := range path {
+        if m.Role == "system" && dropSystem { continue }
+        m.ToolCalls = append([]conversation.ToolCall(nil), m.ToolCalls...)
+        m.Metadata, m.Usage = nil, nil
+        out = append(out, m)
+    }
+    for len(out) > 0 && (out[len(out)-1].Role != "assistant" || len(out[len(out)-1].ToolCalls) > 0) {
+        out = out[:len(out)-1]
+    }
+    return out
+}
+
+func rating(md map[string]interface{}) (float64, bool) {
+    switch v := md["rating"].(type) {
+    case float64:
+        return v, true
+    case int:
+        return float64(v), true
+    case int64:
+        return float64(v), true
+    case string:
+        f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+        return f, err == nil
+    }
+    return 0, false
+}
+
+func usedSkill(t *conversation.Thread, skill string) bool {
+    if s, _ := t.Metadata["skill"].(string); s != "" { return s == skill }
+    for _, m := range t.Messages {
+        if s, _ := m.Metadata["skill"].(string); s == skill { return true }
+    }
+    return false
+}
+
+// fingerprint identifies an example by its normalized content
+func fingerprint(msgs []conversation.Message) string {
+    h := sha256.New()
+    for _, m := range msgs {
+        fmt.Fprintf(h, "%s\x00%s\x00", m.Role, strings.Join(strings.Fields(strings.ToLower(m.Content)), " "))
+        for _, c := range m.ToolCalls { fmt.Fprintf(h, "%s\x00%s\x00", c.Name, c.Arguments) }
+    }
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+// inValidation places a thread in the validation split from a hash of its ID, so
+// the split is stable as threads are added
+func inValidation(id, seed string, fraction float64) bool {
+    if fraction <= 0 { return false }
+    sum := sha256.Sum256([]byte(seed + "\x00" + id))
+    return float64(binary.BigEndian.Uint64(sum[:8]))/float64(^uint64(0)) < fraction
+}
+
+// EstimateTokens approximates the token count of a message
+func EstimateTokens(m conversation.Message) int {
+    n := len(m.Content)
+    for _, c := range m.ToolCalls { n += len(c.Name) + len(c.Arguments) }
+    return (n+3)/4 + 4
+}
+
+func splitStats(examples []Example) SplitStats {
+    var s SplitStats
+    for i, ex := range examples {
+        tokens := 0
+        for _, m := range ex.Messages { tokens += EstimateTokens(m) }
+        s.Messages += len(ex.Messages)
+        s.Tokens += tokens
+        if i == 0 || tokens < s.MinTokens { s.MinTokens = tokens }
+        if tokens > s.MaxTokens { s.MaxTokens = tokens }
+    }
+    s.Examples = len(examples)
+    if s.Examples > 0 { s.AvgTokens = s.Tokens / s.Examples }
+    return s
+}
+
+// WriteFiles writes train.jsonl, validation.jsonl (when not empty) and
+// stats.json to dir and returns the paths written
+func (d *Dataset) WriteFiles(dir string, format Format) ([]string, error) {
+    write, err := writer(format)
+    if err != nil { return nil, err }
+    if err := os.MkdirAll(dir, 0o755); err != nil { return nil, err }
+    var paths []string
+    for _, split := range []struct {
+        name     string
+        examples []Example
+    }{{"train", d.Train}, {"validation", d.Validation}} {
+        if len(split.examples) == 0 && split.name == "validation" { continue }
+        path := filepath.Join(dir, split.name+".jsonl")
+        f, err := os.Create(path)
+        if err != nil { return paths, err }
+        err = write(f, split.examples)
+        if cerr := f.Close(); err == nil { err = cerr }
+        if err != nil { return paths, fmt.Errorf("%s: %w", path, err) }
+        paths = append(paths, path)
+    }
+    stats, _ := json.MarshalIndent(d.Stats, "", "  ")
+    path := filepath.Join(dir, "stats.json")
+    if err := os.WriteFile(path, append(stats, '\n'), 0o644); err != nil { return paths, err }
+    return append(paths, path), nil
+}
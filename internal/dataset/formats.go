@@ -0,0 +1,114 @@
+package dataset
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "strings"
+
+    "github.com/pradord/llm/internal/conversation"
+)
+
+func writer(format Format) (func(io.Writer, []Example) error, error) {
+    switch format {
+    case FormatOpenAI, "":
+        return WriteOpenAI, nil
+    case FormatAnthropic:
+        return WriteAnthropic, nil
+    }
+    return nil, fmt.Errorf("unknown dataset format %q (want openai or anthropic)", format)
+}
+
+// WriteOpenAI writes examples in the OpenAI chat fine-tuning format, one
+// {"messages": [...]} object per line; tool turns keep tool_calls and tool_call_id
+func WriteOpenAI(w io.Writer, examples []Example) error {
+    enc := json.NewEncoder(w)
+    for _, ex := range examples {
+        msgs := conversation.ToOpenAI(ex.Messages)
+        for _, m := range msgs {
+            // assistant turns that only call tools carry null content
+            if m["role"] == "assistant" && m["tool_calls"] != nil && m["content"] == "" { m["content"] = nil }
+        }
+        if err := enc.Encode(map[string]interface{}{"messages": msgs}); err != nil { return err }
+    }
+    return nil
+}
+
+// WriteAnthropic writes examples as {"system": ..., "messages": [...]} with
+// content blocks: assistant tool calls become tool_use blocks and tool results
+// are tool_result blocks in the following user turn
+func WriteAnthropic(w io.Writer, examples []Example) error {
+    enc := json.NewEncoder(w)
+    for _, ex := range examples {
+        if err := enc.Encode(anthropicExample(ex.Messages)); err != nil { return err }
+    }
+    return nil
+}
+
+type anthropicMessage struct {
+    Role    string                   `json:"role"`
+    Content []map[string]interface{} `json:"content"`
+}
+
+func anthropicExample(msgs []conversation.Message) map[string]interface{} {
+    var system []string
+    var out []anthropicMessage
+    add := func(role string, blocks ...map[string]interface{}) {
+        if len(blocks) == 0 { return }
+        // consecutive turns of one role are merged; the API requires alternation
+        if n := len(out); n > 0 && out[n-1].Role == role {
+            out[n-1].Content = append(out[n-1].Content, blocks...)
+            return
+        }
+        out = append(out, anthropicMessage{Role: role, Content: blocks})
+    }
+    for _, m := range msgs {
+        switch m.Role {
+        case "system":
+            if m.Content != "" { system = append(system, m.Content) }
+        case "tool":
+            add("user", map[string]interface{}{"type": "tool_result", "tool_use_id": m.ToolCallID, "content": m.Content})
+        case "assistant":
+            var blocks []map[string]interface{}
+            if m.Content != "" { blocks = append(blocks, textBlock(m.Content)) }
+            for _, c := range m.ToolCalls {
+                var input interface{} = map[string]interface{}{}
+                if strings.TrimSpace(c.Arguments) != "" && json.Unmarshal([]byte(c.Arguments), &input) != nil {
+                    input = map[string]interface{}{"input": c.Arguments}
+                }
+                blocks = append(blocks, map[string]interface{}{"type": "tool_use", "id": c.ID, "name": c.Name, "input": input})
+            }
+            add("assistant", blocks...)
+        default:
+            var blocks []map[string]interface{}
+            if m.Content != "" { blocks = append(blocks, textBlock(m.Content)) }
+            for _, a := range m.Attachments {
+                if b, ok := imageBlock(a); ok { blocks = append(blocks, b) }
+            }
+            add("user", blocks...)
+        }
+    }
+    ex := map[string]interface{}{"messages": out}
+    if len(system) > 0 { ex["system"] = strings.Join(system, "\n\n") }
+    return ex
+}
+
+func textBlock(s string) map[string]interface{} {
+    return map[string]interface{}{"type": "text", "text": s}
+}
+
+// imageBlock converts an image attachment; only data: URLs and http(s) URLs map
+// onto Anthropic image sources
+func imageBlock(a conversation.Attachment) (map[string]interface{}, bool) {
+    if a.Type != "image_url" { return nil, false }
+    if strings.HasPrefix(a.URL, "data:") {
+        meta, data, ok := strings.Cut(strings.TrimPrefix(a.URL, "data:"), ",")
+        if !ok || !strings.HasSuffix(meta, ";base64") { return nil, false }
+        src := map[string]interface{}{"type": "base64", "media_type": strings.TrimSuffix(meta, ";base64"), "data": data}
+        return map[string]interface{}{"type": "image", "source": src}, true
+    }
+    if strings.HasPrefix(a.URL, "https://") || strings.HasPrefix(a.URL, "http://") {
+        return map[string]interface{}{"type": "image", "source": map[string]interface{}{"type": "url", "url": a.URL}}, true
+    }
+    return nil, false
+}
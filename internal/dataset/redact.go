@@ -0,0 +1,71 @@
+package dataset
+
+import (
+    "fmt"
+    "regexp"
+
+    "github.com/pradord/llm/internal/conversation"
+)
+
+const redacted = "[REDACTED]"
+
+// secretPatterns match credentials that commonly leak into agent transcripts
+var secretPatterns = []*regexp.Regexp{
+    regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+    regexp.MustCompile(`\bsk-(?:ant-|proj-|or-v1-)?[A-Za-z0-9_\-]{20,}`), // OpenAI, Anthropic, OpenRouter
+    regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), // AWS access key IDs
+    regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`), // GitHub tokens
+    regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{40,}\b`),
+    regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9\-]{10,}`), // Slack tokens
+    regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`), // Google API keys
+    regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{8,}\.eyJ[A-Za-z0-9_\-]{8,}\.[A-Za-z0-9_\-]{8,}`), // JWTs
+    regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/\-]{16,}=*`),
+    regexp.MustCompile(`(?i)\b[a-z]+://[^\s:/@]+:[^\s@/]+@`), // credentials in URLs
+}
+
+// assignmentPattern keeps the key name and redacts the value of key=value pairs
+var assignmentPattern = regexp.MustCompile(`(?i)\b((?:api[_-]?key|secret|password|passwd|access[_-]?token|auth[_-]?token|client[_-]?secret)["']?\s*[:=]\s*["']?)([^\s"',}]{6,})`)
+
+type redactor struct {
+    patterns []*regexp.Regexp
+}
+
+func newRedactor(extra []string) (*redactor, error) {
+    r := &redactor{patterns: append([]*regexp.Regexp(nil), secretPatterns...)}
+    for _, p := range extra {
+        re, err := regexp.Compile(p)
+        if err != nil { return nil, fmt.Errorf("redaction pattern %q: %w", p, err) }
+        r.patterns = append(r.patterns, re)
+    }
+    return r, nil
+}
+
+// text returns s with secrets replaced and the number of replacements
+func (r *redactor) text(s string) (string, int) {
+    n := 0
+    for _, re := range r.patterns {
+        s = re.ReplaceAllStringFunc(s, func(string) string { n++; return redacted })
+    }
+    s = assignmentPattern.ReplaceAllStringFunc(s, func(m string) string {
+        sub := assignmentPattern.FindStringSubmatch(m)
+        if sub[2] == redacted { return m }
+        n++
+        return sub[1] + redacted
+    })
+    return s, n
+}
+
+// messages redacts content and tool call arguments in place
+func (r *redactor) messages(msgs []conversation.Message) int {
+    total := 0
+    for i := range msgs {
+        var n int
+        msgs[i].Content, n = r.text(msgs[i].Content)
+        total += n
+        for j := range msgs[i].ToolCalls {
+            msgs[i].ToolCalls[j].Arguments, n = r.text(msgs[i].ToolCalls[j].Arguments)
+            total += n
+        }
+    }
+    return total
+}
@@ -0,0 +1,366 @@
+package defs
+
+import (
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "reflect"
+    "regexp"
+    "sort"
+    "strings"
+
+    "github.com/pradord/llm/internal/skills"
+    "github.com/pradord/llm/internal/tools"
+    "gopkg.in/yaml.v3"
+)
+
+// Diagnostic severities
+const (
+    SeverityError   = "error"
+    SeverityWarning = "warning"
+)
+
+// Diagnostic is one problem found in a definition file
+type Diagnostic struct {
+    File     string
+    Line     int
+    Column   int
+    Severity string
+    Message  string
+}
+
+func (d Diagnostic) String() string {
+    return fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+}
+
+// LintError is returned by strict loading when a lint found errors
+type LintError struct {
+    Diagnostics []Diagnostic
+}
+
+func (e *LintError) Error() string {
+    var b strings.Builder
+    n := 0
+    for _, d := range e.Diagnostics {
+        if d.Severity != SeverityError { continue }
+        if n < 5 { fmt.Fprintf(&b, "\n  %s", d) }
+        n++
+    }
+    return fmt.Sprintf("%d error(s) in definitions:%s", n, b.String())
+}
+
+// LintConfig describes what the definitions may refer to
+type LintConfig struct {
+    DefsDir    string           // tools/ and skills/ YAML
+    Tools      []string         // names of the registered tool handlers
+    Skills     []string         // skills registered besides those in DefsDir, e.g. built-ins
+    ValidModel func(string) bool // nil accepts any model
+}
+
+// Lint checks the tool and skill definitions under cfg.DefsDir more strictly
+// than the loaders do: unknown fields, values of the wrong type, missing and
+// duplicate names, references to unknown tools, skills and models, and system
+// prompts that mention tools the skill cannot use. Diagnostics are sorted by
+// file and position.
+func Lint(cfg LintConfig) ([]Diagnostic, error) {
+    l := &linter{cfg: cfg, tools: map[string]bool{}, skills: map[string]bool{}}
+    for _, t := range cfg.Tools { l.tools[t] = true }
+    for _, s := range cfg.Skills { l.skills[s] = true }
+
+    toolDocs, err := l.parseDir(filepath.Join(cfg.DefsDir, "tools"), reflect.TypeOf(tools.ToolMetadata{}))
+    if err != nil { return nil, err }
+    skillDocs, err := l.parseDir(filepath.Join(cfg.DefsDir, "skills"), reflect.TypeOf(skills.SkillYAML{}))
+    if err != nil { return nil, err }
+
+    l.lintTools(toolDocs)
+    for _, t := range l.httpTools { l.tools[t] = true }
+    // skills defined in the directory may require each other
+    for _, d := range skillDocs {
+        if n := field(d.root, "name"); n != nil { l.skills[n.Value] = true }
+    }
+    l.lintSkills(skillDocs)
+
+    sort.SliceStable(l.diags, func(i, j int) bool {
+        a, b := l.diags[i], l.diags[j]
+        if a.File != b.File { return a.File < b.File }
+        if a.Line != b.Line { return a.Line < b.Line }
+        return a.Column < b.Column
+    })
+    return l.diags, nil
+}
+
+// HasErrors reports whether any diagnostic is an error
+func HasErrors(diags []Diagnostic) bool {
+    for _, d := range diags {
+        if d.Severity == SeverityError { return true }
+    }
+    return false
+}
+
+type linter struct {
+    cfg       LintConfig
+    tools     map[string]bool
+    skills    map[string]bool
+    httpTools []string // defined in the tools directory
+    diags     []Diagnostic
+}
+
+type document struct {
+    path string
+    root *yaml.Node // top-level mapping
+}
+
+func (l *linter) report(path string, n *yaml.Node, severity, format string, args ...interface{}) {
+    d := Diagnostic{File: path, Line: 1, Column: 1, Severity: severity, Message: fmt.Sprintf(format, args...)}
+    if n != nil { d.Line, d.Column = n.Line, n.Column }
+    l.diags = append(l.diags, d)
+}
+
+var yamlLine = regexp.MustCompile(`^yaml: (?:line (\d+): )?`)
+
+// parseDir parses every YAML file in dir and checks it against the fields of typ
+func (l *linter) parseDir(dir string, typ reflect.Type) ([]document, error) {
+    var docs []document
+    if _, err := os.Stat(dir); err != nil { return nil, nil }
+    err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+        if err != nil { return err }
+        if d.IsDir() { return nil }
+        ext := strings.ToLower(filepath.Ext(d.Name()))
+        if ext != ".yaml" && ext != ".yml" { return nil }
+        data, err := os.ReadFile(path)
+        if err != nil { return err }
+        var doc yaml.Node
+        if err := yaml.Unmarshal(data, &doc); err != nil {
+            diag := Diagnostic{File: path, Line: 1, Column: 1, Severity: SeverityError, Message: err.Error()}
+            if m := yamlLine.FindStringSubmatch(diag.Message); m != nil {
+                if m[1] != "" { fmt.Sscan(m[1], &diag.Line) }
+                diag.Message = diag.Message[len(m[0]):]
+            }
+            l.diags = append(l.diags, diag)
+            return nil
+        }
+        if len(doc.Content) == 0 {
+            l.report(path, nil, SeverityError, "empty definition")
+            return nil
+        }
+        root := doc.Content[0]
+        if root.Kind != yaml.MappingNode {
+            l.report(path, root, SeverityError, "definition must be a mapping, got %s", kindName(root))
+            return nil
+        }
+        l.checkFields(path, root, typ)
+        docs = append(docs, document{path: path, root: root})
+        return nil
+    })
+    return docs, err
+}
+
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// checkFields reports keys of n that typ has no yaml field for and values that
+// do not decode into the field's type, recursing into nested structs
+func (l *linter) checkFields(path string, n *yaml.Node, typ reflect.Type) {
+    fields := yamlFields(typ)
+    seen := map[string]bool{}
+    for i := 0; i+1 < len(n.Content); i += 2 {
+        key, val := n.Content[i], n.Content[i+1]
+        f, ok := fields[key.Value]
+        if !ok {
+            l.report(path, key, SeverityError, "unknown field %q%s", key.Value, suggest(key.Value, fields))
+            continue
+        }
+        if seen[key.Value] { l.report(path, key, SeverityError, "field %q is set twice", key.Value) }
+        seen[key.Value] = true
+        l.checkValue(path, val, f)
+    }
+}
+
+func (l *linter) checkValue(path string, val *yaml.Node, typ reflect.Type) {
+    if err := val.Decode(reflect.New(typ).Interface()); err != nil {
+        msg := err.Error()
+        if i := strings.LastIndex(msg, ": "); strings.HasPrefix(msg, "yaml: unmarshal errors") && i >= 0 { msg = msg[i+2:] }
+        l.report(path, val, SeverityError, "%s", msg)
+        return
+    }
+    if reflect.PtrTo(typ).Implements(unmarshalerType) {
+        // custom forms, e.g. an example given as a plain string; check mappings only
+        if val.Kind == yaml.MappingNode && typ.Kind() == reflect.Struct { l.checkFields(path, val, typ) }
+        return
+    }
+    switch {
+    case typ.Kind() == reflect.Struct && val.Kind == yaml.MappingNode:
+        l.checkFields(path, val, typ)
+    case typ.Kind() == reflect.Slice && val.Kind == yaml.SequenceNode:
+        for _, item := range val.Content { l.checkValue(path, item, typ.Elem()) }
+    case typ.Kind() == reflect.Ptr:
+        l.checkValue(path, val, typ.Elem())
+    }
+}
+
+// yamlFields maps the yaml keys of a struct to their field types
+func yamlFields(typ reflect.Type) map[string]reflect.Type {
+    out := map[string]reflect.Type{}
+    for i := 0; i < typ.NumField(); i++ {
+        f := typ.Field(i)
+        if f.PkgPath != "" { continue }
+        name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+        if name == "-" { continue }
+        if name == "" { name = strings.ToLower(f.Name) }
+        out[name] = f.Type
+    }
+    return out
+}
+
+// suggest names a known field close to an unknown one, e.g. a typo
+func suggest(name string, fields map[string]reflect.Type) string {
+    best, bestDist := "", 3
+    for f := range fields {
+        if d := distance(name, f); d < bestDist || (d == bestDist && best != "" && f < best) { best, bestDist = f, d }
+    }
+    if best == "" { return "" }
+    return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// distance is the Levenshtein distance between a and b
+func distance(a, b string) int {
+    prev := make([]int, len(b)+1)
+    for j := range prev { prev[j] = j }
+    for i := 1; i <= len(a); i++ {
+        cur := make([]int, len(b)+1)
+        cur[0] = i
+        for j := 1; j <= len(b); j++ {
+            cost := 1
+            if a[i-1] == b[j-1] { cost = 0 }
+            cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+        }
+        prev = cur
+    }
+    return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+    if b < a { a = b }
+    if c < a { a = c }
+    return a
+}
+
+func (l *linter) lintTools(docs []document) {
+    names := map[string]string{}
+    for _, d := range docs {
+        name := field(d.root, "name")
+        if name == nil || name.Value == "" {
+            l.report(d.path, d.root, SeverityError, "missing name")
+            continue
+        }
+        if prev, dup := names[name.Value]; dup {
+            l.report(d.path, name, SeverityError, "tool %q is also defined in %s", name.Value, prev)
+        }
+        names[name.Value] = d.path
+        if h := field(d.root, "http"); h != nil {
+            l.lintHTTPTool(d, name, h)
+        } else if !l.tools[name.Value] {
+            l.report(d.path, name, SeverityWarning, "no tool handler named %q is registered; this metadata is ignored", name.Value)
+        }
+        l.checkModel(d.path, field(d.root, "required_model"))
+        if mt := field(d.root, "model_type"); mt != nil {
+            switch strings.ToLower(mt.Value) {
+            case "", "text", "image", "audio", "video", "transcribe", "embedding", "vision":
+            default:
+                l.report(d.path, mt, SeverityError, "unknown model_type %q", mt.Value)
+            }
+        }
+    }
+}
+
+// lintHTTPTool checks an http tool definition; skills may then use the tool
+func (l *linter) lintHTTPTool(d document, name, h *yaml.Node) {
+    if l.tools[name.Value] {
+        l.report(d.path, name, SeverityError, "an http tool cannot replace the registered tool handler %q", name.Value)
+        return
+    }
+    var m tools.ToolMetadata
+    if err := d.root.Decode(&m); err != nil || m.HTTP == nil { return } // reported by the field checks
+    if _, err := tools.NewHTTPTool(m); err != nil {
+        l.report(d.path, h, SeverityError, "%s", strings.TrimPrefix(err.Error(), "tool "+name.Value+": "))
+        return
+    }
+    l.httpTools = append(l.httpTools, name.Value)
+}
+
+func (l *linter) lintSkills(docs []document) {
+    names := map[string]string{}
+    for _, d := range docs {
+        name := field(d.root, "name")
+        if name == nil || name.Value == "" {
+            l.report(d.path, d.root, SeverityError, "missing name; the loader skips this file")
+            continue
+        }
+        if prev, dup := names[name.Value]; dup {
+            l.report(d.path, name, SeverityError, "skill %q is also defined in %s", name.Value, prev)
+        }
+        names[name.Value] = d.path
+        l.checkModel(d.path, field(d.root, "default_model"))
+
+        allowed := map[string]bool{}
+        if ts := field(d.root, "tools"); ts != nil && ts.Kind == yaml.SequenceNode {
+            for _, t := range ts.Content {
+                allowed[t.Value] = true
+                if !l.tools[t.Value] { l.report(d.path, t, SeverityError, "unknown tool %q", t.Value) }
+            }
+        }
+        if rs := field(d.root, "required_skills"); rs != nil && rs.Kind == yaml.SequenceNode {
+            for _, r := range rs.Content {
+                if r.Value == name.Value {
+                    l.report(d.path, r, SeverityError, "skill %q requires itself", r.Value)
+                } else if !l.skills[r.Value] {
+                    l.report(d.path, r, SeverityError, "unknown skill %q", r.Value)
+                }
+            }
+        }
+        if sp := field(d.root, "system_prompt"); sp != nil {
+            for _, t := range mentionedTools(sp.Value, l.tools) {
+                if !allowed[t] { l.report(d.path, sp, SeverityWarning, "system prompt mentions tool %q, which is not in tools", t) }
+            }
+        }
+        if in := field(d.root, "inputs"); in != nil {
+            var s skills.Skill
+            s.Name = name.Value
+            if in.Decode(&s.Inputs) == nil {
+                if pt := field(d.root, "prompt_template"); pt != nil { s.PromptTemplate = pt.Value }
+                if err := s.CheckInputs(); err != nil { l.report(d.path, in, SeverityError, "%v", err) }
+            }
+        }
+    }
+}
+
+func (l *linter) checkModel(path string, n *yaml.Node) {
+    if n == nil || n.Value == "" || l.cfg.ValidModel == nil { return }
+    if !l.cfg.ValidModel(n.Value) { l.report(path, n, SeverityError, "unknown model %q", n.Value) }
+}
+
+// mentionedTools returns the known tool names that occur as words in text
+func mentionedTools(text string, known map[string]bool) []string {
+    var out []string
+    for name := range known {
+        if regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`).MatchString(text) { out = append(out, name) }
+    }
+    sort.Strings(out)
+    return out
+}
+
+// field returns the value node of key in a mapping node
+func field(m *yaml.Node, key string) *yaml.Node {
+    for i := 0; i+1 < len(m.Content); i += 2 {
+        if m.Content[i].Value == key { return m.Content[i+1] }
+    }
+    return nil
+}
+
+func kindName(n *yaml.Node) string {
+    switch n.Kind {
+    case yaml.SequenceNode: return "a list"
+    case yaml.ScalarNode: return "a scalar"
+    }
+    return "an alias"
+}
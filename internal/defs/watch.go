@@ -0,0 +1,275 @@
+// Package defs reloads tool, skill and project definitions while the process
+// runs. A Watcher polls the definition directories, re-validates whatever
+// changed and swaps it into the registries in one step. When a file does not
+// parse or validate, the last good definitions stay in place.
+package defs
+
+import (
+    "context"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/pradord/llm/internal/project"
+    "github.com/pradord/llm/internal/skills"
+    "github.com/pradord/llm/internal/tools"
+)
+
+// Kinds of definitions, as reported in Event.Kind
+const (
+    KindTools    = "tools"
+    KindSkills   = "skills"
+    KindProjects = "projects"
+)
+
+// Event reports one reload of a kind of definitions. Added, Changed and Removed
+// list files; when Err is set the previous definitions were kept.
+type Event struct {
+    Kind    string
+    Added   []string
+    Changed []string
+    Removed []string
+    Err     error
+    Time    time.Time
+}
+
+func (e Event) String() string {
+    if e.Err != nil { return fmt.Sprintf("%s: reload failed, keeping last good version: %v", e.Kind, e.Err) }
+    return fmt.Sprintf("%s: reloaded (%d added, %d changed, %d removed)", e.Kind, len(e.Added), len(e.Changed), len(e.Removed))
+}
+
+type Config struct {
+    DefsDir     string                // tools/ and skills/ YAML, as for --defs-dir
+    ProjectsDir string                // project YAML, as for --projects-dir
+    Interval    time.Duration         // polling interval (default 2s)
+    Tools       *tools.ToolRegistry   // receives tool metadata
+    Skills      *skills.SkillRegistry // receives skills; an overridden built-in returns when its override is removed
+    Validator   *project.Validator    // checks reloaded projects
+    Strict      bool                  // reject tool and skill changes that Lint reports errors for
+    ValidModel  func(string) bool     // used by the strict lint
+    OnEvent     func(Event)           // called after every reload attempt
+    // Layered returns definitions that go on top of DefsDir's, e.g. installed
+    // skill packs; they are swapped in with every reload so they stay in place
+    Layered func() ([]tools.ToolMetadata, []*skills.Skill)
+}
+
+// Watcher keeps the registries in sync with the definition directories
+type Watcher struct {
+    cfg    Config
+    poll   sync.Mutex // serializes Poll
+    mu     sync.RWMutex
+    seen   map[string]map[string]fileStamp // kind -> path -> stamp
+    failed map[string]bool                 // kinds whose last reload failed
+    loaded []string                        // skill names that came from DefsDir or Layered
+    shadow map[string]*skills.Skill        // built-ins a loaded skill overrides, restored when it goes away
+    defs   map[string]bool                 // skill names that came from DefsDir
+    projs  map[string]*project.Project
+}
+
+type fileStamp struct {
+    size    int64
+    modTime time.Time
+}
+
+func NewWatcher(cfg Config) *Watcher {
+    if cfg.Interval <= 0 { cfg.Interval = 2 * time.Second }
+    return &Watcher{cfg: cfg, seen: map[string]map[string]fileStamp{}, failed: map[string]bool{}, shadow: map[string]*skills.Skill{}, projs: map[string]*project.Project{}}
+}
+
+// Projects returns the last good project definitions
+func (w *Watcher) Projects() map[string]*project.Project {
+    w.mu.RLock()
+    defer w.mu.RUnlock()
+    return w.projs
+}
+
+// Run polls until ctx is done. It loads everything once before the first tick.
+func (w *Watcher) Run(ctx context.Context) {
+    w.Poll()
+    t := time.NewTicker(w.cfg.Interval)
+    defer t.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-t.C:
+            w.Poll()
+        }
+    }
+}
+
+// Poll reloads each kind of definitions whose files were added, changed or
+// removed since the last poll and returns the events. Kinds whose last reload
+// failed are retried whenever any kind changes: a skill can be fixed by a tool
+// change, and the strict lint covers both.
+func (w *Watcher) Poll() []Event {
+    w.poll.Lock()
+    defer w.poll.Unlock()
+    var kinds []watchedKind
+    if w.cfg.DefsDir != "" {
+        if w.cfg.Tools != nil { kinds = append(kinds, watchedKind{KindTools, filepath.Join(w.cfg.DefsDir, "tools"), w.reloadTools}) }
+        if w.cfg.Skills != nil { kinds = append(kinds, watchedKind{KindSkills, filepath.Join(w.cfg.DefsDir, "skills"), w.reloadSkills}) }
+    }
+    if w.cfg.ProjectsDir != "" { kinds = append(kinds, watchedKind{KindProjects, w.cfg.ProjectsDir, w.reloadProjects}) }
+
+    var events []Event
+    pending := make([]*Event, len(kinds))
+    stamps := make([]map[string]fileStamp, len(kinds))
+    changed := false
+    for i, k := range kinds {
+        cur, err := scan(k.dir)
+        if err != nil { events = append(events, Event{Kind: k.kind, Err: err, Time: time.Now()}); continue }
+        stamps[i] = cur
+        if e := w.diff(k.kind, cur); e != nil { pending[i] = e; changed = true }
+    }
+    for i, k := range kinds {
+        e := pending[i]
+        if e == nil && changed && w.failed[k.kind] { e = &Event{Kind: k.kind, Time: time.Now()} }
+        if e == nil { continue }
+        e.Err = k.reload(k.dir)
+        w.seen[k.kind] = stamps[i]
+        w.failed[k.kind] = e.Err != nil
+        events = append(events, *e)
+    }
+    for _, e := range events {
+        if w.cfg.OnEvent != nil { w.cfg.OnEvent(e) }
+    }
+    return events
+}
+
+type watchedKind struct {
+    kind   string
+    dir    string
+    reload func(dir string) error
+}
+
+// diff compares a directory's stamps with the last poll; nil means unchanged
+func (w *Watcher) diff(kind string, cur map[string]fileStamp) *Event {
+    prev, known := w.seen[kind]
+    e := &Event{Kind: kind, Time: time.Now()}
+    for path, st := range cur {
+        old, ok := prev[path]
+        switch {
+        case !ok: e.Added = append(e.Added, path)
+        case old != st: e.Changed = append(e.Changed, path)
+        }
+    }
+    for path := range prev {
+        if _, ok := cur[path]; !ok { e.Removed = append(e.Removed, path) }
+    }
+    if known && len(e.Added)+len(e.Changed)+len(e.Removed) == 0 { return nil }
+    sort.Strings(e.Added); sort.Strings(e.Changed); sort.Strings(e.Removed)
+    return e
+}
+
+// lint runs Lint over DefsDir in strict mode
+func (w *Watcher) lint() error {
+    if !w.cfg.Strict { return nil }
+    cfg := LintConfig{DefsDir: w.cfg.DefsDir, ValidModel: w.cfg.ValidModel}
+    layeredTools, _ := w.layered()
+    if w.cfg.Tools != nil {
+        // http tools come from the definitions being linted, or from Layered
+        for _, n := range w.cfg.Tools.Names() {
+            t, _ := w.cfg.Tools.Get(n)
+            if _, ok := tools.Unwrap(t).(*tools.HTTPTool); !ok { cfg.Tools = append(cfg.Tools, n) }
+        }
+        for _, m := range layeredTools {
+            if m.HTTP != nil { cfg.Tools = append(cfg.Tools, m.Name) }
+        }
+    }
+    if w.cfg.Skills != nil {
+        for _, s := range w.cfg.Skills.List() {
+            if !w.defs[s.Name] { cfg.Skills = append(cfg.Skills, s.Name) }
+        }
+    }
+    diags, err := Lint(cfg)
+    if err != nil { return err }
+    if HasErrors(diags) { return &LintError{Diagnostics: diags} }
+    return nil
+}
+
+func (w *Watcher) reloadTools(dir string) error {
+    if err := w.lint(); err != nil { return err }
+    metas, err := tools.LoadToolMetadataDir(dir)
+    if err != nil { return err }
+    layered, _ := w.layered()
+    return tools.ReplaceToolMetadata(w.cfg.Tools, append(metas, layered...))
+}
+
+func (w *Watcher) layered() ([]tools.ToolMetadata, []*skills.Skill) {
+    if w.cfg.Layered == nil { return nil, nil }
+    return w.cfg.Layered()
+}
+
+func (w *Watcher) reloadSkills(dir string) error {
+    if err := w.lint(); err != nil { return err }
+    list, err := skills.ParseSkillsDir(dir)
+    if err != nil { return err }
+    fromDefs := map[string]bool{}
+    for _, s := range list {
+        if w.cfg.Tools != nil {
+            for _, tn := range s.Tools {
+                if _, ok := w.cfg.Tools.Get(tn); !ok { return fmt.Errorf("skill %s: unknown tool %q", s.Name, tn) }
+            }
+        }
+        if err := s.CheckInputs(); err != nil { return err }
+        fromDefs[s.Name] = true
+    }
+    _, layered := w.layered()
+    list = append(list, layered...) // later skills win, as when they were loaded at startup
+    names := make([]string, 0, len(list))
+    present := map[string]bool{}
+    for _, s := range list { names = append(names, s.Name); present[s.Name] = true }
+    // Built-ins come from their constructors: by the first reload the registry
+    // may already hold the definitions loaded at startup under their names
+    builtins := skills.GetAllBuiltInSkills()
+    shadow := make(map[string]*skills.Skill, len(w.shadow))
+    for name, s := range w.shadow { shadow[name] = s }
+    for name := range present {
+        if b, ok := builtins[name]; ok && shadow[name] == nil { shadow[name] = b }
+    }
+    add := list
+    for name, s := range shadow {
+        if !present[name] { add = append([]*skills.Skill{s}, add...); delete(shadow, name) }
+    }
+    if err := w.cfg.Skills.Replace(w.loaded, add); err != nil { return err }
+    w.loaded, w.defs, w.shadow = names, fromDefs, shadow
+    return nil
+}
+
+func (w *Watcher) reloadProjects(dir string) error {
+    projs, err := project.LoadDir(dir)
+    if err != nil { return err }
+    ids := make([]string, 0, len(projs))
+    for id := range projs { ids = append(ids, id) }
+    sort.Strings(ids)
+    for _, id := range ids {
+        if err := w.cfg.Validator.Validate(projs[id]); err != nil { return fmt.Errorf("%s: %w", projs[id].Source, err) }
+    }
+    w.mu.Lock()
+    w.projs = projs
+    w.mu.Unlock()
+    return nil
+}
+
+// scan stamps the YAML files under dir; a missing dir has no files
+func scan(dir string) (map[string]fileStamp, error) {
+    out := map[string]fileStamp{}
+    if _, err := os.Stat(dir); err != nil { return out, nil }
+    err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+        if err != nil { return err }
+        if d.IsDir() { return nil }
+        ext := strings.ToLower(filepath.Ext(d.Name()))
+        if ext != ".yaml" && ext != ".yml" { return nil }
+        info, err := d.Info()
+        if err != nil { return err }
+        out[path] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+        return nil
+    })
+    return out, err
+}
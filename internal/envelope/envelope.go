@@ -0,0 +1,183 @@
+// Package envelope implements AES-256-GCM envelope encryption for files at rest.
+// A master key never encrypts data directly: every file gets a random data key,
+// stored in the file's first line wrapped (encrypted) by the master key. Lines
+// after the header are sealed one by one so append-only logs stay appendable.
+//
+//   {"envelope":1,"alg":"AES-256-GCM","key_id":"1a2b3c4d","wrapped_key":"..."}
+//   <base64 nonce||ciphertext>
+//   <base64 nonce||ciphertext>
+package envelope
+
+import (
+    "bytes"
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "strings"
+)
+
+const alg = "AES-256-GCM"
+
+// ErrNoKey is returned when reading an encrypted file without a key
+var ErrNoKey = errors.New("file is encrypted but no encryption key is configured")
+
+// Key is a master key used to wrap per-file data keys
+type Key struct {
+    aead cipher.AEAD
+    id   string
+}
+
+// NewKey creates a master key from 32 raw bytes
+func NewKey(raw []byte) (*Key, error) {
+    if len(raw) != 32 { return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(raw)) }
+    aead, err := newAEAD(raw)
+    if err != nil { return nil, err }
+    sum := sha256.Sum256(raw)
+    return &Key{aead: aead, id: hex.EncodeToString(sum[:4])}, nil
+}
+
+// ParseKey decodes a base64 or hex encoded 32-byte key
+func ParseKey(s string) (*Key, error) {
+    s = strings.TrimSpace(s)
+    for _, dec := range []func(string) ([]byte, error){
+        base64.StdEncoding.DecodeString, base64.RawStdEncoding.DecodeString,
+        base64.URLEncoding.DecodeString, base64.RawURLEncoding.DecodeString, hex.DecodeString,
+    } {
+        if raw, err := dec(s); err == nil && len(raw) == 32 { return NewKey(raw) }
+    }
+    return nil, errors.New("encryption key must be 32 bytes encoded as base64 or hex")
+}
+
+// LoadKeyFile reads a key written by GenerateKey (or any base64/hex key) from path
+func LoadKeyFile(path string) (*Key, error) {
+    data, err := os.ReadFile(path)
+    if err != nil { return nil, err }
+    k, err := ParseKey(string(data))
+    if err != nil { return nil, fmt.Errorf("%s: %w", path, err) }
+    return k, nil
+}
+
+// GenerateKey returns a new random key encoded as base64
+func GenerateKey() (string, error) {
+    raw := make([]byte, 32)
+    if _, err := rand.Read(raw); err != nil { return "", err }
+    return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// ID is a short fingerprint of the key recorded in file headers
+func (k *Key) ID() string { return k.id }
+
+// DataKey encrypts the lines of one file
+type DataKey struct {
+    aead   cipher.AEAD
+    header []byte
+}
+
+type header struct {
+    Envelope   int    `json:"envelope"`
+    Alg        string `json:"alg"`
+    KeyID      string `json:"key_id"`
+    WrappedKey string `json:"wrapped_key"`
+}
+
+// NewDataKey creates a random data key for a new file
+func (k *Key) NewDataKey() (*DataKey, error) {
+    raw := make([]byte, 32)
+    if _, err := rand.Read(raw); err != nil { return nil, err }
+    aead, err := newAEAD(raw)
+    if err != nil { return nil, err }
+    wrapped, err := seal(k.aead, raw)
+    if err != nil { return nil, err }
+    h, err := json.Marshal(header{Envelope: 1, Alg: alg, KeyID: k.id, WrappedKey: base64.StdEncoding.EncodeToString(wrapped)})
+    if err != nil { return nil, err }
+    return &DataKey{aead: aead, header: h}, nil
+}
+
+// IsHeader reports whether line is an envelope header, i.e. the file is encrypted
+func IsHeader(line []byte) bool {
+    return bytes.HasPrefix(bytes.TrimSpace(line), []byte(`{"envelope":`))
+}
+
+// OpenHeader unwraps the data key stored in a header line
+func (k *Key) OpenHeader(line []byte) (*DataKey, error) {
+    if k == nil { return nil, ErrNoKey }
+    var h header
+    if err := json.Unmarshal(bytes.TrimSpace(line), &h); err != nil { return nil, fmt.Errorf("envelope header: %w", err) }
+    if h.Alg != alg { return nil, fmt.Errorf("envelope header: unsupported algorithm %q", h.Alg) }
+    if h.KeyID != k.id { return nil, fmt.Errorf("envelope header: file was encrypted with key %s, configured key is %s", h.KeyID, k.id) }
+    wrapped, err := base64.StdEncoding.DecodeString(h.WrappedKey)
+    if err != nil { return nil, fmt.Errorf("envelope header: %w", err) }
+    raw, err := open(k.aead, wrapped)
+    if err != nil { return nil, fmt.Errorf("envelope header: %w", err) }
+    aead, err := newAEAD(raw)
+    if err != nil { return nil, err }
+    return &DataKey{aead: aead, header: append([]byte(nil), bytes.TrimSpace(line)...)}, nil
+}
+
+// Header is the first line of a file encrypted with d, without newline
+func (d *DataKey) Header() []byte { return d.header }
+
+// SealLine encrypts one line (without its newline)
+func (d *DataKey) SealLine(plain []byte) ([]byte, error) {
+    ct, err := seal(d.aead, plain)
+    if err != nil { return nil, err }
+    out := make([]byte, base64.StdEncoding.EncodedLen(len(ct)))
+    base64.StdEncoding.Encode(out, ct)
+    return out, nil
+}
+
+// OpenLine decrypts a line written by SealLine
+func (d *DataKey) OpenLine(line []byte) ([]byte, error) {
+    line = bytes.TrimSpace(line)
+    ct := make([]byte, base64.StdEncoding.DecodedLen(len(line)))
+    n, err := base64.StdEncoding.Decode(ct, line)
+    if err != nil { return nil, err }
+    return open(d.aead, ct[:n])
+}
+
+// Seal encrypts a whole file as a header line followed by one sealed line.
+// A nil key returns data unchanged.
+func (k *Key) Seal(data []byte) ([]byte, error) {
+    if k == nil { return data, nil }
+    dk, err := k.NewDataKey()
+    if err != nil { return nil, err }
+    body, err := dk.SealLine(data)
+    if err != nil { return nil, err }
+    out := make([]byte, 0, len(dk.header)+len(body)+2)
+    out = append(append(out, dk.header...), '\n')
+    return append(append(out, body...), '\n'), nil
+}
+
+// Open decrypts a file written by Seal. Files without an envelope header are
+// returned unchanged so plaintext files stay readable after enabling encryption.
+func (k *Key) Open(data []byte) ([]byte, error) {
+    first, rest, _ := bytes.Cut(data, []byte("\n"))
+    if !IsHeader(first) { return data, nil }
+    dk, err := k.OpenHeader(first)
+    if err != nil { return nil, err }
+    return dk.OpenLine(rest)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+    block, err := aes.NewCipher(key)
+    if err != nil { return nil, err }
+    return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plain []byte) ([]byte, error) {
+    nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+    if _, err := rand.Read(nonce); err != nil { return nil, err }
+    return aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func open(aead cipher.AEAD, ct []byte) ([]byte, error) {
+    if len(ct) < aead.NonceSize() { return nil, errors.New("ciphertext too short") }
+    return aead.Open(nil, ct[:aead.NonceSize()], ct[aead.NonceSize():], nil)
+}
@@ -0,0 +1,87 @@
+package eval
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "regexp"
+    "strings"
+
+    "github.com/pradord/llm/internal/agent"
+    "github.com/pradord/llm/internal/jsonpath"
+    "github.com/pradord/llm/internal/llm"
+)
+
+// check returns why output fails a, or "" when it passes
+func check(ctx context.Context, a Assertion, prompt, output string, client agent.ChatClient, judgeModel llm.Model) string {
+    switch {
+    case a.Contains != "":
+        if !strings.Contains(output, a.Contains) { return fmt.Sprintf("output does not contain %q", a.Contains) }
+    case a.NotContains != "":
+        if strings.Contains(output, a.NotContains) { return fmt.Sprintf("output contains %q", a.NotContains) }
+    case a.Regex != "":
+        re, err := regexp.Compile(a.Regex)
+        if err != nil { return fmt.Sprintf("bad regex %q: %v", a.Regex, err) }
+        if !re.MatchString(output) { return fmt.Sprintf("output does not match %s", a.Regex) }
+    case a.JSONPath != "":
+        var doc interface{}
+        if err := json.Unmarshal([]byte(extractJSON(output)), &doc); err != nil { return fmt.Sprintf("%s: output is not JSON", a.JSONPath) }
+        v, err := jsonpath.Lookup(doc, a.JSONPath)
+        if err != nil { return err.Error() }
+        if a.Equals != nil && !sameJSON(v, a.Equals) { return fmt.Sprintf("%s is %v, want %v", a.JSONPath, v, a.Equals) }
+    case a.Judge != "":
+        score, reason, err := judge(ctx, client, judgeModel, a.Judge, prompt, output)
+        if err != nil { return fmt.Sprintf("judge: %v", err) }
+        min := a.MinScore
+        if min == 0 { min = 4 }
+        if score < min { return fmt.Sprintf("judge scored %.1f (< %.1f): %s", score, min, reason) }
+    }
+    return ""
+}
+
+// extractJSON returns the JSON value in an answer, ignoring code fences and
+// surrounding prose
+func extractJSON(s string) string {
+    s = strings.TrimSpace(s)
+    if i := strings.IndexAny(s, "{["); i > 0 { s = s[i:] }
+    if j := strings.LastIndexAny(s, "}]"); j >= 0 && j < len(s)-1 { s = s[:j+1] }
+    return s
+}
+
+// sameJSON compares values after a JSON round trip, so 3 equals 3.0
+func sameJSON(a, b interface{}) bool {
+    norm := func(v interface{}) interface{} {
+        data, _ := json.Marshal(v)
+        var out interface{}
+        _ = json.Unmarshal(data, &out)
+        return out
+    }
+    return reflect.DeepEqual(norm(a), norm(b))
+}
+
+const judgePrompt = `You grade answers of an AI assistant against a rubric.
+Reply with only JSON: {"score": <1 to 5>, "reason": "<one sentence>"}.
+
+Rubric:
+%s
+
+Request:
+%s
+
+Answer:
+%s`
+
+// judge asks a model to score output against a rubric from 1 to 5
+func judge(ctx context.Context, client agent.ChatClient, model llm.Model, rubric, prompt, output string) (float64, string, error) {
+    msgs := []map[string]interface{}{{"role": "user", "content": fmt.Sprintf(judgePrompt, rubric, prompt, output)}}
+    resp, err := agent.Complete(ctx, client, &agent.ChatRequest{Messages: msgs, Model: model})
+    if err != nil { return 0, "", err }
+    content := resp.Content
+    var verdict struct {
+        Score  float64 `json:"score"`
+        Reason string  `json:"reason"`
+    }
+    if err := json.Unmarshal([]byte(extractJSON(content)), &verdict); err != nil { return 0, "", fmt.Errorf("unreadable verdict %q", clip(content, 80)) }
+    return verdict.Score, verdict.Reason, nil
+}
@@ -0,0 +1,160 @@
+// Package eval runs golden test cases against skills. A suite is a YAML file
+// of cases for one skill: a prompt or skill inputs, the tools the agent should
+// use, and assertions on the answer. Suites run against a real provider or,
+// for cases with scripted mock replies, offline. Variants let one suite
+// compare models or system prompts; reports record pass rates and estimated
+// costs so runs can be compared over time.
+package eval
+
+import (
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "github.com/pradord/llm/internal/llm"
+    "gopkg.in/yaml.v3"
+)
+
+// Suite is the set of cases for one skill
+type Suite struct {
+    Name        string            `yaml:"name" json:"name"` // defaults to the file name
+    Skill       string            `yaml:"skill" json:"skill"`
+    Description string            `yaml:"description" json:"description,omitempty"`
+    JudgeModel  llm.Model         `yaml:"judge_model" json:"judge_model,omitempty"` // for judge assertions (default: the variant's model)
+    Pricing     map[string]Price  `yaml:"pricing" json:"pricing,omitempty"`         // by model, for cost estimates
+    Variants    []Variant         `yaml:"variants" json:"variants,omitempty"`       // default: the skill as defined
+    Cases       []Case            `yaml:"cases" json:"cases"`
+    Source      string            `yaml:"-" json:"source,omitempty"`
+}
+
+// Price is the cost of a model in USD per million tokens
+type Price struct {
+    Input  float64 `yaml:"input" json:"input"`
+    Output float64 `yaml:"output" json:"output"`
+}
+
+// Variant changes the skill under test, e.g. to compare models or a new
+// version of its system prompt
+type Variant struct {
+    Name         string    `yaml:"name" json:"name"`
+    Model        llm.Model `yaml:"model" json:"model,omitempty"`
+    SystemPrompt string    `yaml:"system_prompt" json:"system_prompt,omitempty"`
+}
+
+// Case is one golden test
+type Case struct {
+    Name        string                 `yaml:"name" json:"name"`
+    Prompt      string                 `yaml:"prompt" json:"prompt,omitempty"`
+    Inputs      map[string]interface{} `yaml:"inputs" json:"inputs,omitempty"` // rendered with the skill's prompt template
+    ExpectTools []string               `yaml:"expect_tools" json:"expect_tools,omitempty"` // each must be called at least once
+    ForbidTools []string               `yaml:"forbid_tools" json:"forbid_tools,omitempty"`
+    Assert      []Assertion            `yaml:"assert" json:"assert,omitempty"`
+    Mock        []MockStep             `yaml:"mock" json:"mock,omitempty"` // scripted model replies for offline runs
+}
+
+// Assertion checks the final answer. Exactly one check is set.
+type Assertion struct {
+    Contains    string      `yaml:"contains" json:"contains,omitempty"`
+    NotContains string      `yaml:"not_contains" json:"not_contains,omitempty"`
+    Regex       string      `yaml:"regex" json:"regex,omitempty"`
+    JSONPath    string      `yaml:"json_path" json:"json_path,omitempty"` // e.g. $.issues[0].line; with Equals, compares, otherwise must exist
+    Equals      interface{} `yaml:"equals" json:"equals,omitempty"`
+    Judge       string      `yaml:"judge" json:"judge,omitempty"`         // rubric for an LLM judge scoring 1 to 5
+    MinScore    float64     `yaml:"min_score" json:"min_score,omitempty"` // passing judge score (default 4)
+}
+
+// MockStep is one scripted model reply: a final answer, or a tool call whose
+// tool returns Result instead of running
+type MockStep struct {
+    Content string                 `yaml:"content" json:"content,omitempty"`
+    Tool    string                 `yaml:"tool" json:"tool,omitempty"`
+    Args    map[string]interface{} `yaml:"args" json:"args,omitempty"`
+    Result  string                 `yaml:"result" json:"result,omitempty"`
+}
+
+func (a Assertion) String() string {
+    switch {
+    case a.Contains != "": return fmt.Sprintf("contains %q", a.Contains)
+    case a.NotContains != "": return fmt.Sprintf("not_contains %q", a.NotContains)
+    case a.Regex != "": return fmt.Sprintf("regex %s", a.Regex)
+    case a.JSONPath != "" && a.Equals != nil: return fmt.Sprintf("%s == %v", a.JSONPath, a.Equals)
+    case a.JSONPath != "": return fmt.Sprintf("%s exists", a.JSONPath)
+    case a.Judge != "": return fmt.Sprintf("judge %q", clip(a.Judge, 40))
+    }
+    return "empty assertion"
+}
+
+// LoadSuite reads a suite file and checks that it is complete
+func LoadSuite(path string) (*Suite, error) {
+    data, err := os.ReadFile(path)
+    if err != nil { return nil, err }
+    var s Suite
+    if err := yaml.Unmarshal(data, &s); err != nil { return nil, fmt.Errorf("%s: %w", path, err) }
+    if s.Name == "" { s.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) }
+    s.Source = path
+    if err := s.validate(); err != nil { return nil, fmt.Errorf("%s: %w", path, err) }
+    return &s, nil
+}
+
+// LoadDir reads every suite (.yaml or .yml) under dir, sorted by name
+func LoadDir(dir string) ([]*Suite, error) {
+    var out []*Suite
+    err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+        if err != nil { return err }
+        if d.IsDir() { return nil }
+        if ext := strings.ToLower(filepath.Ext(d.Name())); ext != ".yaml" && ext != ".yml" { return nil }
+        s, err := LoadSuite(path)
+        if err != nil { return err }
+        out = append(out, s)
+        return nil
+    })
+    sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+    return out, err
+}
+
+func (s *Suite) validate() error {
+    if s.Skill == "" { return fmt.Errorf("suite %s: skill is required", s.Name) }
+    if len(s.Cases) == 0 { return fmt.Errorf("suite %s: no cases", s.Name) }
+    names := map[string]bool{}
+    for i, c := range s.Cases {
+        if c.Name == "" { return fmt.Errorf("case %d: name is required", i+1) }
+        if names[c.Name] { return fmt.Errorf("case %q is defined twice", c.Name) }
+        names[c.Name] = true
+        if c.Prompt == "" && len(c.Inputs) == 0 { return fmt.Errorf("case %q: prompt or inputs is required", c.Name) }
+        for _, a := range c.Assert {
+            if n := a.checks(); n != 1 { return fmt.Errorf("case %q: assertion %q must set exactly one check, has %d", c.Name, a, n) }
+        }
+    }
+    variants := map[string]bool{}
+    for _, v := range s.Variants {
+        if v.Name == "" { return fmt.Errorf("variants need a name") }
+        if variants[v.Name] { return fmt.Errorf("variant %q is defined twice", v.Name) }
+        variants[v.Name] = true
+    }
+    return nil
+}
+
+func (a Assertion) checks() int {
+    n := 0
+    for _, set := range []bool{a.Contains != "", a.NotContains != "", a.Regex != "", a.JSONPath != "", a.Judge != ""} {
+        if set { n++ }
+    }
+    return n
+}
+
+// Mockable reports whether every case has scripted replies, so the suite can
+// run without a provider
+func (s *Suite) Mockable() bool {
+    for _, c := range s.Cases {
+        if len(c.Mock) == 0 { return false }
+    }
+    return true
+}
+
+func clip(s string, n int) string {
+    if len(s) <= n { return s }
+    return s[:n] + "..."
+}
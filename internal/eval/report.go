@@ -0,0 +1,135 @@
+package eval
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/pradord/llm/internal/llm"
+)
+
+// Report is the outcome of one suite variant
+type Report struct {
+    Suite        string        `json:"suite"`
+    Skill        string        `json:"skill"`
+    Variant      string        `json:"variant"`
+    Model        llm.Model     `json:"model,omitempty"`
+    Mock         bool          `json:"mock,omitempty"`
+    StartedAt    time.Time     `json:"started_at"`
+    Duration     time.Duration `json:"duration"`
+    Passed       int           `json:"passed"`
+    Total        int           `json:"total"`
+    InputTokens  int           `json:"input_tokens"`           // as reported by the provider, else estimated
+    OutputTokens int           `json:"output_tokens"`          // as reported by the provider, else estimated
+    JudgeTokens  int           `json:"judge_tokens,omitempty"` // input and output of judge calls, counted the same way
+    Cost         float64       `json:"cost"`                   // USD, from the suite's pricing, judges included
+    Cases        []CaseResult  `json:"cases"`
+}
+
+// CaseResult is the outcome of one case
+type CaseResult struct {
+    Name         string        `json:"name"`
+    Passed       bool          `json:"passed"`
+    Failures     []string      `json:"failures,omitempty"`
+    Skipped      []string      `json:"skipped,omitempty"` // assertions not checked, e.g. judges in mock runs
+    Error        string        `json:"error,omitempty"`
+    ToolsUsed    []string      `json:"tools_used,omitempty"`
+    Steps        int           `json:"steps"`
+    InvalidCalls int           `json:"invalid_tool_calls,omitempty"` // tool calls rejected for invalid arguments
+    Output       string        `json:"output,omitempty"`
+    InputTokens  int           `json:"input_tokens"`
+    OutputTokens int           `json:"output_tokens"`
+    JudgeTokens  int           `json:"judge_tokens,omitempty"`
+    Cost         float64       `json:"cost"` // run at the case's model, judges at the judge model
+    Duration     time.Duration `json:"duration"`
+}
+
+func (r *Report) total() {
+    r.Passed, r.Total, r.InputTokens, r.OutputTokens, r.JudgeTokens, r.Cost = 0, len(r.Cases), 0, 0, 0, 0
+    for _, c := range r.Cases {
+        if c.Passed { r.Passed++ }
+        r.InputTokens += c.InputTokens
+        r.OutputTokens += c.OutputTokens
+        r.JudgeTokens += c.JudgeTokens
+        r.Cost += c.Cost
+    }
+}
+
+// PassRate is the share of passed cases, from 0 to 1
+func (r *Report) PassRate() float64 {
+    if r.Total == 0 { return 0 }
+    return float64(r.Passed) / float64(r.Total)
+}
+
+// Label names the report in comparisons
+func (r *Report) Label() string { return r.Suite + "/" + r.Variant }
+
+// String lists the cases with their failures and a summary line
+func (r *Report) String() string {
+    var b strings.Builder
+    mode := ""
+    if r.Mock { mode = " (mock)" }
+    fmt.Fprintf(&b, "%s [%s, %s]%s\n", r.Label(), r.Skill, r.Model, mode)
+    for _, c := range r.Cases {
+        status := "PASS"
+        if !c.Passed { status = "FAIL" }
+        fmt.Fprintf(&b, "  %s %-32s %d step(s) %6s", status, c.Name, c.Steps, c.Duration.Round(time.Millisecond))
+        if len(c.ToolsUsed) > 0 { fmt.Fprintf(&b, "  tools: %s", strings.Join(c.ToolsUsed, ", ")) }
+        if c.InvalidCalls > 0 { fmt.Fprintf(&b, "  invalid calls: %d", c.InvalidCalls) }
+        b.WriteString("\n")
+        if c.Error != "" { fmt.Fprintf(&b, "       error: %s\n", c.Error) }
+        for _, f := range c.Failures { fmt.Fprintf(&b, "       - %s\n", f) }
+        for _, s := range c.Skipped { fmt.Fprintf(&b, "       skipped: %s\n", s) }
+    }
+    judges := ""
+    if r.JudgeTokens > 0 { judges = fmt.Sprintf(" (+%d judge)", r.JudgeTokens) }
+    fmt.Fprintf(&b, "  %d/%d passed (%.0f%%), ~%d tokens%s, $%.4f, %s\n", r.Passed, r.Total, 100*r.PassRate(), r.InputTokens+r.OutputTokens, judges, r.Cost, r.Duration.Round(time.Millisecond))
+    return b.String()
+}
+
+// Compare tabulates pass rates and costs of reports, e.g. the variants of a
+// suite or saved runs of different prompt versions, and lists the cases whose
+// result differs from the first report of their suite
+func Compare(reports ...*Report) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "%-40s %-32s %9s %8s %10s %10s\n", "REPORT", "MODEL", "PASSED", "RATE", "TOKENS", "COST")
+    for _, r := range reports {
+        fmt.Fprintf(&b, "%-40s %-32s %4d/%-4d %7.0f%% %10d %10.4f\n", clip(r.Label(), 40), r.Model, r.Passed, r.Total, 100*r.PassRate(), r.InputTokens+r.OutputTokens, r.Cost)
+    }
+    // each report is compared with the first report of the same suite
+    base := map[string]map[string]bool{}
+    for _, r := range reports {
+        if base[r.Suite] != nil {
+            for _, c := range r.Cases {
+                was, ok := base[r.Suite][c.Name]
+                switch {
+                case !ok:
+                case was && !c.Passed: fmt.Fprintf(&b, "regressed in %s: %s\n", r.Label(), c.Name)
+                case !was && c.Passed: fmt.Fprintf(&b, "fixed in %s: %s\n", r.Label(), c.Name)
+                }
+            }
+            continue
+        }
+        base[r.Suite] = map[string]bool{}
+        for _, c := range r.Cases { base[r.Suite][c.Name] = c.Passed }
+    }
+    return b.String()
+}
+
+// WriteReports saves reports as JSON for a later Compare
+func WriteReports(path string, reports []*Report) error {
+    data, err := json.MarshalIndent(reports, "", "  ")
+    if err != nil { return err }
+    return os.WriteFile(path, data, 0o644)
+}
+
+// ReadReports loads reports saved by WriteReports
+func ReadReports(path string) ([]*Report, error) {
+    data, err := os.ReadFile(path)
+    if err != nil { return nil, err }
+    var out []*Report
+    if err := json.Unmarshal(data, &out); err != nil { return nil, fmt.Errorf("%s: %w", path, err) }
+    return out, nil
+}
@@ -0,0 +1,255 @@
+package eval
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "sync"
+    "time"
+
+    "github.com/pradord/llm/internal/agent"
+    "github.com/pradord/llm/internal/llm"
+    "github.com/pradord/llm/internal/skills"
+    "github.com/pradord/llm/internal/tools"
+)
+
+// Runner executes suites through agent.Executor
+type Runner struct {
+    Client       agent.ChatClient      // provider; nil runs every case on its Mock replies
+    Skills       *skills.SkillRegistry // the skills under test, with their tool registry
+    Parallel     int                   // cases run at once (default 4)
+    Models       []llm.Model           // extra variants that only change the model
+    DefaultModel llm.Model             // model of skills that set none, for pricing and judging
+}
+
+// Run runs every case of the suite once per variant and returns a report per
+// variant, in the order the variants are defined
+func (r *Runner) Run(ctx context.Context, s *Suite) ([]*Report, error) {
+    skill, ok := r.Skills.Get(s.Skill)
+    if !ok { return nil, fmt.Errorf("suite %s: unknown skill %q", s.Name, s.Skill) }
+    if r.Client == nil && !s.Mockable() { return nil, fmt.Errorf("suite %s: every case needs mock replies to run without a provider", s.Name) }
+    variants := append([]Variant(nil), s.Variants...)
+    if len(variants) == 0 { variants = append(variants, Variant{Name: "default"}) }
+    for _, m := range r.Models {
+        variants = append(variants, Variant{Name: string(m), Model: m})
+    }
+    var reports []*Report
+    for _, v := range variants {
+        rep, err := r.runVariant(ctx, s, skill, v)
+        if err != nil { return reports, err }
+        reports = append(reports, rep)
+        if err := ctx.Err(); err != nil { return reports, err }
+    }
+    return reports, nil
+}
+
+func (r *Runner) runVariant(ctx context.Context, s *Suite, base *skills.Skill, v Variant) (*Report, error) {
+    sk := *base
+    if v.Model != "" { sk.DefaultModel = v.Model }
+    if v.SystemPrompt != "" { sk.SystemPrompt = v.SystemPrompt }
+    model := sk.DefaultModel
+    if model == "" { model = r.DefaultModel }
+    rep := &Report{Suite: s.Name, Skill: s.Skill, Variant: v.Name, Model: model, Mock: r.Client == nil, StartedAt: time.Now()}
+    toolList, err := r.Skills.GetTools(sk.Name)
+    if err != nil { return nil, err }
+
+    parallel := r.Parallel
+    if parallel <= 0 { parallel = 4 }
+    rep.Cases = make([]CaseResult, len(s.Cases))
+    sem := make(chan struct{}, parallel)
+    var wg sync.WaitGroup
+    for i := range s.Cases {
+        select {
+        case sem <- struct{}{}:
+        case <-ctx.Done():
+            // cases that did not start are reported, not run
+            rep.Cases[i] = CaseResult{Name: s.Cases[i].Name, Error: ctx.Err().Error()}
+            continue
+        }
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            defer func() { <-sem }()
+            rep.Cases[i] = r.runCase(ctx, s, &sk, model, toolList, s.Cases[i])
+        }(i)
+    }
+    wg.Wait()
+    rep.Duration = time.Since(rep.StartedAt)
+    rep.total()
+    return rep, nil
+}
+
+func (r *Runner) runCase(ctx context.Context, s *Suite, sk *skills.Skill, model llm.Model, toolList []tools.Tool, c Case) CaseResult {
+    res := CaseResult{Name: c.Name}
+    start := time.Now()
+    defer func() { res.Duration = time.Since(start) }()
+
+    prompt := c.Prompt
+    if len(c.Inputs) > 0 {
+        var err error
+        if prompt, err = sk.RenderPrompt(c.Inputs); err != nil { res.Error = err.Error(); return res }
+    }
+    var client agent.ChatClient = r.Client
+    if client == nil {
+        m := newMock(c.Mock)
+        client, toolList = m, m.tools(toolList)
+    }
+    // judge calls are metered apart so they count neither as the run's tokens
+    // nor at the price of the model under test
+    runMeter, judgeMeter := &meter{ChatClient: client}, &meter{ChatClient: r.Client}
+    judgeModel := s.JudgeModel
+    if judgeModel == "" { judgeModel = model }
+    run, err := agent.NewExecutorWithClient(runMeter).RunWithResult(ctx, sk, prompt, toolList)
+    if err != nil {
+        res.Error = err.Error()
+    } else {
+        res.Output, res.Steps, res.InvalidCalls = run.Output, run.Steps, run.InvalidToolCalls
+        res.ToolsUsed = toolsUsed(run.Messages)
+        res.Failures = checkTools(c, res.ToolsUsed)
+        for _, a := range c.Assert {
+            if a.Judge != "" && r.Client == nil { res.Skipped = append(res.Skipped, a.String()+" (mock run)"); continue }
+            if msg := check(ctx, a, prompt, run.Output, judgeMeter, judgeModel); msg != "" { res.Failures = append(res.Failures, msg) }
+        }
+    }
+    res.Passed = res.Error == "" && len(res.Failures) == 0
+    res.InputTokens, res.OutputTokens = runMeter.tokens()
+    judgeIn, judgeOut := judgeMeter.tokens()
+    res.JudgeTokens = judgeIn + judgeOut
+    res.Cost = s.cost(model, res.InputTokens, res.OutputTokens) + s.cost(judgeModel, judgeIn, judgeOut)
+    return res
+}
+
+// cost prices tokens of model from the suite's pricing; unpriced models cost 0
+func (s *Suite) cost(model llm.Model, in, out int) float64 {
+    p, ok := s.Pricing[string(model)]
+    if !ok { return 0 }
+    return (float64(in)*p.Input + float64(out)*p.Output) / 1e6
+}
+
+// toolsUsed lists the tools called in a transcript, in order of first use
+func toolsUsed(msgs []map[string]interface{}) []string {
+    var out []string
+    seen := map[string]bool{}
+    for _, m := range msgs {
+        calls, _ := m["tool_calls"].([]map[string]interface{})
+        for _, c := range calls {
+            fn, _ := c["function"].(map[string]interface{})
+            name, _ := fn["name"].(string)
+            if name != "" && !seen[name] { seen[name] = true; out = append(out, name) }
+        }
+    }
+    return out
+}
+
+func checkTools(c Case, used []string) []string {
+    have := map[string]bool{}
+    for _, t := range used { have[t] = true }
+    var out []string
+    for _, t := range c.ExpectTools {
+        if !have[t] { out = append(out, fmt.Sprintf("expected tool %s to be called", t)) }
+    }
+    for _, t := range c.ForbidTools {
+        if have[t] { out = append(out, fmt.Sprintf("tool %s must not be called", t)) }
+    }
+    return out
+}
+
+// meter counts the tokens of a client's replies: the provider's figures when
+// it reports them (see agent.CompletionClient), otherwise an estimate of about
+// 4 characters per token
+type meter struct {
+    agent.ChatClient
+    mu      sync.Mutex
+    in, out int
+}
+
+func (m *meter) Complete(ctx context.Context, req *agent.ChatRequest) (*agent.ChatResponse, error) {
+    resp, err := agent.Complete(ctx, m.ChatClient, req)
+    switch {
+    case err != nil:
+        m.estimate(req.Messages, req.Tools, "", nil)
+    case resp.Usage != nil:
+        m.add(resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+    default:
+        m.estimate(req.Messages, req.Tools, resp.Content, resp.ToolCalls)
+    }
+    return resp, err
+}
+
+func (m *meter) ChatWithTools(ctx context.Context, messages []map[string]interface{}, toolSchemas []llm.ToolFunction, opts ...llm.Option) (string, []llm.ToolCall, error) {
+    content, calls, err := m.ChatClient.ChatWithTools(ctx, messages, toolSchemas, opts...)
+    m.estimate(messages, toolSchemas, content, calls)
+    return content, calls, err
+}
+
+func (m *meter) estimate(messages []map[string]interface{}, toolSchemas []llm.ToolFunction, content string, calls []llm.ToolCall) {
+    in := 0
+    for _, msg := range messages {
+        if s, ok := msg["content"].(string); ok { in += len(s) }
+    }
+    if len(toolSchemas) > 0 { b, _ := json.Marshal(toolSchemas); in += len(b) }
+    out := len(content)
+    for _, c := range calls { out += len(c.Function.Name) + len(c.Function.Arguments) }
+    m.add((in+3)/4, (out+3)/4)
+}
+
+func (m *meter) add(in, out int) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.in += in
+    m.out += out
+}
+
+func (m *meter) tokens() (int, int) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    return m.in, m.out
+}
+
+// mock replays a case's scripted replies
+type mock struct {
+    mu      sync.Mutex
+    steps   []MockStep
+    next    int
+    results map[string][]string // tool -> queued results
+}
+
+func newMock(steps []MockStep) *mock { return &mock{steps: steps, results: map[string][]string{}} }
+
+func (m *mock) ChatWithTools(ctx context.Context, messages []map[string]interface{}, toolSchemas []llm.ToolFunction, opts ...llm.Option) (string, []llm.ToolCall, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if m.next >= len(m.steps) { return "", nil, fmt.Errorf("mock: no scripted reply left for step %d", m.next+1) }
+    step := m.steps[m.next]
+    m.next++
+    if step.Tool == "" { return step.Content, nil, nil }
+    args, _ := json.Marshal(step.Args)
+    var call llm.ToolCall
+    call.ID = fmt.Sprintf("mock_%d", m.next)
+    call.Type = "function"
+    call.Function.Name = step.Tool
+    call.Function.Arguments = string(args)
+    m.results[step.Tool] = append(m.results[step.Tool], step.Result)
+    return step.Content, []llm.ToolCall{call}, nil
+}
+
+// tools replaces toolList with stand-ins that return the scripted results
+func (m *mock) tools(toolList []tools.Tool) []tools.Tool {
+    out := make([]tools.Tool, len(toolList))
+    for i, t := range toolList { out[i] = &mockTool{Tool: t, m: m} }
+    return out
+}
+
+type mockTool struct {
+    tools.Tool
+    m *mock
+}
+
+func (t *mockTool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+    t.m.mu.Lock()
+    defer t.m.mu.Unlock()
+    q := t.m.results[t.Name()]
+    if len(q) == 0 { return "", fmt.Errorf("mock: no scripted result for %s", t.Name()) }
+    t.m.results[t.Name()] = q[1:]
+    return q[0], nil
+}
@@ -0,0 +1,46 @@
+// Package filelock provides advisory locks on a lock file so that several
+// processes sharing a data directory (e.g. the CLI and a server) serialize writes.
+package filelock
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "time"
+)
+
+// ErrTimeout is returned when a lock could not be acquired before the timeout
+var ErrTimeout = errors.New("timed out waiting for file lock")
+
+// Lock is a held lock; call Release when done
+type Lock struct {
+    f    *os.File
+    path string
+}
+
+// Acquire takes a shared (readers) or exclusive (writer) lock on path, creating
+// the file if needed. It polls until the lock is free or timeout elapses; a
+// timeout <= 0 makes a single attempt.
+func Acquire(path string, exclusive bool, timeout time.Duration) (*Lock, error) {
+    f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+    if err != nil { return nil, err }
+    deadline := time.Now().Add(timeout)
+    wait := time.Millisecond
+    for {
+        ok, err := tryLock(f, exclusive)
+        if err != nil { f.Close(); return nil, fmt.Errorf("lock %s: %w", path, err) }
+        if ok { return &Lock{f: f, path: path}, nil }
+        if !time.Now().Before(deadline) { f.Close(); return nil, fmt.Errorf("lock %s: %w", path, ErrTimeout) }
+        time.Sleep(wait)
+        if wait < 50*time.Millisecond { wait *= 2 }
+    }
+}
+
+// Release unlocks and closes the lock file
+func (l *Lock) Release() error {
+    if l == nil || l.f == nil { return nil }
+    err := unlock(l.f)
+    if cerr := l.f.Close(); err == nil { err = cerr }
+    l.f = nil
+    return err
+}
@@ -0,0 +1,21 @@
+//go:build !unix
+
+package filelock
+
+import (
+    "os"
+)
+
+// Without flock every lock is exclusive and held by creating a sibling
+// "<path>.held" file. A crashed holder leaves it behind and must be removed by hand.
+
+func tryLock(f *os.File, exclusive bool) (bool, error) {
+    h, err := os.OpenFile(f.Name()+".held", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+    if os.IsExist(err) { return false, nil }
+    if err != nil { return false, err }
+    return true, h.Close()
+}
+
+func unlock(f *os.File) error {
+    return os.Remove(f.Name() + ".held")
+}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package filelock
+
+import (
+    "os"
+    "syscall"
+)
+
+func tryLock(f *os.File, exclusive bool) (bool, error) {
+    how := syscall.LOCK_SH
+    if exclusive { how = syscall.LOCK_EX }
+    for {
+        err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+        switch err {
+        case nil:
+            return true, nil
+        case syscall.EWOULDBLOCK:
+            return false, nil
+        case syscall.EINTR:
+            continue
+        default:
+            return false, err
+        }
+    }
+}
+
+func unlock(f *os.File) error {
+    return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
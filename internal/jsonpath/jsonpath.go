@@ -0,0 +1,57 @@
+// Package jsonpath resolves simple JSON paths such as $.issues[0].line or
+// $.items[*].title in values decoded by encoding/json. Supported steps are
+// .field, ["field"], [index] and [*], which maps the rest of the path over
+// every element of an array.
+package jsonpath
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+var token = regexp.MustCompile(`\.([^.\[\]]+)|\[(\d+|\*)\]|\["([^"]+)"\]`)
+
+// Lookup returns the value at path in doc
+func Lookup(doc interface{}, path string) (interface{}, error) {
+    return lookup(doc, path, strings.TrimPrefix(path, "$"))
+}
+
+func lookup(cur interface{}, path, rest string) (interface{}, error) {
+    for rest != "" {
+        m := token.FindStringSubmatchIndex(rest)
+        if m == nil || m[0] != 0 { return nil, fmt.Errorf("%s: bad path at %q", path, rest) }
+        key, idx, all := "", -1, false
+        switch {
+        case m[2] >= 0: key = rest[m[2]:m[3]]
+        case m[4] >= 0 && rest[m[4]:m[5]] == "*": all = true
+        case m[4] >= 0: idx, _ = strconv.Atoi(rest[m[4]:m[5]])
+        default: key = rest[m[6]:m[7]]
+        }
+        step := rest[:m[1]]
+        rest = rest[m[1]:]
+        switch v := cur.(type) {
+        case map[string]interface{}:
+            if idx >= 0 || all { return nil, fmt.Errorf("%s: not an array", path) }
+            next, ok := v[key]
+            if !ok { return nil, fmt.Errorf("%s: no field %q", path, key) }
+            cur = next
+        case []interface{}:
+            if all {
+                out := make([]interface{}, 0, len(v))
+                for _, e := range v {
+                    r, err := lookup(e, path, rest)
+                    if err != nil { return nil, err }
+                    out = append(out, r)
+                }
+                return out, nil
+            }
+            if idx < 0 || idx >= len(v) { return nil, fmt.Errorf("%s: no element %s", path, step) }
+            cur = v[idx]
+        default:
+            return nil, fmt.Errorf("%s: cannot index %v", path, cur)
+        }
+    }
+    return cur, nil
+}
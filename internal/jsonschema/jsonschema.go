@@ -0,0 +1,254 @@
+// Package jsonschema validates decoded JSON against the subset of JSON Schema
+// that models are asked to follow: type, enum, const, properties, required,
+// additionalProperties, items, length, range and pattern keywords, and
+// allOf/anyOf/oneOf/not. Unknown keywords are ignored.
+package jsonschema
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "reflect"
+    "regexp"
+    "sort"
+    "strings"
+    "unicode/utf8"
+)
+
+// Problem is one way a value fails its schema
+type Problem struct {
+    Path    string `json:"path"` // JSON pointer style, "" for the root
+    Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+    if p.Path == "" { return p.Message }
+    return p.Path + ": " + p.Message
+}
+
+// ValidationError lists the problems found by Validate
+type ValidationError struct {
+    Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+    parts := make([]string, len(e.Problems))
+    for i, p := range e.Problems { parts[i] = p.String() }
+    return "does not match schema: " + strings.Join(parts, "; ")
+}
+
+// Validate checks a value decoded by encoding/json (map[string]interface{},
+// []interface{}, float64, string, bool or nil) against schema and returns a
+// *ValidationError, or nil when it matches
+func Validate(schema map[string]interface{}, v interface{}) error {
+    var problems []Problem
+    validate(schema, v, "", &problems)
+    if len(problems) == 0 { return nil }
+    return &ValidationError{Problems: problems}
+}
+
+// ValidateJSON decodes data and validates it
+func ValidateJSON(schema map[string]interface{}, data []byte) (interface{}, error) {
+    var v interface{}
+    if err := json.Unmarshal(data, &v); err != nil { return nil, fmt.Errorf("invalid JSON: %w", err) }
+    return v, Validate(schema, v)
+}
+
+// ApplyDefaults sets the default of every property missing from the objects
+// in v, recursing into properties and array items, and returns v. Objects are
+// changed in place.
+func ApplyDefaults(schema map[string]interface{}, v interface{}) interface{} {
+    switch x := v.(type) {
+    case map[string]interface{}:
+        props, _ := schema["properties"].(map[string]interface{})
+        for k, p := range props {
+            ps, ok := p.(map[string]interface{})
+            if !ok { continue }
+            if cur, ok := x[k]; ok {
+                x[k] = ApplyDefaults(ps, cur)
+            } else if d, ok := ps["default"]; ok {
+                // a copy, so the schema's value is never shared
+                var cp interface{}
+                if b, err := json.Marshal(d); err == nil && json.Unmarshal(b, &cp) == nil { x[k] = cp }
+            }
+        }
+    case []interface{}:
+        if items, ok := schema["items"].(map[string]interface{}); ok {
+            for i, e := range x { x[i] = ApplyDefaults(items, e) }
+        }
+    }
+    return v
+}
+
+func validate(s map[string]interface{}, v interface{}, path string, out *[]Problem) {
+    add := func(format string, args ...interface{}) { *out = append(*out, Problem{path, fmt.Sprintf(format, args...)}) }
+    v = normalize(v)
+
+    if t, ok := s["type"]; ok && !typeMatches(t, v) {
+        add("expected %s, got %s", typeNames(t), typeOf(v))
+        return
+    }
+    if enum, ok := s["enum"].([]interface{}); ok {
+        found := false
+        for _, e := range enum {
+            if reflect.DeepEqual(normalize(e), v) { found = true; break }
+        }
+        if !found { add("must be one of %s", compact(enum)) }
+    }
+    if c, ok := s["const"]; ok && !reflect.DeepEqual(normalize(c), v) { add("must be %s", compact(c)) }
+
+    switch x := v.(type) {
+    case string:
+        n := utf8.RuneCountInString(x)
+        if min, ok := num(s["minLength"]); ok && float64(n) < min { add("must be at least %g characters", min) }
+        if max, ok := num(s["maxLength"]); ok && float64(n) > max { add("must be at most %g characters", max) }
+        if p, ok := s["pattern"].(string); ok {
+            if re, err := regexp.Compile(p); err == nil && !re.MatchString(x) { add("must match pattern %q", p) }
+        }
+    case float64:
+        if min, ok := num(s["minimum"]); ok && x < min { add("must be >= %g", min) }
+        if max, ok := num(s["maximum"]); ok && x > max { add("must be <= %g", max) }
+        if min, ok := num(s["exclusiveMinimum"]); ok && x <= min { add("must be > %g", min) }
+        if max, ok := num(s["exclusiveMaximum"]); ok && x >= max { add("must be < %g", max) }
+        if m, ok := num(s["multipleOf"]); ok && m > 0 && math.Abs(math.Remainder(x, m)) > 1e-9 { add("must be a multiple of %g", m) }
+    case []interface{}:
+        if min, ok := num(s["minItems"]); ok && float64(len(x)) < min { add("must have at least %g items", min) }
+        if max, ok := num(s["maxItems"]); ok && float64(len(x)) > max { add("must have at most %g items", max) }
+        if items, ok := s["items"].(map[string]interface{}); ok {
+            for i, e := range x { validate(items, e, fmt.Sprintf("%s/%d", path, i), out) }
+        }
+        if u, _ := s["uniqueItems"].(bool); u {
+            for i := range x {
+                for j := i + 1; j < len(x); j++ {
+                    if reflect.DeepEqual(normalize(x[i]), normalize(x[j])) { add("items %d and %d are equal", i, j) }
+                }
+            }
+        }
+    case map[string]interface{}:
+        props, _ := s["properties"].(map[string]interface{})
+        for _, r := range strs(s["required"]) {
+            if _, ok := x[r]; !ok { add("missing required property %q", r) }
+        }
+        keys := make([]string, 0, len(x))
+        for k := range x { keys = append(keys, k) }
+        sort.Strings(keys)
+        for _, k := range keys {
+            if ps, ok := props[k].(map[string]interface{}); ok {
+                validate(ps, x[k], path+"/"+escape(k), out)
+                continue
+            }
+            switch ap := s["additionalProperties"].(type) {
+            case bool:
+                if !ap { add("unexpected property %q", k) }
+            case map[string]interface{}:
+                validate(ap, x[k], path+"/"+escape(k), out)
+            }
+        }
+        if min, ok := num(s["minProperties"]); ok && float64(len(x)) < min { add("must have at least %g properties", min) }
+        if max, ok := num(s["maxProperties"]); ok && float64(len(x)) > max { add("must have at most %g properties", max) }
+    }
+
+    if all, ok := s["allOf"].([]interface{}); ok {
+        for _, sub := range all {
+            if m, ok := sub.(map[string]interface{}); ok { validate(m, v, path, out) }
+        }
+    }
+    if any, ok := s["anyOf"].([]interface{}); ok && matching(any, v) == 0 { add("must match at least one schema in anyOf") }
+    if one, ok := s["oneOf"].([]interface{}); ok {
+        if n := matching(one, v); n != 1 { add("must match exactly one schema in oneOf, matches %d", n) }
+    }
+    if not, ok := s["not"].(map[string]interface{}); ok && Validate(not, v) == nil { add("must not match the schema in not") }
+}
+
+func matching(schemas []interface{}, v interface{}) int {
+    n := 0
+    for _, sub := range schemas {
+        if m, ok := sub.(map[string]interface{}); ok && Validate(m, v) == nil { n++ }
+    }
+    return n
+}
+
+func typeMatches(t interface{}, v interface{}) bool {
+    names := strs(t)
+    if s, ok := t.(string); ok { names = []string{s} }
+    for _, name := range names {
+        switch name {
+        case "integer":
+            if f, ok := v.(float64); ok && f == math.Trunc(f) { return true }
+        case "number":
+            if _, ok := v.(float64); ok { return true }
+        default:
+            if typeOf(v) == name { return true }
+        }
+    }
+    return false
+}
+
+func typeNames(t interface{}) string {
+    if s, ok := t.(string); ok { return s }
+    return strings.Join(strs(t), " or ")
+}
+
+func typeOf(v interface{}) string {
+    switch v.(type) {
+    case nil:
+        return "null"
+    case bool:
+        return "boolean"
+    case float64:
+        return "number"
+    case string:
+        return "string"
+    case []interface{}:
+        return "array"
+    case map[string]interface{}:
+        return "object"
+    }
+    return fmt.Sprintf("%T", v)
+}
+
+// normalize maps Go numbers and YAML-decoded values onto the encoding/json types
+func normalize(v interface{}) interface{} {
+    switch x := v.(type) {
+    case int:
+        return float64(x)
+    case int64:
+        return float64(x)
+    case float32:
+        return float64(x)
+    case json.Number:
+        f, _ := x.Float64()
+        return f
+    case []string:
+        out := make([]interface{}, len(x))
+        for i, s := range x { out[i] = s }
+        return out
+    }
+    return v
+}
+
+func num(v interface{}) (float64, bool) {
+    f, ok := normalize(v).(float64)
+    return f, ok
+}
+
+func strs(v interface{}) []string {
+    switch x := v.(type) {
+    case []string:
+        return x
+    case []interface{}:
+        out := make([]string, 0, len(x))
+        for _, e := range x {
+            if s, ok := e.(string); ok { out = append(out, s) }
+        }
+        return out
+    }
+    return nil
+}
+
+func escape(k string) string { return strings.ReplaceAll(strings.ReplaceAll(k, "~", "~0"), "/", "~1") }
+
+func compact(v interface{}) string {
+    b, _ := json.Marshal(v)
+    return string(b)
+}
@@ -0,0 +1,72 @@
+package jsonschema
+
+import (
+    "encoding/json"
+    "reflect"
+    "strings"
+    "time"
+)
+
+// For derives a schema from a Go type the way encoding/json would encode it.
+// Struct fields are required unless tagged omitempty; a `jsonschema:"..."` tag
+// becomes the field's description. Struct objects allow no other properties.
+func For(t reflect.Type) map[string]interface{} {
+    return forType(t, map[reflect.Type]bool{})
+}
+
+var (
+    timeType      = reflect.TypeOf(time.Time{})
+    rawType       = reflect.TypeOf(json.RawMessage(nil))
+    marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+func forType(t reflect.Type, seen map[reflect.Type]bool) map[string]interface{} {
+    for t.Kind() == reflect.Pointer { t = t.Elem() }
+    switch {
+    case t == timeType:
+        return map[string]interface{}{"type": "string", "format": "date-time"}
+    case t == rawType || t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType):
+        return map[string]interface{}{} // custom encoding, anything goes
+    }
+    switch t.Kind() {
+    case reflect.Bool:
+        return map[string]interface{}{"type": "boolean"}
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return map[string]interface{}{"type": "integer"}
+    case reflect.Float32, reflect.Float64:
+        return map[string]interface{}{"type": "number"}
+    case reflect.String:
+        return map[string]interface{}{"type": "string"}
+    case reflect.Slice, reflect.Array:
+        if t.Elem().Kind() == reflect.Uint8 { return map[string]interface{}{"type": "string"} } // base64
+        return map[string]interface{}{"type": "array", "items": forType(t.Elem(), seen)}
+    case reflect.Map:
+        return map[string]interface{}{"type": "object", "additionalProperties": forType(t.Elem(), seen)}
+    case reflect.Struct:
+        if seen[t] { return map[string]interface{}{"type": "object"} } // recursive type
+        seen[t] = true
+        defer delete(seen, t)
+        props := map[string]interface{}{}
+        required := []interface{}{}
+        for i := 0; i < t.NumField(); i++ {
+            f := t.Field(i)
+            if !f.IsExported() { continue }
+            name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+            if name == "-" { continue }
+            if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+                embedded := forType(f.Type, seen)
+                for k, v := range embedded["properties"].(map[string]interface{}) { props[k] = v }
+                required = append(required, embedded["required"].([]interface{})...)
+                continue
+            }
+            if name == "" { name = f.Name }
+            ps := forType(f.Type, seen)
+            if d := f.Tag.Get("jsonschema"); d != "" { ps["description"] = d }
+            props[name] = ps
+            if !strings.Contains(opts, "omitempty") { required = append(required, name) }
+        }
+        return map[string]interface{}{"type": "object", "properties": props, "required": required, "additionalProperties": false}
+    }
+    return map[string]interface{}{}
+}
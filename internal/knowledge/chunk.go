@@ -0,0 +1,57 @@
+package knowledge
+
+import (
+    "strings"
+    "unicode/utf8"
+)
+
+type line struct {
+    text string
+    n    int // 1-based line number in the document
+}
+
+// SplitText cuts text into chunks of about size characters on line boundaries.
+// Consecutive chunks share up to overlap characters of whole lines so a passage
+// cut in two is still found in one piece. In Markdown, a heading starts a new
+// chunk once the current one is half full. Lines longer than size are split.
+func SplitText(text string, size, overlap int) []Chunk {
+    var lines []line
+    for i, l := range strings.SplitAfter(text, "\n") {
+        for len(l) > size {
+            cut := strings.LastIndexAny(l[:size], " \t")
+            if cut <= 0 {
+                // no space to cut at: back off to a rune boundary, or take one
+                // whole rune when size is shorter than it
+                cut = size
+                for cut > 0 && !utf8.RuneStart(l[cut]) { cut-- }
+                if cut == 0 { _, cut = utf8.DecodeRuneInString(l) }
+            }
+            lines = append(lines, line{l[:cut], i + 1})
+            l = l[cut:]
+        }
+        if l != "" { lines = append(lines, line{l, i + 1}) }
+    }
+
+    var out []Chunk
+    var cur []line
+    curLen, fresh := 0, 0 // fresh counts characters not yet part of an emitted chunk
+    flush := func(carry bool) {
+        var b strings.Builder
+        for _, l := range cur { b.WriteString(l.text) }
+        if s := strings.TrimSpace(b.String()); s != "" { out = append(out, Chunk{Line: cur[0].n, Text: s}) }
+        keep, n := len(cur), 0
+        for carry && keep > 0 && n+len(cur[keep-1].text) <= overlap { keep--; n += len(cur[keep].text) }
+        cur, curLen, fresh = append([]line(nil), cur[keep:]...), n, 0
+    }
+    for _, l := range lines {
+        heading := strings.HasPrefix(l.text, "#") && curLen >= size/2
+        if fresh > 0 && (curLen+len(l.text) > size || heading) {
+            flush(!heading) // a new section does not need the previous one's tail
+        }
+        cur = append(cur, l)
+        curLen += len(l.text)
+        fresh += len(l.text)
+    }
+    if fresh > 0 { flush(false) }
+    return out
+}
@@ -0,0 +1,138 @@
+package knowledge
+
+import (
+    "bytes"
+    "compress/zlib"
+    "errors"
+    "fmt"
+    "io"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "unicode/utf8"
+)
+
+// textExts are read as plain text; code is indexed as is
+var textExts = map[string]bool{
+    ".md": true, ".markdown": true, ".mdx": true, ".txt": true, ".rst": true, ".adoc": true, ".org": true,
+    ".go": true, ".py": true, ".js": true, ".jsx": true, ".ts": true, ".tsx": true, ".java": true, ".kt": true,
+    ".rs": true, ".c": true, ".h": true, ".cc": true, ".cpp": true, ".hpp": true, ".cs": true, ".rb": true,
+    ".php": true, ".swift": true, ".scala": true, ".sh": true, ".sql": true, ".proto": true,
+    ".yaml": true, ".yml": true, ".json": true, ".toml": true, ".ini": true, ".csv": true,
+    ".html": true, ".htm": true, ".css": true, ".xml": true,
+}
+
+// Supported reports whether Extract handles the file type of path
+func Supported(path string) bool {
+    ext := strings.ToLower(filepath.Ext(path))
+    return textExts[ext] || ext == ".pdf"
+}
+
+// Extract returns the text of a document
+func Extract(path string, data []byte) (string, error) {
+    if strings.ToLower(filepath.Ext(path)) == ".pdf" { return extractPDF(data) }
+    if !utf8.Valid(data) { return "", errors.New("not UTF-8 text") }
+    return string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), nil
+}
+
+var (
+    pdfStream   = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+    pdfTextOps  = regexp.MustCompile(`(?s)BT(.*?)ET`)
+    pdfLineOps  = regexp.MustCompile(`\s(T\*|Td|TD|'|")\s`)
+)
+
+// extractPDF is a best-effort extractor for text-based PDFs: it inflates content
+// streams and collects the strings shown by Tj, TJ, ' and " operators. Scanned
+// documents and fonts with custom encodings yield no usable text.
+func extractPDF(data []byte) (string, error) {
+    if !bytes.HasPrefix(data, []byte("%PDF")) { return "", errors.New("not a PDF file") }
+    var b strings.Builder
+    for _, loc := range pdfStream.FindAllSubmatchIndex(data, -1) {
+        dict := data[loc[2]:loc[3]]
+        start := loc[1]
+        end := bytes.Index(data[start:], []byte("endstream"))
+        if end < 0 { break }
+        body := data[start : start+end]
+        if bytes.Contains(dict, []byte("/FlateDecode")) {
+            r, err := zlib.NewReader(bytes.NewReader(body))
+            if err != nil { continue }
+            body, err = io.ReadAll(r)
+            if err != nil && len(body) == 0 { continue }
+        } else if bytes.Contains(dict, []byte("/Filter")) {
+            continue // images and other encodings
+        }
+        for _, m := range pdfTextOps.FindAllSubmatch(body, -1) {
+            b.WriteString(pdfText(m[1]))
+            b.WriteString("\n")
+        }
+    }
+    text := strings.TrimSpace(b.String())
+    if text == "" { return "", errors.New("no extractable text in PDF (scanned or unsupported encoding)") }
+    return text, nil
+}
+
+// pdfText decodes the literal strings in a BT..ET text object
+func pdfText(ops []byte) string {
+    ops = pdfLineOps.ReplaceAll(ops, []byte(" \x00 "))
+    var b strings.Builder
+    for i := 0; i < len(ops); i++ {
+        switch c := ops[i]; c {
+        case 0:
+            b.WriteByte('\n')
+        case '(':
+            s, n := pdfLiteral(ops[i:])
+            b.WriteString(s)
+            i += n - 1
+        default:
+            // in a TJ array, a large negative kerning between two strings is a word gap
+            if c == '-' && i+1 < len(ops) && ops[i+1] >= '0' && ops[i+1] <= '9' {
+                j := i + 1
+                for j < len(ops) && (ops[j] >= '0' && ops[j] <= '9' || ops[j] == '.') { j++ }
+                var v float64
+                fmt.Sscanf(string(ops[i+1:j]), "%g", &v)
+                if v > 200 { b.WriteByte(' ') }
+                i = j - 1
+            }
+        }
+    }
+    return b.String()
+}
+
+// pdfLiteral decodes a (...) string starting at s[0] and returns its length in s
+func pdfLiteral(s []byte) (string, int) {
+    var b strings.Builder
+    depth := 0
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        switch {
+        case c == '\\' && i+1 < len(s):
+            i++
+            switch e := s[i]; e {
+            case 'n':
+                b.WriteByte('\n')
+            case 'r', 't', 'b', 'f':
+                b.WriteByte(' ')
+            case '\r', '\n':
+            default:
+                if e >= '0' && e <= '7' {
+                    v, j := 0, i
+                    for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7' { v = v*8 + int(s[j]-'0'); j++ }
+                    if v >= 32 && v < 127 { b.WriteByte(byte(v)) }
+                    i = j - 1
+                } else {
+                    b.WriteByte(e)
+                }
+            }
+        case c == '(':
+            if depth > 0 { b.WriteByte(c) }
+            depth++
+        case c == ')':
+            depth--
+            if depth == 0 { return b.String(), i + 1 }
+            b.WriteByte(c)
+        case c >= 32 && c < 127:
+            b.WriteByte(c)
+        }
+    }
+    return b.String(), len(s)
+}
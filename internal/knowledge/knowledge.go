@@ -0,0 +1,212 @@
+// Package knowledge keeps a per-project knowledge base: documents from a folder
+// are split into overlapping chunks, embedded, and searched by cosine similarity.
+// Ingest is incremental; only files whose content changed are embedded again.
+package knowledge
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/pradord/llm/internal/embeddings"
+    "github.com/pradord/llm/internal/envelope"
+)
+
+// Embedder turns text into a vector; *embeddings.Client implements it
+type Embedder interface {
+    Embed(ctx context.Context, input string) ([]float64, error)
+}
+
+type Config struct {
+    ChunkSize   int           // target chunk length in characters (default 1500)
+    Overlap     int           // characters repeated between neighbouring chunks (default 200, <0 disables)
+    MaxFileSize int64         // larger files are skipped (default 5 MiB)
+    Model       string        // embedding model name; changing it re-embeds everything
+    Key         *envelope.Key // encrypt the index at rest
+}
+
+// Chunk is one embedded piece of a document
+type Chunk struct {
+    Path   string    `json:"path"` // relative to the ingested directory
+    Line   int       `json:"line"` // first line of the chunk
+    Text   string    `json:"text"`
+    Vector []float64 `json:"vector,omitempty"`
+}
+
+type fileEntry struct {
+    Hash    string    `json:"hash"`
+    Size    int64     `json:"size"`
+    ModTime time.Time `json:"mod_time"`
+    Chunks  []Chunk   `json:"chunks"`
+}
+
+type index struct {
+    Model     string                `json:"model,omitempty"`
+    Source    string                `json:"source,omitempty"`
+    UpdatedAt time.Time             `json:"updated_at"`
+    Files     map[string]*fileEntry `json:"files"`
+}
+
+// Base is the knowledge base of one project, stored as <dir>/index.json
+type Base struct {
+    dir      string
+    embedder Embedder
+    cfg      Config
+    mu       sync.RWMutex
+    idx      *index
+}
+
+// Result is a chunk matching a query
+type Result struct {
+    Chunk
+    Score float64 `json:"score"`
+}
+
+// IngestReport lists the files Ingest looked at, by relative path
+type IngestReport struct {
+    Added     []string          `json:"added,omitempty"`
+    Updated   []string          `json:"updated,omitempty"`
+    Removed   []string          `json:"removed,omitempty"`
+    Unchanged int               `json:"unchanged"`
+    Skipped   map[string]string `json:"skipped,omitempty"` // path -> reason
+    Chunks    int               `json:"chunks"`            // chunks embedded by this run
+}
+
+// Dir returns the directory of a project's knowledge base under root
+func Dir(root, projectID string) string { return filepath.Join(root, projectID) }
+
+// Open loads the knowledge base stored in dir, or starts an empty one
+func Open(dir string, e Embedder, cfg Config) (*Base, error) {
+    if cfg.ChunkSize <= 0 { cfg.ChunkSize = 1500 }
+    if cfg.Overlap == 0 { cfg.Overlap = 200 } else if cfg.Overlap < 0 { cfg.Overlap = 0 }
+    if cfg.Overlap >= cfg.ChunkSize { cfg.Overlap = cfg.ChunkSize / 4 }
+    if cfg.MaxFileSize <= 0 { cfg.MaxFileSize = 5 << 20 }
+    b := &Base{dir: dir, embedder: e, cfg: cfg, idx: &index{Files: map[string]*fileEntry{}}}
+    data, err := os.ReadFile(b.path())
+    if errors.Is(err, os.ErrNotExist) { return b, nil }
+    if err != nil { return nil, err }
+    if data, err = cfg.Key.Open(data); err != nil { return nil, err }
+    if err := json.Unmarshal(data, b.idx); err != nil { return nil, fmt.Errorf("knowledge index %s: %w", b.path(), err) }
+    if b.idx.Files == nil { b.idx.Files = map[string]*fileEntry{} }
+    return b, nil
+}
+
+func (b *Base) path() string { return filepath.Join(b.dir, "index.json") }
+
+// Len returns the number of stored chunks
+func (b *Base) Len() int {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    n := 0
+    for _, f := range b.idx.Files { n += len(f.Chunks) }
+    return n
+}
+
+// Ingest brings the knowledge base in line with the documents under srcDir:
+// new and modified files are chunked and embedded, deleted files are dropped,
+// and the index is saved. Unsupported or unreadable files are reported as skipped.
+func (b *Base) Ingest(ctx context.Context, srcDir string) (*IngestReport, error) {
+    if b.embedder == nil { return nil, errors.New("knowledge: no embedder configured") }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    rep := &IngestReport{Skipped: map[string]string{}}
+    if b.idx.Model != b.cfg.Model || b.idx.Source != srcDir {
+        // vectors from another model are not comparable; paths from another folder are stale
+        b.idx.Files = map[string]*fileEntry{}
+    }
+    seen := map[string]bool{}
+    err := filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+        if err != nil { return err }
+        if d.IsDir() {
+            if path != srcDir && strings.HasPrefix(d.Name(), ".") { return filepath.SkipDir }
+            return nil
+        }
+        rel, _ := filepath.Rel(srcDir, path)
+        rel = filepath.ToSlash(rel)
+        if !Supported(path) { return nil }
+        seen[rel] = true
+        info, err := d.Info()
+        if err != nil { rep.Skipped[rel] = err.Error(); return nil }
+        if info.Size() > b.cfg.MaxFileSize { rep.Skipped[rel] = "file too large"; return nil }
+        prev := b.idx.Files[rel]
+        if prev != nil && prev.Size == info.Size() && prev.ModTime.Equal(info.ModTime()) {
+            rep.Unchanged++
+            return nil
+        }
+        data, err := os.ReadFile(path)
+        if err != nil { rep.Skipped[rel] = err.Error(); return nil }
+        sum := sha256.Sum256(data)
+        hash := hex.EncodeToString(sum[:])
+        if prev != nil && prev.Hash == hash {
+            prev.Size, prev.ModTime = info.Size(), info.ModTime()
+            rep.Unchanged++
+            return nil
+        }
+        text, err := Extract(path, data)
+        if err != nil { rep.Skipped[rel] = err.Error(); delete(b.idx.Files, rel); return nil }
+        entry := &fileEntry{Hash: hash, Size: info.Size(), ModTime: info.ModTime()}
+        for _, c := range SplitText(text, b.cfg.ChunkSize, b.cfg.Overlap) {
+            vec, err := b.embedder.Embed(ctx, "File: "+rel+"\n\n"+c.Text)
+            if err != nil { return fmt.Errorf("embed %s: %w", rel, err) }
+            c.Path, c.Vector = rel, vec
+            entry.Chunks = append(entry.Chunks, c)
+        }
+        b.idx.Files[rel] = entry
+        rep.Chunks += len(entry.Chunks)
+        if prev == nil { rep.Added = append(rep.Added, rel) } else { rep.Updated = append(rep.Updated, rel) }
+        return nil
+    })
+    if err == nil {
+        for rel := range b.idx.Files {
+            if !seen[rel] { delete(b.idx.Files, rel); rep.Removed = append(rep.Removed, rel) }
+        }
+        sort.Strings(rep.Removed)
+    }
+    // keep what was embedded before an error so a retry does not start over
+    b.idx.Model, b.idx.Source, b.idx.UpdatedAt = b.cfg.Model, srcDir, time.Now()
+    if serr := b.save(); err == nil { err = serr }
+    return rep, err
+}
+
+func (b *Base) save() error {
+    data, err := json.Marshal(b.idx)
+    if err != nil { return err }
+    if data, err = b.cfg.Key.Seal(data); err != nil { return err }
+    if err := os.MkdirAll(b.dir, 0o700); err != nil { return err }
+    tmp := b.path() + ".tmp"
+    if err := os.WriteFile(tmp, data, 0o600); err != nil { return err }
+    return os.Rename(tmp, b.path())
+}
+
+// Search returns the k chunks most similar to query, best first
+func (b *Base) Search(ctx context.Context, query string, k int) ([]Result, error) {
+    if b.embedder == nil { return nil, errors.New("knowledge: no embedder configured") }
+    if k <= 0 { k = 5 }
+    q, err := b.embedder.Embed(ctx, query)
+    if err != nil { return nil, err }
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    var out []Result
+    for _, f := range b.idx.Files {
+        for _, c := range f.Chunks {
+            out = append(out, Result{Chunk: c, Score: embeddings.Cosine(q, c.Vector)})
+        }
+    }
+    sort.Slice(out, func(i, j int) bool {
+        if out[i].Score != out[j].Score { return out[i].Score > out[j].Score }
+        if out[i].Path != out[j].Path { return out[i].Path < out[j].Path }
+        return out[i].Line < out[j].Line
+    })
+    if len(out) > k { out = out[:k] }
+    return out, nil
+}
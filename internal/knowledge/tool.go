@@ -0,0 +1,60 @@
+package knowledge
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "strings"
+
+    "github.com/pradord/llm/internal/llm"
+)
+
+// SearchTool exposes a project's knowledge base to the model as search_knowledge
+type SearchTool struct {
+    base      *Base
+    projectID string
+    k         int
+}
+
+func NewSearchTool(b *Base, projectID string) *SearchTool {
+    return &SearchTool{base: b, projectID: projectID, k: 5}
+}
+
+func (t *SearchTool) Name() string { return "search_knowledge" }
+
+func (t *SearchTool) Description() string {
+    return fmt.Sprintf("Search the documents of project %s and return the most relevant passages with their file and line", t.projectID)
+}
+
+func (t *SearchTool) Parameters() interface{} {
+    return map[string]interface{}{
+        "type": "object",
+        "properties": map[string]interface{}{
+            "query": map[string]interface{}{"type": "string", "description": "What to look for, phrased as a question or keywords"},
+            "k":     map[string]interface{}{"type": "integer", "description": "Number of passages to return (default 5, max 20)"},
+        },
+        "required": []string{"query"},
+    }
+}
+
+func (t *SearchTool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+    var p struct {
+        Query string `json:"query"`
+        K     int    `json:"k"`
+    }
+    if err := json.Unmarshal(args, &p); err != nil { return "", err }
+    if strings.TrimSpace(p.Query) == "" { return "", fmt.Errorf("query is required") }
+    if p.K <= 0 { p.K = t.k }
+    if p.K > 20 { p.K = 20 }
+    results, err := t.base.Search(ctx, p.Query, p.K)
+    if err != nil { return "", err }
+    if len(results) == 0 { return "No matching documents.", nil }
+    var b strings.Builder
+    for i, r := range results {
+        fmt.Fprintf(&b, "[%d] %s:%d (score %.2f)\n%s\n\n", i+1, r.Path, r.Line, r.Score, r.Text)
+    }
+    return strings.TrimSpace(b.String()), nil
+}
+
+func (t *SearchTool) RequiredModel() llm.Model  { return "" }
+func (t *SearchTool) ModelType() llm.ModelType { return llm.ModelTypeText }
@@ -0,0 +1,284 @@
+// Package mcp connects to Model Context Protocol servers and registers their
+// tools in a tools.ToolRegistry. Servers run as subprocesses speaking over
+// stdio, or are reached over the streamable HTTP transport.
+package mcp
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "regexp"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/pradord/llm/internal/config"
+    "github.com/pradord/llm/internal/llm"
+    "github.com/pradord/llm/internal/tools"
+)
+
+// ProtocolVersion is the MCP revision this package implements
+const ProtocolVersion = "2025-03-26"
+
+// ClientName is sent to servers as the client's name
+const ClientName = "llm"
+
+// ToolInfo is a tool listed by a server
+type ToolInfo struct {
+    Name        string                 `json:"name"`
+    Description string                 `json:"description,omitempty"`
+    InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// Content is one item of a tool result
+type Content struct {
+    Type     string           `json:"type"` // text, image, audio, resource or resource_link
+    Text     string           `json:"text,omitempty"`
+    Data     string           `json:"data,omitempty"` // base64 image or audio
+    MimeType string           `json:"mimeType,omitempty"`
+    URI      string           `json:"uri,omitempty"` // resource_link
+    Resource *ResourceContent `json:"resource,omitempty"`
+}
+
+// ResourceContent is the contents of a resource
+type ResourceContent struct {
+    URI      string `json:"uri"`
+    MimeType string `json:"mimeType,omitempty"`
+    Text     string `json:"text,omitempty"`
+    Blob     string `json:"blob,omitempty"` // base64
+}
+
+// CallResult is the result of a tools/call
+type CallResult struct {
+    Content           []Content   `json:"content"`
+    StructuredContent interface{} `json:"structuredContent,omitempty"`
+    IsError           bool        `json:"isError,omitempty"`
+}
+
+// Text renders the result for a model: text items as they are, other items
+// as short placeholders
+func (r *CallResult) Text() string {
+    var parts []string
+    for _, c := range r.Content {
+        switch {
+        case c.Type == "text":
+            parts = append(parts, c.Text)
+        case c.Type == "resource" && c.Resource != nil && c.Resource.Text != "":
+            parts = append(parts, c.Resource.Text)
+        case c.Type == "resource" && c.Resource != nil:
+            parts = append(parts, fmt.Sprintf("[resource %s]", c.Resource.URI))
+        case c.Type == "resource_link":
+            parts = append(parts, fmt.Sprintf("[resource %s]", c.URI))
+        default:
+            parts = append(parts, fmt.Sprintf("[%s %s, %d bytes]", c.Type, c.MimeType, len(c.Data)*3/4))
+        }
+    }
+    if len(parts) == 0 && r.StructuredContent != nil {
+        b, _ := json.Marshal(r.StructuredContent)
+        return string(b)
+    }
+    return strings.Join(parts, "\n")
+}
+
+// Client is a connection to one MCP server. It is safe for concurrent use.
+type Client struct {
+    Name       string
+    ServerInfo struct {
+        Name    string `json:"name"`
+        Version string `json:"version"`
+    }
+    t       transport
+    timeout time.Duration
+    nextID  int64
+}
+
+var serverName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Connect starts or reaches the server and performs the initialization
+// handshake
+func Connect(ctx context.Context, cfg config.MCPServerConfig) (*Client, error) {
+    if !serverName.MatchString(cfg.Name) { return nil, fmt.Errorf("mcp server %q: name must be letters, digits, '_' or '-'", cfg.Name) }
+    if (cfg.Command == "") == (cfg.URL == "") { return nil, fmt.Errorf("mcp server %s: set either command or url", cfg.Name) }
+    c := &Client{Name: cfg.Name, timeout: 60 * time.Second}
+    if cfg.TimeoutSeconds > 0 { c.timeout = time.Duration(cfg.TimeoutSeconds) * time.Second }
+    if cfg.Command != "" {
+        t, err := newStdioTransport(cfg.Command, cfg.Args, cfg.Env)
+        if err != nil { return nil, fmt.Errorf("mcp server %s: %w", cfg.Name, err) }
+        c.t = t
+    } else {
+        c.t = newHTTPTransport(cfg.URL, cfg.Headers)
+    }
+    if err := c.initialize(ctx); err != nil {
+        c.t.close()
+        return nil, err
+    }
+    return c, nil
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+    params := map[string]interface{}{
+        "protocolVersion": ProtocolVersion,
+        "capabilities":    map[string]interface{}{},
+        "clientInfo":      map[string]string{"name": ClientName, "version": "1.0"},
+    }
+    var res struct {
+        ProtocolVersion string `json:"protocolVersion"`
+        ServerInfo      struct {
+            Name    string `json:"name"`
+            Version string `json:"version"`
+        } `json:"serverInfo"`
+    }
+    if err := c.call(ctx, "initialize", params, &res); err != nil { return err }
+    c.ServerInfo.Name, c.ServerInfo.Version = res.ServerInfo.Name, res.ServerInfo.Version
+    if h, ok := c.t.(*httpTransport); ok {
+        h.mu.Lock()
+        h.version = res.ProtocolVersion
+        h.mu.Unlock()
+    }
+    if _, err := c.t.send(ctx, &message{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+        return fmt.Errorf("mcp server %s: %w", c.Name, err)
+    }
+    return nil
+}
+
+// call sends a request and decodes its result into out
+func (c *Client) call(ctx context.Context, method string, params, out interface{}) error {
+    ctx, cancel := context.WithTimeout(ctx, c.timeout)
+    defer cancel()
+    p, err := json.Marshal(params)
+    if err != nil { return err }
+    id := atomic.AddInt64(&c.nextID, 1)
+    resp, err := c.t.send(ctx, &message{JSONRPC: "2.0", ID: json.RawMessage(fmt.Sprint(id)), Method: method, Params: p})
+    if err != nil { return fmt.Errorf("mcp server %s: %s: %w", c.Name, method, err) }
+    if resp.Error != nil { return fmt.Errorf("mcp server %s: %s: %w", c.Name, method, resp.Error) }
+    if out == nil { return nil }
+    if err := json.Unmarshal(resp.Result, out); err != nil { return fmt.Errorf("mcp server %s: %s: invalid result: %w", c.Name, method, err) }
+    return nil
+}
+
+// ListTools returns every tool of the server, following pagination
+func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+    var out []ToolInfo
+    cursor := ""
+    for {
+        params := map[string]interface{}{}
+        if cursor != "" { params["cursor"] = cursor }
+        var page struct {
+            Tools      []ToolInfo `json:"tools"`
+            NextCursor string     `json:"nextCursor"`
+        }
+        if err := c.call(ctx, "tools/list", params, &page); err != nil { return nil, err }
+        out = append(out, page.Tools...)
+        if page.NextCursor == "" || page.NextCursor == cursor { return out, nil }
+        cursor = page.NextCursor
+    }
+}
+
+// CallTool calls a tool. A result with IsError set is returned without an
+// error; protocol failures are errors.
+func (c *Client) CallTool(ctx context.Context, name string, args json.RawMessage) (*CallResult, error) {
+    if len(args) == 0 { args = json.RawMessage(`{}`) }
+    var res CallResult
+    if err := c.call(ctx, "tools/call", map[string]interface{}{"name": name, "arguments": args}, &res); err != nil { return nil, err }
+    return &res, nil
+}
+
+// Close ends the session and stops a stdio server
+func (c *Client) Close() error { return c.t.close() }
+
+// Tool is a server's tool as a tools.Tool, named <server>_<tool> with the
+// characters function names cannot have, e.g. '.' or '/', replaced by '_'
+type Tool struct {
+    client *Client
+    info   ToolInfo
+    name   string
+}
+
+var toolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// maxToolName is the longest function name model APIs accept
+const maxToolName = 64
+
+func NewTool(c *Client, info ToolInfo) *Tool {
+    return &Tool{client: c, info: info, name: c.Name + "_" + toolNameChars.ReplaceAllString(info.Name, "_")}
+}
+
+func (t *Tool) Name() string { return t.name }
+
+func (t *Tool) Description() string {
+    if t.info.Description == "" { return fmt.Sprintf("%s (MCP server %s)", t.info.Name, t.client.Name) }
+    return t.info.Description
+}
+
+func (t *Tool) Parameters() interface{} {
+    if t.info.InputSchema == nil { return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}} }
+    return t.info.InputSchema
+}
+
+// Execute forwards the call; a result the server marks as an error is returned
+// as an error with the server's message
+func (t *Tool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+    res, err := t.client.CallTool(ctx, t.info.Name, args)
+    if err != nil { return "", err }
+    if res.IsError { return "", fmt.Errorf("%s", res.Text()) }
+    return res.Text(), nil
+}
+
+func (t *Tool) RequiredModel() llm.Model  { return "" }
+func (t *Tool) ModelType() llm.ModelType { return llm.ModelTypeText }
+
+// Register lists the server's tools and registers those allowed (all when
+// allow is empty). Tools whose names are already registered, or that are
+// unnamed or longer than a function name may be as <server>_<tool>, are
+// skipped and reported in the error. It returns the registered names.
+func Register(ctx context.Context, reg *tools.ToolRegistry, c *Client, allow []string) ([]string, error) {
+    infos, err := c.ListTools(ctx)
+    if err != nil { return nil, err }
+    allowed := map[string]bool{}
+    for _, a := range allow { allowed[a] = true }
+    var names, taken, invalid []string
+    for _, info := range infos {
+        if len(allowed) > 0 && !allowed[info.Name] { continue }
+        t := NewTool(c, info)
+        if info.Name == "" || len(t.Name()) > maxToolName { invalid = append(invalid, fmt.Sprintf("%q", info.Name)); continue }
+        if _, ok := reg.Get(t.Name()); ok { taken = append(taken, t.Name()); continue }
+        reg.Register(t)
+        names = append(names, t.Name())
+    }
+    var problems []string
+    if len(taken) > 0 { problems = append(problems, "tool names already registered: "+strings.Join(taken, ", ")) }
+    if len(invalid) > 0 { problems = append(problems, fmt.Sprintf("tool names empty or longer than %d characters with the server prefix: %s", maxToolName, strings.Join(invalid, ", "))) }
+    if len(problems) > 0 { return names, fmt.Errorf("mcp server %s: %s", c.Name, strings.Join(problems, "; ")) }
+    return names, nil
+}
+
+// ConnectAll connects to every configured server and registers its tools,
+// concurrently. Servers that fail are reported in the error; the others stay
+// connected and should be closed by the caller.
+func ConnectAll(ctx context.Context, servers []config.MCPServerConfig, reg *tools.ToolRegistry) ([]*Client, error) {
+    var (
+        mu       sync.Mutex
+        wg       sync.WaitGroup
+        clients  []*Client
+        problems []string
+    )
+    seen := map[string]bool{}
+    for _, cfg := range servers {
+        if seen[cfg.Name] { problems = append(problems, fmt.Sprintf("mcp server %s is configured twice", cfg.Name)); continue }
+        seen[cfg.Name] = true
+        wg.Add(1)
+        go func(cfg config.MCPServerConfig) {
+            defer wg.Done()
+            c, err := Connect(ctx, cfg)
+            if err == nil { _, err = Register(ctx, reg, c, cfg.Tools) }
+            mu.Lock()
+            defer mu.Unlock()
+            if c != nil { clients = append(clients, c) }
+            if err != nil { problems = append(problems, err.Error()) }
+        }(cfg)
+    }
+    wg.Wait()
+    if len(problems) > 0 { return clients, fmt.Errorf("%s", strings.Join(problems, "\n")) }
+    return clients, nil
+}
@@ -0,0 +1,129 @@
+package mcp
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "fmt"
+    "os"
+    "sort"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/pradord/llm/internal/config"
+    "github.com/pradord/llm/internal/tools"
+)
+
+// The test binary doubles as a stdio MCP server: run with MCP_TEST_SERVER set,
+// it serves testServer instead of the tests
+func TestMain(m *testing.M) {
+    if os.Getenv("MCP_TEST_SERVER") != "" { testServer(); return }
+    os.Exit(m.Run())
+}
+
+// testServer lists its tools over two pages and has tools that echo, fail
+// with isError, and exit the process
+func testServer() {
+    pages := map[string]struct {
+        tools []ToolInfo
+        next  string
+    }{
+        "":      {[]ToolInfo{{Name: "echo", InputSchema: map[string]interface{}{"type": "object"}}, {Name: "fail"}}, "page2"},
+        "page2": {[]ToolInfo{{Name: "crash"}, {Name: "files.read"}, {Name: strings.Repeat("x", 64)}}, ""},
+    }
+    in := bufio.NewScanner(os.Stdin)
+    out := json.NewEncoder(os.Stdout)
+    for in.Scan() {
+        var msg message
+        if json.Unmarshal(in.Bytes(), &msg) != nil || len(msg.ID) == 0 { continue }
+        var params struct {
+            Cursor    string          `json:"cursor"`
+            Name      string          `json:"name"`
+            Arguments json.RawMessage `json:"arguments"`
+        }
+        json.Unmarshal(msg.Params, &params)
+        var result interface{}
+        switch msg.Method {
+        case "initialize":
+            result = map[string]interface{}{"protocolVersion": ProtocolVersion, "capabilities": map[string]interface{}{}, "serverInfo": map[string]string{"name": "test", "version": "0.1"}}
+        case "tools/list":
+            p := pages[params.Cursor]
+            result = map[string]interface{}{"tools": p.tools, "nextCursor": p.next}
+        case "tools/call":
+            switch params.Name {
+            case "crash":
+                fmt.Fprintln(os.Stderr, "crashing as asked")
+                os.Exit(3)
+            case "fail":
+                result = CallResult{Content: []Content{{Type: "text", Text: "no such record"}}, IsError: true}
+            default:
+                result = CallResult{Content: []Content{{Type: "text", Text: params.Name + " " + string(params.Arguments)}}}
+            }
+        default:
+            out.Encode(&message{JSONRPC: "2.0", ID: msg.ID, Error: &Error{Code: codeMethodNotFound, Message: msg.Method}})
+            continue
+        }
+        b, _ := json.Marshal(result)
+        out.Encode(&message{JSONRPC: "2.0", ID: msg.ID, Result: b})
+    }
+}
+
+func connectTestServer(t *testing.T) *Client {
+    t.Helper()
+    exe, err := os.Executable()
+    if err != nil { t.Fatal(err) }
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+    c, err := Connect(ctx, config.MCPServerConfig{Name: "test", Command: exe, Env: map[string]string{"MCP_TEST_SERVER": "1"}})
+    if err != nil { t.Fatal(err) }
+    t.Cleanup(func() { c.Close() })
+    return c
+}
+
+func TestClientInitializeAndListTools(t *testing.T) {
+    c := connectTestServer(t)
+    if c.ServerInfo.Name != "test" || c.ServerInfo.Version != "0.1" { t.Fatalf("server info %+v", c.ServerInfo) }
+    infos, err := c.ListTools(context.Background())
+    if err != nil { t.Fatal(err) }
+    var names []string
+    for _, info := range infos { names = append(names, info.Name) }
+    if strings.Join(names, ",") != "echo,fail,crash,files.read,"+strings.Repeat("x", 64) { t.Fatalf("tools %v: pages not followed", names) }
+}
+
+func TestRegisterToolNames(t *testing.T) {
+    c := connectTestServer(t)
+    reg := tools.NewToolRegistry()
+    names, err := Register(context.Background(), reg, c, nil)
+    sort.Strings(names)
+    if strings.Join(names, ",") != "test_crash,test_echo,test_fail,test_files_read" { t.Fatalf("registered %v", names) }
+    if err == nil || !strings.Contains(err.Error(), "longer than 64") { t.Fatalf("got %v, want the long name reported", err) }
+
+    // the server is called with the tool's own name
+    out, err := reg.Execute(context.Background(), "test_files_read", json.RawMessage(`{"path":"a"}`))
+    if err != nil { t.Fatal(err) }
+    if out != `files.read {"path":"a"}` { t.Fatalf("got %q", out) }
+
+    if _, err := Register(context.Background(), reg, c, []string{"echo"}); err == nil || !strings.Contains(err.Error(), "already registered: test_echo") {
+        t.Fatalf("got %v, want test_echo reported as taken", err)
+    }
+}
+
+func TestClientToolErrors(t *testing.T) {
+    c := connectTestServer(t)
+    ctx := context.Background()
+
+    res, err := c.CallTool(ctx, "fail", nil)
+    if err != nil { t.Fatal(err) }
+    if !res.IsError || res.Text() != "no such record" { t.Fatalf("result %+v", res) }
+    if _, err := NewTool(c, ToolInfo{Name: "fail"}).Execute(ctx, nil); err == nil || err.Error() != "no such record" {
+        t.Fatalf("got %v, want the server's message as the error", err)
+    }
+
+    _, err = c.CallTool(ctx, "crash", nil)
+    if err == nil || !strings.Contains(err.Error(), "server exited") || !strings.Contains(err.Error(), "crashing as asked") {
+        t.Fatalf("got %v, want the exit and the server's stderr", err)
+    }
+    // later calls fail at once
+    if _, err := c.CallTool(ctx, "echo", nil); err == nil || !strings.Contains(err.Error(), "server exited") { t.Fatalf("after exit: %v", err) }
+}
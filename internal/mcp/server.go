@@ -0,0 +1,462 @@
+package mcp
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "io/fs"
+    "net/url"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+
+    "github.com/pradord/llm/internal/agent"
+    "github.com/pradord/llm/internal/knowledge"
+    "github.com/pradord/llm/internal/llm"
+    "github.com/pradord/llm/internal/project"
+    "github.com/pradord/llm/internal/skills"
+    "github.com/pradord/llm/internal/tools"
+)
+
+// How skills are published by a Server
+const (
+    SkillsAsTools   = "tools"   // skill_<name> tools that run the skill with the executor
+    SkillsAsPrompts = "prompts" // prompts with the skill's instructions, for the client's own model
+    SkillsAsBoth    = "both"
+)
+
+// maxResourceBytes bounds the knowledge documents a Server reads, as ingestion does
+const maxResourceBytes = 5 << 20
+
+// Server publishes tools, skills and project knowledge to MCP clients. Calls
+// go through the same checks as an agent run: only the tools
+// agent.EffectiveTools allows are listed or callable (agent.DefaultTools when
+// no layer lists tools), arguments are validated against the tool's
+// parameters, and skills run as agent.Executor.RunSkill runs them for the CLI.
+type Server struct {
+    Name      string
+    Version   string
+    Tools     *tools.ToolRegistry
+    Skills    *skills.SkillRegistry
+    Executor  *agent.Executor  // runs skills called as tools; nil publishes skills as prompts only
+    Layers    []agent.Layer    // settings below the skill layer, lowest first; default the built-in defaults
+    Call      []agent.Layer    // settings above the skill layer, e.g. the tools serve is limited to
+    Project   *project.Project // limits skills to the project's, prefixes its system prompt and publishes its knowledge folder; may be nil
+    SkillMode string           // SkillsAsTools, SkillsAsPrompts or SkillsAsBoth (default)
+
+    wmu     sync.Mutex // serializes writes
+    mu      sync.Mutex
+    running map[string]context.CancelFunc // in-flight requests by ID
+}
+
+func NewServer(toolReg *tools.ToolRegistry, skillReg *skills.SkillRegistry) *Server {
+    return &Server{Name: ClientName, Version: "1.0", Tools: toolReg, Skills: skillReg,
+        Layers: []agent.Layer{agent.DefaultsLayer()}, SkillMode: SkillsAsBoth}
+}
+
+// Serve reads newline-delimited JSON-RPC messages from in and writes the
+// replies to out until in ends. Requests are handled concurrently, so a long
+// skill run does not hold up other calls; Serve returns once they finish.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+    var wg sync.WaitGroup
+    defer wg.Wait()
+    r := bufio.NewReaderSize(in, 64*1024)
+    for {
+        line, err := r.ReadBytes('\n')
+        if len(bytes.TrimSpace(line)) > 0 {
+            var msg message
+            switch {
+            case json.Unmarshal(line, &msg) != nil:
+                s.write(out, &message{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: codeParseError, Message: "parse error"}})
+            case msg.Method == "":
+                // a response; the server sends no requests
+            case len(msg.ID) == 0:
+                s.notification(&msg)
+            default:
+                wg.Add(1)
+                go func(msg message) {
+                    defer wg.Done()
+                    s.write(out, s.handle(ctx, &msg))
+                }(msg)
+            }
+        }
+        if err == io.EOF { return nil }
+        if err != nil { return err }
+    }
+}
+
+func (s *Server) write(out io.Writer, msg *message) {
+    if msg == nil { return }
+    data, err := json.Marshal(msg)
+    if err != nil { return }
+    s.wmu.Lock()
+    defer s.wmu.Unlock()
+    _, _ = out.Write(append(data, '\n'))
+}
+
+// notification handles notifications/cancelled; others need no action
+func (s *Server) notification(msg *message) {
+    if msg.Method != "notifications/cancelled" { return }
+    var p struct{ RequestID json.RawMessage `json:"requestId"` }
+    if json.Unmarshal(msg.Params, &p) != nil { return }
+    s.mu.Lock()
+    cancel := s.running[string(p.RequestID)]
+    delete(s.running, string(p.RequestID))
+    s.mu.Unlock()
+    if cancel != nil { cancel() }
+}
+
+// handle answers a request, or returns nil when the client cancelled it
+func (s *Server) handle(ctx context.Context, msg *message) *message {
+    reqCtx, cancel := context.WithCancel(ctx)
+    defer cancel()
+    id := string(msg.ID)
+    s.mu.Lock()
+    if s.running == nil { s.running = map[string]context.CancelFunc{} }
+    s.running[id] = cancel
+    s.mu.Unlock()
+    result, err := s.dispatch(reqCtx, msg.Method, msg.Params)
+    s.mu.Lock()
+    _, tracked := s.running[id]
+    delete(s.running, id)
+    s.mu.Unlock()
+    if !tracked && ctx.Err() == nil { return nil }
+
+    reply := &message{JSONRPC: "2.0", ID: msg.ID}
+    if err == nil {
+        if reply.Result, err = json.Marshal(result); err == nil { return reply }
+    }
+    var e *Error
+    if !errors.As(err, &e) { e = &Error{Code: codeInternalError, Message: err.Error()} }
+    reply.Result, reply.Error = nil, e
+    return reply
+}
+
+func (s *Server) dispatch(ctx context.Context, method string, params json.RawMessage) (interface{}, error) {
+    switch method {
+    case "initialize":
+        return s.initialize(params), nil
+    case "ping":
+        return struct{}{}, nil
+    case "tools/list":
+        infos := []ToolInfo{}
+        for _, t := range s.publishedTools() {
+            infos = append(infos, ToolInfo{Name: t.Name(), Description: t.Description(), InputSchema: inputSchema(t)})
+        }
+        return map[string]interface{}{"tools": infos}, nil
+    case "tools/call":
+        return s.callTool(ctx, params)
+    case "prompts/list":
+        prompts := []Prompt{}
+        for _, sk := range s.promptSkills() { prompts = append(prompts, skillPrompt(sk)) }
+        return map[string]interface{}{"prompts": prompts}, nil
+    case "prompts/get":
+        return s.getPrompt(params)
+    case "resources/list":
+        return s.listResources()
+    case "resources/read":
+        return s.readResource(params)
+    }
+    return nil, &Error{Code: codeMethodNotFound, Message: "method not found: " + method}
+}
+
+// supportedVersions are the protocol revisions whose stdio messages this server speaks
+var supportedVersions = map[string]bool{ProtocolVersion: true, "2024-11-05": true}
+
+func (s *Server) initialize(params json.RawMessage) interface{} {
+    var p struct{ ProtocolVersion string `json:"protocolVersion"` }
+    _ = json.Unmarshal(params, &p)
+    version := ProtocolVersion
+    if supportedVersions[p.ProtocolVersion] { version = p.ProtocolVersion }
+    caps := map[string]interface{}{"tools": map[string]interface{}{}}
+    if len(s.promptSkills()) > 0 { caps["prompts"] = map[string]interface{}{} }
+    if s.knowledgeDir() != "" { caps["resources"] = map[string]interface{}{} }
+    return map[string]interface{}{
+        "protocolVersion": version,
+        "capabilities":    caps,
+        "serverInfo":      map[string]string{"name": s.Name, "version": s.Version},
+    }
+}
+
+// setup is what skill runs and the published tools are resolved from
+func (s *Server) setup() *agent.RunSetup {
+    return &agent.RunSetup{Layers: s.Layers, Call: s.Call, Tools: s.Tools, Prompt: func(prompt string) (string, error) {
+        return s.Project.SkillSystemPrompt(prompt, project.PromptData{Vars: map[string]string{}})
+    }}
+}
+
+// publishedTools are the registered tools the settings allow, by name, then a
+// skill_<name> tool per published skill when skills run as tools
+func (s *Server) publishedTools() []tools.Tool {
+    var out []tools.Tool
+    if s.Tools != nil {
+        names := agent.EffectiveTools(s.setup().Settings(nil))
+        sort.Strings(names)
+        for _, name := range names {
+            if t, ok := s.Tools.Get(name); ok { out = append(out, t) }
+        }
+    }
+    if s.Executor != nil && s.SkillMode != SkillsAsPrompts {
+        for _, sk := range s.publishedSkills() { out = append(out, &skillTool{server: s, skill: sk}) }
+    }
+    return out
+}
+
+// publishedSkills are the registered skills, limited to the project's list
+// when it has one, by name
+func (s *Server) publishedSkills() []*skills.Skill {
+    if s.Skills == nil { return nil }
+    var allow map[string]bool
+    if s.Project != nil && len(s.Project.Skills) > 0 {
+        allow = map[string]bool{}
+        for _, name := range s.Project.Skills { allow[name] = true }
+    }
+    var out []*skills.Skill
+    for _, sk := range s.Skills.List() {
+        if allow == nil || allow[sk.Name] { out = append(out, sk) }
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+    return out
+}
+
+func (s *Server) promptSkills() []*skills.Skill {
+    if s.SkillMode == SkillsAsTools && s.Executor != nil { return nil }
+    return s.publishedSkills()
+}
+
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (interface{}, error) {
+    var p struct {
+        Name      string          `json:"name"`
+        Arguments json.RawMessage `json:"arguments"`
+    }
+    if err := json.Unmarshal(params, &p); err != nil { return nil, &Error{Code: codeInvalidParams, Message: err.Error()} }
+    var selected tools.Tool
+    for _, t := range s.publishedTools() {
+        if t.Name() == p.Name { selected = t; break }
+    }
+    if selected == nil { return nil, &Error{Code: codeInvalidParams, Message: "unknown tool: " + p.Name} }
+    args := string(p.Arguments)
+    if args == "null" { args = "" }
+    out, err := agent.ExecuteTool(ctx, selected, args)
+    var argErr *agent.ArgumentError
+    switch {
+    case errors.As(err, &argErr):
+        return toolError(argErr.ToolResult()), nil
+    case err != nil:
+        return toolError("tool error: " + err.Error()), nil
+    }
+    return &CallResult{Content: []Content{{Type: "text", Text: out}}}, nil
+}
+
+// toolError is a failed call reported to the client's model, as the agent
+// loop reports it to its own
+func toolError(text string) *CallResult {
+    return &CallResult{Content: []Content{{Type: "text", Text: text}}, IsError: true}
+}
+
+// inputSchema is a tool's parameters as decoded JSON
+func inputSchema(t tools.Tool) map[string]interface{} {
+    schema := map[string]interface{}{}
+    if b, err := json.Marshal(t.Parameters()); err == nil { _ = json.Unmarshal(b, &schema) }
+    if len(schema) == 0 { schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}} }
+    return schema
+}
+
+// skillTool runs a published skill as an agent
+type skillTool struct {
+    server *Server
+    skill  *skills.Skill
+}
+
+func (t *skillTool) Name() string        { return "skill_" + t.skill.Name }
+func (t *skillTool) Description() string { return t.skill.Description }
+
+func (t *skillTool) Parameters() interface{} {
+    if schema := t.skill.InputSchema(); schema != nil { return schema }
+    return map[string]interface{}{
+        "type": "object",
+        "properties": map[string]interface{}{
+            "task": map[string]interface{}{"type": "string", "description": "The request for the skill, with any context it needs"},
+        },
+        "required": []string{"task"},
+    }
+}
+
+func (t *skillTool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+    var values map[string]interface{}
+    if err := json.Unmarshal(args, &values); err != nil { return "", err }
+    prompt, _ := values["task"].(string)
+    if len(t.skill.Inputs) > 0 {
+        var err error
+        if prompt, err = t.skill.RenderPrompt(values); err != nil { return "", err }
+    }
+    return t.server.runSkill(ctx, t.skill, prompt)
+}
+
+func (t *skillTool) RequiredModel() llm.Model  { return t.skill.DefaultModel }
+func (t *skillTool) ModelType() llm.ModelType { return llm.ModelTypeText }
+
+// runSkill runs skill as the CLI does: with the project's system prompt, the
+// resolved settings, the tools they allow and its delegation tools
+func (s *Server) runSkill(ctx context.Context, skill *skills.Skill, prompt string) (string, error) {
+    res, err := s.Executor.RunSkill(ctx, s.setup(), s.Skills, skill, prompt)
+    if err != nil { return "", fmt.Errorf("skill %s: %w", skill.Name, err) }
+    return res.Output, nil
+}
+
+// projectSkill is a copy of skill with the project's system prompt in front
+func (s *Server) projectSkill(skill *skills.Skill) (*skills.Skill, error) {
+    sk := *skill
+    prompt, err := s.Project.SkillSystemPrompt(sk.SystemPrompt, project.PromptData{Vars: map[string]string{}})
+    if err != nil { return nil, err }
+    sk.SystemPrompt = prompt
+    return &sk, nil
+}
+
+// Prompt is a skill published as an MCP prompt
+type Prompt struct {
+    Name        string           `json:"name"`
+    Description string           `json:"description,omitempty"`
+    Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+type PromptArgument struct {
+    Name        string `json:"name"`
+    Description string `json:"description,omitempty"`
+    Required    bool   `json:"required,omitempty"`
+}
+
+// skillPrompt takes the arguments from the skill's inputs; skills without
+// inputs take a task
+func skillPrompt(sk *skills.Skill) Prompt {
+    p := Prompt{Name: sk.Name, Description: sk.Description}
+    for _, in := range sk.Inputs {
+        p.Arguments = append(p.Arguments, PromptArgument{Name: in.Name, Description: in.Description, Required: in.Required && in.Default == nil})
+    }
+    if len(sk.Inputs) == 0 { p.Arguments = []PromptArgument{{Name: "task", Description: "The request for the skill"}} }
+    return p
+}
+
+// getPrompt renders a skill's instructions and prompt as one user message
+func (s *Server) getPrompt(params json.RawMessage) (interface{}, error) {
+    var p struct {
+        Name      string            `json:"name"`
+        Arguments map[string]string `json:"arguments"`
+    }
+    if err := json.Unmarshal(params, &p); err != nil { return nil, &Error{Code: codeInvalidParams, Message: err.Error()} }
+    var skill *skills.Skill
+    for _, sk := range s.promptSkills() {
+        if sk.Name == p.Name { skill = sk; break }
+    }
+    if skill == nil { return nil, &Error{Code: codeInvalidParams, Message: "unknown prompt: " + p.Name} }
+    sk, err := s.projectSkill(skill)
+    if err != nil { return nil, err }
+    prompt := p.Arguments["task"]
+    if len(sk.Inputs) > 0 {
+        values, err := sk.ParseInputs(p.Arguments, nil)
+        if err == nil { prompt, err = sk.RenderPrompt(values) }
+        if err != nil { return nil, &Error{Code: codeInvalidParams, Message: err.Error()} }
+    }
+    text := sk.SystemPrompt
+    if prompt != "" { text += "\n\n" + prompt }
+    return map[string]interface{}{
+        "description": sk.Description,
+        "messages":    []map[string]interface{}{{"role": "user", "content": Content{Type: "text", Text: text}}},
+    }, nil
+}
+
+// Resource is a document of the project's knowledge folder
+type Resource struct {
+    URI      string `json:"uri"`
+    Name     string `json:"name"`
+    MimeType string `json:"mimeType,omitempty"`
+    Size     int64  `json:"size,omitempty"`
+}
+
+func (s *Server) knowledgeDir() string {
+    if s.Project == nil { return "" }
+    return s.Project.Knowledge
+}
+
+// knowledgeURI is knowledge://<project>/<path>
+func (s *Server) knowledgeURI(rel string) string {
+    return (&url.URL{Scheme: "knowledge", Host: s.Project.ID, Path: "/" + rel}).String()
+}
+
+// listResources lists the documents ingestion would index
+func (s *Server) listResources() (interface{}, error) {
+    out := []Resource{}
+    dir := s.knowledgeDir()
+    if dir == "" { return map[string]interface{}{"resources": out}, nil }
+    err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+        if err != nil { return err }
+        if d.IsDir() {
+            if path != dir && strings.HasPrefix(d.Name(), ".") { return filepath.SkipDir }
+            return nil
+        }
+        rel, _ := filepath.Rel(dir, path)
+        rel = filepath.ToSlash(rel)
+        info, ok := s.document(rel)
+        if !ok || info.Size() > maxResourceBytes { return nil }
+        out = append(out, Resource{URI: s.knowledgeURI(rel), Name: rel, MimeType: mimeType(rel), Size: info.Size()})
+        return nil
+    })
+    if err != nil { return nil, err }
+    return map[string]interface{}{"resources": out}, nil
+}
+
+// document checks that rel names a supported, non-hidden file of the
+// knowledge folder, also after following symlinks
+func (s *Server) document(rel string) (fs.FileInfo, bool) {
+    dir := s.knowledgeDir()
+    if dir == "" || !filepath.IsLocal(filepath.FromSlash(rel)) || !knowledge.Supported(rel) { return nil, false }
+    for _, part := range strings.Split(rel, "/") {
+        if strings.HasPrefix(part, ".") { return nil, false }
+    }
+    path, err := filepath.EvalSymlinks(filepath.Join(dir, filepath.FromSlash(rel)))
+    if err != nil { return nil, false }
+    root, err := filepath.EvalSymlinks(dir)
+    if err != nil { return nil, false }
+    if r, err := filepath.Rel(root, path); err != nil || !filepath.IsLocal(r) { return nil, false }
+    info, err := os.Stat(path)
+    if err != nil || info.IsDir() { return nil, false }
+    return info, true
+}
+
+// readResource returns a listed document's text, extracted as for ingestion
+func (s *Server) readResource(params json.RawMessage) (interface{}, error) {
+    var p struct{ URI string `json:"uri"` }
+    if err := json.Unmarshal(params, &p); err != nil { return nil, &Error{Code: codeInvalidParams, Message: err.Error()} }
+    notFound := &Error{Code: codeResourceNotFound, Message: "resource not found: " + p.URI}
+    u, err := url.Parse(p.URI)
+    if err != nil || s.knowledgeDir() == "" || u.Scheme != "knowledge" || u.Host != s.Project.ID { return nil, notFound }
+    rel := strings.TrimPrefix(u.Path, "/")
+    info, ok := s.document(rel)
+    if !ok { return nil, notFound }
+    path := filepath.Join(s.knowledgeDir(), filepath.FromSlash(rel))
+    if info.Size() > maxResourceBytes { return nil, fmt.Errorf("%s is larger than %d bytes", rel, maxResourceBytes) }
+    data, err := os.ReadFile(path)
+    if err != nil { return nil, err }
+    text, err := knowledge.Extract(path, data)
+    if err != nil { return nil, fmt.Errorf("%s: %w", rel, err) }
+    return map[string]interface{}{"contents": []ResourceContent{{URI: p.URI, MimeType: mimeType(rel), Text: text}}}, nil
+}
+
+// mimeType of a document's text; PDFs are served as their extracted text
+func mimeType(rel string) string {
+    switch strings.ToLower(filepath.Ext(rel)) {
+    case ".md", ".markdown", ".mdx":
+        return "text/markdown"
+    case ".html", ".htm":
+        return "text/html"
+    case ".json":
+        return "application/json"
+    }
+    return "text/plain"
+}
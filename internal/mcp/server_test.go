@@ -0,0 +1,119 @@
+package mcp
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "io"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+
+    "github.com/pradord/llm/internal/agent"
+    "github.com/pradord/llm/internal/project"
+    "github.com/pradord/llm/internal/skills"
+    "github.com/pradord/llm/internal/tools"
+)
+
+// serveTest runs srv.Serve over pipes and returns a function that sends one
+// request and waits for its reply
+func serveTest(t *testing.T, srv *Server) func(method string, params interface{}) *message {
+    t.Helper()
+    inR, inW := io.Pipe()
+    outR, outW := io.Pipe()
+    done := make(chan error, 1)
+    go func() { done <- srv.Serve(context.Background(), inR, outW); outW.Close() }()
+    t.Cleanup(func() {
+        inW.Close()
+        if err := <-done; err != nil { t.Errorf("Serve: %v", err) }
+    })
+    replies := bufio.NewScanner(outR)
+    id := 0
+    return func(method string, params interface{}) *message {
+        t.Helper()
+        id++
+        p, _ := json.Marshal(params)
+        req, _ := json.Marshal(&message{JSONRPC: "2.0", ID: json.RawMessage(strconv.Itoa(id)), Method: method, Params: p})
+        if _, err := inW.Write(append(req, '\n')); err != nil { t.Fatal(err) }
+        if !replies.Scan() { t.Fatalf("%s: no reply: %v", method, replies.Err()) }
+        var reply message
+        if err := json.Unmarshal(replies.Bytes(), &reply); err != nil { t.Fatalf("%s: reply %s: %v", method, replies.Bytes(), err) }
+        return &reply
+    }
+}
+
+func newTestServer() *Server {
+    reg := tools.NewToolRegistry()
+    reg.Register(tools.NewCalculator())
+    reg.Register(tools.NewSimpleTool("other", "not allowed", func(ctx context.Context, args map[string]interface{}) (string, error) { return "ran", nil }))
+    srv := NewServer(reg, skills.NewSkillRegistry(reg))
+    srv.Call = []agent.Layer{{Name: agent.LayerCall, Settings: agent.Settings{Tools: []string{"calculator"}}}}
+    return srv
+}
+
+func TestServerInitialize(t *testing.T) {
+    call := serveTest(t, newTestServer())
+    reply := call("initialize", map[string]interface{}{"protocolVersion": "2024-11-05"})
+    if reply.Error != nil { t.Fatal(reply.Error) }
+    var res struct {
+        ProtocolVersion string                     `json:"protocolVersion"`
+        Capabilities    map[string]json.RawMessage `json:"capabilities"`
+        ServerInfo      struct{ Name string }      `json:"serverInfo"`
+    }
+    if err := json.Unmarshal(reply.Result, &res); err != nil { t.Fatal(err) }
+    if res.ProtocolVersion != "2024-11-05" { t.Fatalf("protocol %q, want the client's supported version", res.ProtocolVersion) }
+    if res.ServerInfo.Name != ClientName { t.Fatalf("server info %+v", res.ServerInfo) }
+    if _, ok := res.Capabilities["tools"]; !ok { t.Fatalf("capabilities %v lack tools", res.Capabilities) }
+    if _, ok := res.Capabilities["resources"]; ok { t.Fatal("resources advertised without a knowledge folder") }
+}
+
+func TestServerToolsFollowSettings(t *testing.T) {
+    call := serveTest(t, newTestServer())
+    var list struct{ Tools []ToolInfo }
+    if err := json.Unmarshal(call("tools/list", nil).Result, &list); err != nil { t.Fatal(err) }
+    if len(list.Tools) != 1 || list.Tools[0].Name != "calculator" { t.Fatalf("listed %+v, want only the allowed calculator", list.Tools) }
+
+    // a registered tool the settings leave out cannot be called either
+    if reply := call("tools/call", map[string]interface{}{"name": "other"}); reply.Error == nil || reply.Error.Code != codeInvalidParams {
+        t.Fatalf("calling an unpublished tool: %+v", reply)
+    }
+}
+
+func TestServerToolCallInvalidArguments(t *testing.T) {
+    call := serveTest(t, newTestServer())
+    reply := call("tools/call", map[string]interface{}{"name": "calculator", "arguments": map[string]interface{}{"expression": 5}})
+    if reply.Error != nil { t.Fatalf("got a protocol error %v, want a tool result", reply.Error) }
+    var res CallResult
+    if err := json.Unmarshal(reply.Result, &res); err != nil { t.Fatal(err) }
+    if !res.IsError || !strings.Contains(res.Text(), "expression") { t.Fatalf("result %+v, want isError naming the argument", res) }
+
+    reply = call("tools/call", map[string]interface{}{"name": "calculator", "arguments": map[string]interface{}{"expression": "2 + 3"}})
+    res = CallResult{}
+    if err := json.Unmarshal(reply.Result, &res); err != nil { t.Fatal(err) }
+    if res.IsError || !strings.Contains(res.Text(), "2 + 3") { t.Fatalf("result %+v", res) }
+}
+
+func TestServerResourcesStayInKnowledgeFolder(t *testing.T) {
+    root := t.TempDir()
+    kb := filepath.Join(root, "kb")
+    if err := os.Mkdir(kb, 0o755); err != nil { t.Fatal(err) }
+    if err := os.WriteFile(filepath.Join(kb, "notes.md"), []byte("# Notes\nshared"), 0o644); err != nil { t.Fatal(err) }
+    if err := os.WriteFile(filepath.Join(root, "secret.md"), []byte("private"), 0o644); err != nil { t.Fatal(err) }
+    if err := os.Symlink(filepath.Join(root, "secret.md"), filepath.Join(kb, "link.md")); err != nil { t.Skip("symlinks unavailable:", err) }
+    srv := newTestServer()
+    srv.Project = &project.Project{ID: "p", Knowledge: kb}
+    call := serveTest(t, srv)
+
+    var res struct{ Contents []ResourceContent }
+    reply := call("resources/read", map[string]string{"uri": "knowledge://p/notes.md"})
+    if reply.Error != nil { t.Fatal(reply.Error) }
+    if err := json.Unmarshal(reply.Result, &res); err != nil { t.Fatal(err) }
+    if len(res.Contents) != 1 || !strings.Contains(res.Contents[0].Text, "shared") { t.Fatalf("contents %+v", res.Contents) }
+
+    for _, uri := range []string{"knowledge://p/../secret.md", "knowledge://p/%2E%2E/secret.md", "knowledge://p/link.md"} {
+        reply := call("resources/read", map[string]string{"uri": uri})
+        if reply.Error == nil || reply.Error.Code != codeResourceNotFound { t.Errorf("%s: got %s, want not found", uri, reply.Result) }
+    }
+}
@@ -0,0 +1,299 @@
+package mcp
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "os/exec"
+    "strings"
+    "sync"
+)
+
+// message is a JSON-RPC 2.0 request, notification or response
+type message struct {
+    JSONRPC string          `json:"jsonrpc"`
+    ID      json.RawMessage `json:"id,omitempty"`
+    Method  string          `json:"method,omitempty"`
+    Params  json.RawMessage `json:"params,omitempty"`
+    Result  json.RawMessage `json:"result,omitempty"`
+    Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error returned by an MCP server
+type Error struct {
+    Code    int             `json:"code"`
+    Message string          `json:"message"`
+    Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message) }
+
+// JSON-RPC error codes, and the MCP code for unknown resources
+const (
+    codeParseError       = -32700
+    codeMethodNotFound   = -32601
+    codeInvalidParams    = -32602
+    codeInternalError    = -32603
+    codeResourceNotFound = -32002
+)
+
+// transport carries messages to a server. send waits for the response to a
+// request (a message with an ID) and returns nil for notifications.
+type transport interface {
+    send(ctx context.Context, msg *message) (*message, error)
+    close() error
+}
+
+// stdioTransport runs the server as a subprocess and exchanges
+// newline-delimited messages over its stdin and stdout
+type stdioTransport struct {
+    cmd    *exec.Cmd
+    stdin  io.WriteCloser
+    stderr *tail
+    wmu    sync.Mutex // serializes writes
+
+    mu      sync.Mutex
+    pending map[string]chan *message
+    done    chan struct{}
+    err     error // why the server stopped
+}
+
+func newStdioTransport(command string, args []string, env map[string]string) (*stdioTransport, error) {
+    cmd := exec.Command(command, args...)
+    cmd.Env = os.Environ()
+    for k, v := range env { cmd.Env = append(cmd.Env, k+"="+os.ExpandEnv(v)) }
+    stdin, err := cmd.StdinPipe()
+    if err != nil { return nil, err }
+    stdout, err := cmd.StdoutPipe()
+    if err != nil { return nil, err }
+    t := &stdioTransport{cmd: cmd, stdin: stdin, stderr: &tail{max: 4096}, pending: map[string]chan *message{}, done: make(chan struct{})}
+    cmd.Stderr = t.stderr
+    if err := cmd.Start(); err != nil { return nil, fmt.Errorf("start %s: %w", command, err) }
+    go t.read(stdout)
+    return t, nil
+}
+
+func (t *stdioTransport) read(stdout io.Reader) {
+    r := bufio.NewReader(stdout)
+    var err error
+    for {
+        var line []byte
+        if line, err = r.ReadBytes('\n'); len(bytes.TrimSpace(line)) > 0 {
+            var msg message
+            if json.Unmarshal(line, &msg) == nil { t.handle(&msg) }
+        }
+        if err != nil { break }
+    }
+    werr := t.cmd.Wait()
+    t.mu.Lock()
+    t.err = fmt.Errorf("server exited")
+    if werr != nil { t.err = fmt.Errorf("server exited: %v", werr) }
+    if s := strings.TrimSpace(t.stderr.String()); s != "" { t.err = fmt.Errorf("%v: %s", t.err, s) }
+    t.mu.Unlock()
+    close(t.done)
+}
+
+// handle delivers a response, or answers a request from the server
+func (t *stdioTransport) handle(msg *message) {
+    switch {
+    case msg.Method != "" && len(msg.ID) > 0:
+        _ = t.write(serverRequestReply(msg))
+    case msg.Method != "":
+        // notifications, e.g. logging or list changes, are ignored
+    default:
+        t.mu.Lock()
+        ch := t.pending[string(msg.ID)]
+        delete(t.pending, string(msg.ID))
+        t.mu.Unlock()
+        if ch != nil { ch <- msg }
+    }
+}
+
+func (t *stdioTransport) write(msg *message) error {
+    data, err := json.Marshal(msg)
+    if err != nil { return err }
+    t.wmu.Lock()
+    defer t.wmu.Unlock()
+    _, err = t.stdin.Write(append(data, '\n'))
+    return err
+}
+
+func (t *stdioTransport) send(ctx context.Context, msg *message) (*message, error) {
+    var ch chan *message
+    if len(msg.ID) > 0 {
+        ch = make(chan *message, 1)
+        t.mu.Lock()
+        if t.err != nil { t.mu.Unlock(); return nil, t.err }
+        t.pending[string(msg.ID)] = ch
+        t.mu.Unlock()
+        defer func() { t.mu.Lock(); delete(t.pending, string(msg.ID)); t.mu.Unlock() }()
+    }
+    if err := t.write(msg); err != nil { return nil, t.stopped(err) }
+    if ch == nil { return nil, nil }
+    select {
+    case resp := <-ch:
+        return resp, nil
+    case <-t.done:
+        return nil, t.stopped(nil)
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    }
+}
+
+// stopped prefers the reason the server stopped over a write error
+func (t *stdioTransport) stopped(err error) error {
+    select {
+    case <-t.done:
+        t.mu.Lock()
+        defer t.mu.Unlock()
+        return t.err
+    default:
+        return err
+    }
+}
+
+func (t *stdioTransport) close() error {
+    t.stdin.Close()
+    select {
+    case <-t.done:
+    default:
+        if t.cmd.Process != nil { _ = t.cmd.Process.Kill() }
+        <-t.done
+    }
+    return nil
+}
+
+// serverRequestReply answers a request the server sends the client. Only ping
+// is supported; this client offers no sampling or roots.
+func serverRequestReply(msg *message) *message {
+    if msg.Method == "ping" { return &message{JSONRPC: "2.0", ID: msg.ID, Result: json.RawMessage(`{}`)} }
+    return &message{JSONRPC: "2.0", ID: msg.ID, Error: &Error{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}}
+}
+
+// tail keeps the end of a subprocess's stderr for error messages
+type tail struct {
+    mu  sync.Mutex
+    buf []byte
+    max int
+}
+
+func (t *tail) Write(p []byte) (int, error) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    t.buf = append(t.buf, p...)
+    if len(t.buf) > t.max { t.buf = t.buf[len(t.buf)-t.max:] }
+    return len(p), nil
+}
+
+func (t *tail) String() string {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    return string(t.buf)
+}
+
+// httpTransport speaks the streamable HTTP transport: each message is POSTed
+// to the endpoint, which replies with JSON or an event stream
+type httpTransport struct {
+    url        string
+    headers    map[string]string
+    httpClient *http.Client
+
+    mu      sync.Mutex
+    session string // Mcp-Session-Id assigned at initialization
+    version string // negotiated protocol version
+}
+
+func newHTTPTransport(url string, headers map[string]string) *httpTransport {
+    h := make(map[string]string, len(headers))
+    for k, v := range headers { h[k] = os.ExpandEnv(v) }
+    return &httpTransport{url: url, headers: h, httpClient: &http.Client{}}
+}
+
+func (t *httpTransport) send(ctx context.Context, msg *message) (*message, error) {
+    data, err := json.Marshal(msg)
+    if err != nil { return nil, err }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(data))
+    if err != nil { return nil, err }
+    t.header(req)
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Accept", "application/json, text/event-stream")
+    resp, err := t.httpClient.Do(req)
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+        t.mu.Lock()
+        t.session = id
+        t.mu.Unlock()
+    }
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        if resp.StatusCode == http.StatusNotFound && t.sessionID() != "" { return nil, fmt.Errorf("session expired (HTTP 404)") }
+        return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+    }
+    if len(msg.ID) == 0 { return nil, nil }
+
+    if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") { return t.readStream(ctx, resp.Body, msg.ID) }
+    var out message
+    if err := json.NewDecoder(io.LimitReader(resp.Body, maxMessageBytes)).Decode(&out); err != nil { return nil, fmt.Errorf("invalid response: %w", err) }
+    return &out, nil
+}
+
+const maxMessageBytes = 16 << 20
+
+// readStream reads server-sent events until the response to id arrives.
+// Requests from the server are answered with a separate POST.
+func (t *httpTransport) readStream(ctx context.Context, body io.Reader, id json.RawMessage) (*message, error) {
+    r := bufio.NewReaderSize(body, 64*1024)
+    var data strings.Builder
+    for {
+        line, err := r.ReadString('\n')
+        line = strings.TrimRight(line, "\r\n")
+        switch {
+        case strings.HasPrefix(line, "data:"):
+            data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+        case line == "" && data.Len() > 0:
+            var msg message
+            if json.Unmarshal([]byte(data.String()), &msg) == nil {
+                switch {
+                case msg.Method != "" && len(msg.ID) > 0:
+                    _, _ = t.send(ctx, serverRequestReply(&msg))
+                case msg.Method == "" && bytes.Equal(msg.ID, id):
+                    return &msg, nil
+                }
+            }
+            data.Reset()
+        }
+        if err != nil { return nil, fmt.Errorf("event stream ended without a response") }
+    }
+}
+
+func (t *httpTransport) header(req *http.Request) {
+    for k, v := range t.headers { req.Header.Set(k, v) }
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    if t.session != "" { req.Header.Set("Mcp-Session-Id", t.session) }
+    if t.version != "" { req.Header.Set("MCP-Protocol-Version", t.version) }
+}
+
+func (t *httpTransport) sessionID() string {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    return t.session
+}
+
+// close ends the session, when the server assigned one
+func (t *httpTransport) close() error {
+    if t.sessionID() == "" { return nil }
+    req, err := http.NewRequest(http.MethodDelete, t.url, nil)
+    if err != nil { return err }
+    t.header(req)
+    resp, err := t.httpClient.Do(req)
+    if err != nil { return err }
+    resp.Body.Close()
+    return nil
+}
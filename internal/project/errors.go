@@ -0,0 +1,38 @@
+package project
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+)
+
+var (
+    // ErrConflict matches (via errors.Is) any error caused by a concurrent modification
+    ErrConflict = errors.New("concurrent modification")
+    // ErrExists is returned by Create when a project with the ID already exists
+    ErrExists = errors.New("project already exists")
+)
+
+// ConflictError is returned by Update when the project changed since the caller
+// read it. Reload with Get, reapply the change and retry.
+type ConflictError struct {
+    ProjectID string
+    Expected  int64 // version the caller read
+    Actual    int64 // version currently stored
+}
+
+func (e *ConflictError) Error() string {
+    return fmt.Sprintf("project %s was modified concurrently (have version %d, stored version %d)", e.ProjectID, e.Expected, e.Actual)
+}
+
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+// ValidationError lists everything wrong with a project definition
+type ValidationError struct {
+    ProjectID string
+    Problems  []string
+}
+
+func (e *ValidationError) Error() string {
+    return fmt.Sprintf("project %s is invalid: %s", e.ProjectID, strings.Join(e.Problems, "; "))
+}
@@ -4,50 +4,159 @@ import (
     "crypto/rand"
     "encoding/hex"
     "encoding/json"
+    "fmt"
     "os"
     "path/filepath"
     "strings"
     "sync"
     "time"
+
+    "github.com/pradord/llm/internal/envelope"
+    "github.com/pradord/llm/internal/filelock"
 )
 
+// Store persists projects. Update fails with a *ConflictError when the project
+// changed after it was read; Archive hides a project without deleting it.
+type Store interface {
+    Create(p *Project) (*Project, error)
+    Get(id string) (*Project, error)
+    List() ([]*Project, error)
+    Update(p *Project) (*Project, error)
+    Delete(id string) error
+    Archive(id string) error
+}
+
+// FileStore persists projects as JSON files under a directory. Operations hold an
+// advisory lock on <dir>/.lock so several processes can share the directory.
+// Files are owner-only and, with FileStoreConfig.Key, envelope-encrypted.
+// Archived projects move to <dir>/archive.
 type FileStore struct {
     dir string
+    cfg FileStoreConfig
     mu  sync.Mutex
 }
 
+// FileStoreConfig tunes cross-process locking
+type FileStoreConfig struct {
+    LockTimeout time.Duration // how long to wait for the directory lock (default 5s)
+    Key         *envelope.Key // encrypt project files at rest; plaintext files are still read
+    Validator   *Validator    // when set, Create and Update reject invalid projects
+}
+
 func NewFileStore(dir string) (*FileStore, error) {
+    return NewFileStoreWithConfig(dir, FileStoreConfig{})
+}
+
+func NewFileStoreWithConfig(dir string, cfg FileStoreConfig) (*FileStore, error) {
     if dir == "" { dir = ".llm_projects" }
-    if err := os.MkdirAll(dir, 0o755); err != nil { return nil, err }
-    return &FileStore{dir: dir}, nil
+    if cfg.LockTimeout <= 0 { cfg.LockTimeout = 5 * time.Second }
+    if err := os.MkdirAll(dir, 0o700); err != nil { return nil, err }
+    return &FileStore{dir: dir, cfg: cfg}, nil
 }
 
-func (fs *FileStore) path(id string) string { return filepath.Join(fs.dir, id+".json") }
+func (fs *FileStore) path(id string) string        { return filepath.Join(fs.dir, id+".json") }
+func (fs *FileStore) archivePath(id string) string { return filepath.Join(fs.dir, "archive", id+".json") }
+
+// lock serializes access within the process and, through the lock file, with
+// other processes using the same directory
+func (fs *FileStore) lock(exclusive bool) (func(), error) {
+    fs.mu.Lock()
+    l, err := filelock.Acquire(filepath.Join(fs.dir, ".lock"), exclusive, fs.cfg.LockTimeout)
+    if err != nil {
+        fs.mu.Unlock()
+        return nil, fmt.Errorf("project store: %w", err)
+    }
+    return func() { _ = l.Release(); fs.mu.Unlock() }, nil
+}
 
 func (fs *FileStore) Create(p *Project) (*Project, error) {
-    fs.mu.Lock(); defer fs.mu.Unlock()
+    unlock, err := fs.lock(true)
+    if err != nil { return nil, err }
+    defer unlock()
     if p.ID == "" { p.ID = strings.TrimSpace(newID()) }
+    if err := fs.validate(p); err != nil { return nil, err }
+    if _, err := os.Stat(fs.path(p.ID)); err == nil { return nil, fmt.Errorf("project %s: %w", p.ID, ErrExists) }
     now := time.Now()
     p.CreatedAt = now; p.UpdatedAt = now
+    p.Version = 1
     if err := fs.save(p); err != nil { return nil, err }
     return p, nil
 }
 
 func (fs *FileStore) Get(id string) (*Project, error) {
-    data, err := os.ReadFile(fs.path(id))
+    unlock, err := fs.lock(false)
+    if err != nil { return nil, err }
+    defer unlock()
+    return fs.load(id)
+}
+
+// Update replaces a stored project. p.Version must be the version that was read;
+// on success it is incremented and CreatedAt is kept from the stored project.
+func (fs *FileStore) Update(p *Project) (*Project, error) {
+    if err := fs.validate(p); err != nil { return nil, err }
+    unlock, err := fs.lock(true)
+    if err != nil { return nil, err }
+    defer unlock()
+    cur, err := fs.load(p.ID)
+    if err != nil { return nil, err }
+    if cur.Version != p.Version {
+        return nil, &ConflictError{ProjectID: p.ID, Expected: p.Version, Actual: cur.Version}
+    }
+    p.Version++
+    p.CreatedAt, p.UpdatedAt = cur.CreatedAt, time.Now()
+    if err := fs.save(p); err != nil { return nil, err }
+    return p, nil
+}
+
+func (fs *FileStore) Delete(id string) error {
+    unlock, err := fs.lock(true)
+    if err != nil { return err }
+    defer unlock()
+    if err := checkID(id); err != nil { return err }
+    return os.Remove(fs.path(id))
+}
+
+// Archive moves a project out of List and Get; its file is kept under archive/
+func (fs *FileStore) Archive(id string) error {
+    unlock, err := fs.lock(true)
+    if err != nil { return err }
+    defer unlock()
+    if err := checkID(id); err != nil { return err }
+    if _, err := os.Stat(fs.path(id)); err != nil { return err }
+    if err := os.MkdirAll(filepath.Dir(fs.archivePath(id)), 0o700); err != nil { return err }
+    return os.Rename(fs.path(id), fs.archivePath(id))
+}
+
+func (fs *FileStore) load(id string) (*Project, error) {
+    if err := checkID(id); err != nil { return nil, err }
+    data, err := fs.read(fs.path(id))
     if err != nil { return nil, err }
     var p Project
     if err := json.Unmarshal(data, &p); err != nil { return nil, err }
     return &p, nil
 }
 
+func (fs *FileStore) validate(p *Project) error {
+    if fs.cfg.Validator == nil { return checkID(p.ID) }
+    return fs.cfg.Validator.Validate(p)
+}
+
+// checkID rejects IDs that cannot safely be used as file names
+func checkID(id string) error {
+    if !idPattern.MatchString(id) { return fmt.Errorf("invalid project id %q", id) }
+    return nil
+}
+
 func (fs *FileStore) List() ([]*Project, error) {
+    unlock, err := fs.lock(false)
+    if err != nil { return nil, err }
+    defer unlock()
     entries, err := os.ReadDir(fs.dir)
     if err != nil { return nil, err }
     var out []*Project
     for _, e := range entries {
         if e.IsDir() || filepath.Ext(e.Name()) != ".json" { continue }
-        data, err := os.ReadFile(filepath.Join(fs.dir, e.Name()))
+        data, err := fs.read(filepath.Join(fs.dir, e.Name()))
         if err != nil { continue }
         var p Project
         if json.Unmarshal(data, &p) == nil { out = append(out, &p) }
@@ -58,11 +167,19 @@ func (fs *FileStore) List() ([]*Project, error) {
 func (fs *FileStore) save(p *Project) error {
     data, err := json.MarshalIndent(p, "", "  ")
     if err != nil { return err }
+    if data, err = fs.cfg.Key.Seal(data); err != nil { return err }
     tmp := fs.path(p.ID)+".tmp"
-    if err := os.WriteFile(tmp, data, 0o644); err != nil { return err }
+    if err := os.WriteFile(tmp, data, 0o600); err != nil { return err }
     return os.Rename(tmp, fs.path(p.ID))
 }
 
+// read returns a project file's JSON, decrypting it when needed
+func (fs *FileStore) read(path string) ([]byte, error) {
+    data, err := os.ReadFile(path)
+    if err != nil { return nil, err }
+    return fs.cfg.Key.Open(data)
+}
+
 // simple id generator
 func newID() string {
     b := make([]byte, 8)
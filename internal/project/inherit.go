@@ -0,0 +1,90 @@
+package project
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Resolve flattens `extends` chains so every project carries the effective
+// settings of its ancestors. A child overrides its parent field by field; lists
+// and the system prompt replace the parent's unless named in Merge. Merged lists
+// are the parent's followed by the child's, and a "-name" entry removes name.
+// Variables always merge, the child winning. defs is not modified.
+func Resolve(defs map[string]*Project) (map[string]*Project, error) {
+    out := make(map[string]*Project, len(defs))
+    visiting := map[string]bool{}
+    var resolve func(id string, chain []string) (*Project, error)
+    resolve = func(id string, chain []string) (*Project, error) {
+        if p, ok := out[id]; ok { return p, nil }
+        def := defs[id]
+        if visiting[id] { return nil, fmt.Errorf("project inheritance cycle: %s -> %s", strings.Join(chain, " -> "), id) }
+        p := *def
+        if def.Extends != "" {
+            if defs[def.Extends] == nil { return nil, fmt.Errorf("project %s extends unknown project %q", id, def.Extends) }
+            visiting[id] = true
+            parent, err := resolve(def.Extends, append(chain, id))
+            delete(visiting, id)
+            if err != nil { return nil, err }
+            p = inherit(parent, def)
+        }
+        out[id] = &p
+        return &p, nil
+    }
+    for id := range defs {
+        if _, err := resolve(id, nil); err != nil { return nil, err }
+    }
+    return out, nil
+}
+
+// inherit returns child with its unset fields taken from parent
+func inherit(parent, child *Project) Project {
+    p := *child
+    merge := map[string]bool{}
+    for _, f := range child.Merge { merge[strings.ToLower(strings.TrimSpace(f))] = true }
+
+    p.Tools = inheritList(parent.Tools, child.Tools, merge["tools"])
+    p.Skills = inheritList(parent.Skills, child.Skills, merge["skills"])
+    switch {
+    case child.SystemPrompt == "":
+        p.SystemPrompt = parent.SystemPrompt
+    case merge["system_prompt"] && parent.SystemPrompt != "":
+        p.SystemPrompt = strings.TrimRight(parent.SystemPrompt, "\n") + "\n\n" + child.SystemPrompt
+    }
+    if p.Name == "" { p.Name = parent.Name }
+    if p.DefaultModel == "" { p.DefaultModel = parent.DefaultModel }
+    if p.Temperature == nil && parent.Temperature != nil { t := *parent.Temperature; p.Temperature = &t }
+    if p.MaxSteps == 0 { p.MaxSteps = parent.MaxSteps }
+    if p.MaxChars == 0 { p.MaxChars = parent.MaxChars }
+    if p.OwnerUserID == "" { p.OwnerUserID = parent.OwnerUserID }
+    if p.Knowledge == "" { p.Knowledge = parent.Knowledge }
+    if p.Retention == nil && parent.Retention != nil { r := *parent.Retention; p.Retention = &r }
+    if len(parent.Variables) > 0 {
+        p.Variables = make(map[string]string, len(parent.Variables)+len(child.Variables))
+        for k, v := range parent.Variables { p.Variables[k] = v }
+        for k, v := range child.Variables { p.Variables[k] = v }
+    }
+    return p
+}
+
+func inheritList(parent, child []string, merge bool) []string {
+    if !merge {
+        if child == nil { return append([]string(nil), parent...) }
+        return child
+    }
+    var out []string
+    seen := map[string]bool{}
+    for _, name := range append(append([]string(nil), parent...), child...) {
+        if strings.HasPrefix(name, "-") {
+            removed := strings.TrimPrefix(name, "-")
+            for i := 0; i < len(out); i++ {
+                if out[i] == removed { out = append(out[:i], out[i+1:]...); i-- }
+            }
+            delete(seen, removed)
+            continue
+        }
+        if seen[name] { continue }
+        seen[name] = true
+        out = append(out, name)
+    }
+    return out
+}
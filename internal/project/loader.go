@@ -1,6 +1,7 @@
 package project
 
 import (
+    "fmt"
     "io/fs"
     "os"
     "path/filepath"
@@ -9,7 +10,9 @@ import (
     "gopkg.in/yaml.v3"
 )
 
-// LoadDir loads all project YAMLs from a directory
+// LoadDir loads all project YAMLs (.yaml or .yml) from a directory and resolves
+// their extends chains (see Resolve). Each project records its file in Source;
+// an ID defined in two files is an error.
 func LoadDir(dir string) (map[string]*Project, error) {
     out := map[string]*Project{}
     if dir == "" { return out, nil }
@@ -17,18 +20,22 @@ func LoadDir(dir string) (map[string]*Project, error) {
     err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
         if err != nil { return err }
         if d.IsDir() { return nil }
-        if !strings.HasSuffix(strings.ToLower(d.Name()), ".yaml") { return nil }
+        ext := strings.ToLower(filepath.Ext(d.Name()))
+        if ext != ".yaml" && ext != ".yml" { return nil }
         data, err := os.ReadFile(path)
         if err != nil { return err }
         var p Project
-        if err := yaml.Unmarshal(data, &p); err != nil { return err }
+        if err := yaml.Unmarshal(data, &p); err != nil { return fmt.Errorf("%s: %w", path, err) }
         if p.ID == "" { p.ID = strings.TrimSuffix(d.Name(), filepath.Ext(d.Name())) }
+        if prev, dup := out[p.ID]; dup { return fmt.Errorf("project %q is defined in both %s and %s", p.ID, prev.Source, path) }
+        p.Source = path
         now := time.Now()
         if p.CreatedAt.IsZero() { p.CreatedAt = now }
         p.UpdatedAt = now
         out[p.ID] = &p
         return nil
     })
-    return out, err
+    if err != nil { return nil, err }
+    return Resolve(out)
 }
 
@@ -0,0 +1,74 @@
+package project
+
+import (
+    "fmt"
+    "strings"
+    "text/template"
+    "time"
+)
+
+// PromptData is what a system prompt template can refer to besides the project:
+//
+//   {{.User}} {{.UserID}} {{.Date}} {{.Now.Format "Jan 2"}}
+//   {{.Project.ID}} {{.Project.Name}} {{.Vars.team}} {{default "n/a" (index .Vars "region")}}
+//
+// Vars starts from Project.Variables and is overridden by PromptData.Vars. A
+// missing {{.Vars.x}} is an error; use index for optional variables.
+type PromptData struct {
+    UserID   string
+    UserName string            // shown as .User; defaults to UserID
+    Now      time.Time         // defaults to time.Now()
+    Vars     map[string]string // runtime variables, e.g. from the command line
+}
+
+type promptContext struct {
+    User, UserID, Date string
+    Now                time.Time
+    Project            struct{ ID, Name string }
+    Vars               map[string]string
+}
+
+var promptFuncs = template.FuncMap{
+    "default": func(def, v string) string {
+        if v == "" { return def }
+        return v
+    },
+    "upper": strings.ToUpper,
+    "lower": strings.ToLower,
+}
+
+// parsePrompt parses a system prompt template. Unknown variables are errors so a
+// typo does not silently render as empty.
+func parsePrompt(p *Project) (*template.Template, error) {
+    t, err := template.New(p.ID).Funcs(promptFuncs).Option("missingkey=error").Parse(p.SystemPrompt)
+    if err != nil { return nil, fmt.Errorf("project %s: system_prompt: %w", p.ID, err) }
+    return t, nil
+}
+
+// RenderSystemPrompt executes the project's system prompt template
+func (p *Project) RenderSystemPrompt(data PromptData) (string, error) {
+    if !strings.Contains(p.SystemPrompt, "{{") { return p.SystemPrompt, nil }
+    t, err := parsePrompt(p)
+    if err != nil { return "", err }
+    ctx := promptContext{UserID: data.UserID, User: data.UserName, Now: data.Now, Vars: map[string]string{}}
+    if ctx.User == "" { ctx.User = data.UserID }
+    if ctx.Now.IsZero() { ctx.Now = time.Now() }
+    ctx.Date = ctx.Now.Format("2006-01-02")
+    ctx.Project.ID, ctx.Project.Name = p.ID, p.Name
+    for k, v := range p.Variables { ctx.Vars[k] = v }
+    for k, v := range data.Vars { ctx.Vars[k] = v }
+    var b strings.Builder
+    if err := t.Execute(&b, ctx); err != nil { return "", fmt.Errorf("project %s: system_prompt: %w", p.ID, err) }
+    return b.String(), nil
+}
+
+// SkillSystemPrompt is the system prompt for running a skill under the project:
+// the rendered project prompt followed by the skill's own prompt. A nil project
+// returns skillPrompt unchanged.
+func (p *Project) SkillSystemPrompt(skillPrompt string, data PromptData) (string, error) {
+    if p == nil { return skillPrompt, nil }
+    prompt, err := p.RenderSystemPrompt(data)
+    if err != nil { return "", err }
+    if strings.TrimSpace(prompt) == "" { return skillPrompt, nil }
+    return strings.TrimRight(prompt, "\n") + "\n\n" + skillPrompt, nil
+}
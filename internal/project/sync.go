@@ -0,0 +1,79 @@
+package project
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "reflect"
+    "sort"
+)
+
+// SyncReport describes what Sync changed in the store
+type SyncReport struct {
+    Created   []string          `json:"created,omitempty"`
+    Updated   []string          `json:"updated,omitempty"`
+    Unchanged []string          `json:"unchanged,omitempty"`
+    Archived  []string          `json:"archived,omitempty"`
+    Skipped   map[string]string `json:"skipped,omitempty"` // project ID -> reason
+}
+
+// Sync makes the store match YAML definitions (see LoadDir). Definitions are
+// created or updated in the store; projects created at runtime (empty Source)
+// are never overwritten. With prune, stored projects that came from a definition
+// which no longer exists are archived. Invalid definitions are skipped and
+// reported rather than failing the whole sync.
+func Sync(store Store, defs map[string]*Project, v *Validator, prune bool) (*SyncReport, error) {
+    stored, err := store.List()
+    if err != nil { return nil, err }
+    byID := map[string]*Project{}
+    for _, p := range stored { byID[p.ID] = p }
+
+    rep := &SyncReport{Skipped: map[string]string{}}
+    ids := make([]string, 0, len(defs))
+    for id := range defs { ids = append(ids, id) }
+    sort.Strings(ids)
+    for _, id := range ids {
+        def := *defs[id]
+        if err := v.Validate(&def); err != nil {
+            rep.Skipped[id] = err.Error()
+            continue
+        }
+        cur, ok := byID[id]
+        switch {
+        case !ok:
+            if _, err := store.Create(&def); err != nil { return rep, fmt.Errorf("create %s: %w", id, err) }
+            rep.Created = append(rep.Created, id)
+        case cur.Source == "":
+            rep.Skipped[id] = "a project with this ID was created at runtime"
+        case sameDefinition(cur, &def):
+            rep.Unchanged = append(rep.Unchanged, id)
+        default:
+            def.Version = cur.Version
+            if _, err := store.Update(&def); err != nil { return rep, fmt.Errorf("update %s: %w", id, err) }
+            rep.Updated = append(rep.Updated, id)
+        }
+    }
+    if prune {
+        for _, p := range stored {
+            if p.Source == "" || defs[p.ID] != nil { continue }
+            if err := store.Archive(p.ID); err != nil && !errors.Is(err, os.ErrNotExist) {
+                return rep, fmt.Errorf("archive %s: %w", p.ID, err)
+            }
+            rep.Archived = append(rep.Archived, p.ID)
+        }
+        sort.Strings(rep.Archived)
+    }
+    return rep, nil
+}
+
+// sameDefinition compares the fields a definition file controls
+func sameDefinition(a, b *Project) bool {
+    x, y := *a, *b
+    for _, p := range []*Project{&x, &y} {
+        p.CreatedAt, p.UpdatedAt, p.Version = p.CreatedAt.UTC().Truncate(0), p.UpdatedAt.UTC().Truncate(0), 0
+        if len(p.Tools) == 0 { p.Tools = nil }
+        if len(p.Skills) == 0 { p.Skills = nil }
+    }
+    x.CreatedAt, x.UpdatedAt = y.CreatedAt, y.UpdatedAt
+    return reflect.DeepEqual(x, y)
+}
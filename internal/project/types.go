@@ -1,16 +1,52 @@
 package project
 
-import "time"
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
 
 // Project defines a scoped workspace with its own system prompt, tools, and skills
 type Project struct {
     ID           string    `yaml:"id" json:"id"`
     Name         string    `yaml:"name" json:"name"`
+    Extends      string    `yaml:"extends,omitempty" json:"extends,omitempty"`     // parent project ID; see Resolve
+    Merge        []string  `yaml:"merge,omitempty" json:"merge,omitempty"`         // fields combined with the parent instead of replacing it: tools, skills, system_prompt
+    Variables    map[string]string `yaml:"variables,omitempty" json:"variables,omitempty"` // available to the system prompt template as .Vars
     SystemPrompt string    `yaml:"system_prompt" json:"system_prompt"`
     Tools        []string  `yaml:"tools" json:"tools"`
     Skills       []string  `yaml:"skills" json:"skills"`
     DefaultModel string    `yaml:"default_model" json:"default_model"`
+    Temperature  *float64  `yaml:"temperature,omitempty" json:"temperature,omitempty"` // agent run settings; see agent.Resolve
+    MaxSteps     int       `yaml:"max_steps,omitempty" json:"max_steps,omitempty"`
+    MaxChars     int       `yaml:"max_chars,omitempty" json:"max_chars,omitempty"`
     OwnerUserID  string    `yaml:"owner_user_id" json:"owner_user_id"`
+    Knowledge    string    `yaml:"knowledge,omitempty" json:"knowledge,omitempty"` // folder of documents searchable with the search_knowledge tool
+    Retention    *Retention `yaml:"retention,omitempty" json:"retention,omitempty"`
     CreatedAt    time.Time `yaml:"created_at" json:"created_at"`
     UpdatedAt    time.Time `yaml:"updated_at" json:"updated_at"`
+    Version      int64     `yaml:"-" json:"version,omitempty"` // incremented on every store write; Update rejects stale versions
+    Source       string    `yaml:"-" json:"source,omitempty"`  // definition file the project was loaded or synced from; empty for runtime projects
+}
+
+// Retention limits how long the project's threads are kept
+type Retention struct {
+    MaxAge     string `yaml:"max_age" json:"max_age"`         // e.g. "90d" or "720h"; empty keeps threads regardless of age
+    MaxThreads int    `yaml:"max_threads" json:"max_threads"` // keep only the most recently updated threads; 0 = no limit
+    Action     string `yaml:"action" json:"action"`           // archive (default) or delete
+}
+
+// MaxAgeDuration parses MaxAge, which accepts Go durations plus a "d" (days) suffix
+func (r *Retention) MaxAgeDuration() (time.Duration, error) {
+    s := strings.TrimSpace(r.MaxAge)
+    if s == "" { return 0, nil }
+    if days, ok := strings.CutSuffix(s, "d"); ok {
+        n, err := strconv.Atoi(days)
+        if err != nil || n < 0 { return 0, fmt.Errorf("invalid max_age %q", r.MaxAge) }
+        return time.Duration(n) * 24 * time.Hour, nil
+    }
+    d, err := time.ParseDuration(s)
+    if err != nil { return 0, fmt.Errorf("invalid max_age %q", r.MaxAge) }
+    return d, nil
 }
@@ -0,0 +1,55 @@
+package project
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Validator checks project definitions against what exists at runtime. The
+// lookups are plain functions so the project package does not depend on the
+// tool and skill registries; a nil lookup skips that check.
+type Validator struct {
+    HasTool    func(name string) bool
+    HasSkill   func(name string) bool
+    ValidModel func(model string) bool
+}
+
+// Validate returns a *ValidationError listing every problem found, or nil
+func (v *Validator) Validate(p *Project) error {
+    var problems []string
+    add := func(format string, args ...interface{}) { problems = append(problems, fmt.Sprintf(format, args...)) }
+    if p.ID == "" {
+        add("id is required")
+    } else if !idPattern.MatchString(p.ID) {
+        add("id %q may only contain letters, digits, '.', '_' and '-'", p.ID)
+    }
+    for _, t := range p.Tools {
+        t = strings.TrimPrefix(t, "-")
+        if v != nil && v.HasTool != nil && !v.HasTool(t) { add("unknown tool %q", t) }
+    }
+    for _, s := range p.Skills {
+        s = strings.TrimPrefix(s, "-")
+        if v != nil && v.HasSkill != nil && !v.HasSkill(s) { add("unknown skill %q", s) }
+    }
+    if p.DefaultModel != "" && v != nil && v.ValidModel != nil && !v.ValidModel(p.DefaultModel) {
+        add("unknown default_model %q", p.DefaultModel)
+    }
+    if t := p.Temperature; t != nil && (*t < 0 || *t > 2) { add("temperature must be between 0 and 2, got %g", *t) }
+    if p.MaxSteps < 0 || p.MaxChars < 0 { add("max_steps and max_chars must not be negative") }
+    for _, f := range p.Merge {
+        if f != "tools" && f != "skills" && f != "system_prompt" { add("merge: unknown field %q (want tools, skills or system_prompt)", f) }
+    }
+    if strings.Contains(p.SystemPrompt, "{{") {
+        if _, err := parsePrompt(p); err != nil { add("%v", err) }
+    }
+    if r := p.Retention; r != nil {
+        if _, err := r.MaxAgeDuration(); err != nil { add("retention: %v", err) }
+        if r.MaxThreads < 0 { add("retention: max_threads must not be negative") }
+        if r.Action != "" && r.Action != "archive" && r.Action != "delete" { add("retention: action must be archive or delete, got %q", r.Action) }
+    }
+    if len(problems) == 0 { return nil }
+    return &ValidationError{ProjectID: p.ID, Problems: problems}
+}
@@ -0,0 +1,312 @@
+// Package router picks the skill for a request. Skills are scored by the
+// similarity of the request to their description and examples, using
+// embeddings when an Embedder is set and word overlap otherwise. When the best
+// match is weak or ambiguous, an optional LLM classifier decides. A request
+// made of sequential steps ("research X, then write a post") can route to a
+// chain of skills.
+package router
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+
+    "github.com/pradord/llm/internal/agent"
+    "github.com/pradord/llm/internal/embeddings"
+    "github.com/pradord/llm/internal/llm"
+    "github.com/pradord/llm/internal/skills"
+)
+
+// Auto is the skill name that asks for routing, e.g. --skill auto
+const Auto = "auto"
+
+// Routing methods, as reported in Route.Method
+const (
+    MethodEmbedding  = "embedding"
+    MethodKeyword    = "keyword"
+    MethodClassifier = "classifier"
+)
+
+// Embedder turns text into a vector; *embeddings.Client implements it
+type Embedder interface {
+    Embed(ctx context.Context, input string) ([]float64, error)
+}
+
+// Candidate is a skill with its similarity to the request, from 0 to 1
+type Candidate struct {
+    Skill string  `json:"skill"`
+    Score float64 `json:"score"`
+}
+
+// Step is one skill of a chain with the part of the request it handles
+type Step struct {
+    Skill  string `json:"skill"`
+    Prompt string `json:"prompt"`
+}
+
+// Route is the routing decision for a request
+type Route struct {
+    Skill      string      `json:"skill"`      // first skill to run
+    Chain      []Step      `json:"chain"`      // all skills to run in order; one step when no chain was found
+    Confidence float64     `json:"confidence"` // 0 to 1
+    Method     string      `json:"method"`
+    Candidates []Candidate `json:"candidates"` // all allowed skills, best first
+}
+
+func (r *Route) String() string {
+    names := make([]string, len(r.Chain))
+    for i, s := range r.Chain { names[i] = s.Skill }
+    return fmt.Sprintf("%s (confidence %.2f via %s)", strings.Join(names, " -> "), r.Confidence, r.Method)
+}
+
+// Router routes requests to the skills of a registry
+type Router struct {
+    Skills          *skills.SkillRegistry
+    Embedder        Embedder         // nil scores by word overlap
+    Classifier      agent.ChatClient // asked when the best score is below Threshold or within Margin of the next
+    ClassifierModel llm.Model
+    Threshold       float64 // minimum confident score (default 0.75 with embeddings, 0.2 with word overlap)
+    Margin          float64 // minimum lead of the best skill over the next (default 0.03)
+
+    mu      sync.Mutex
+    vectors map[string][][]float64 // skill text hash -> vectors of description and examples
+}
+
+func New(reg *skills.SkillRegistry, e Embedder) *Router {
+    return &Router{Skills: reg, Embedder: e}
+}
+
+// Route picks the skill, or chain of skills, for prompt among allow (a
+// project's Skills allowlist); an empty allow permits every registered skill.
+// When embedding fails, the router falls back to word overlap.
+func (r *Router) Route(ctx context.Context, prompt string, allow []string) (*Route, error) {
+    cands, err := r.candidates(allow)
+    if err != nil { return nil, err }
+
+    method := MethodKeyword
+    var scored []Candidate
+    if r.Embedder != nil {
+        if scored, err = r.scoreEmbedding(ctx, prompt, cands); err == nil { method = MethodEmbedding }
+    }
+    if method == MethodKeyword { scored = scoreKeywords(prompt, cands) }
+    route := &Route{Skill: scored[0].Skill, Confidence: scored[0].Score, Method: method, Candidates: scored}
+    route.Chain = []Step{{Skill: route.Skill, Prompt: prompt}}
+
+    // sequential requests route each part
+    if parts := splitSteps(prompt); len(parts) > 1 {
+        if chain, conf := r.routeParts(ctx, parts, cands, method); chain != nil {
+            route.Chain, route.Skill, route.Confidence = chain, chain[0].Skill, conf
+        }
+    }
+
+    if r.Classifier != nil && !r.confident(route, method) {
+        if c, err := r.classify(ctx, prompt, cands); err == nil {
+            c.Candidates = scored
+            return c, nil
+        }
+    }
+    return route, nil
+}
+
+func (r *Router) confident(route *Route, method string) bool {
+    threshold, margin := r.Threshold, r.Margin
+    if threshold == 0 {
+        threshold = 0.75
+        if method == MethodKeyword { threshold = 0.2 }
+    }
+    if margin == 0 { margin = 0.03 }
+    if route.Confidence < threshold { return false }
+    if len(route.Chain) == 1 && len(route.Candidates) > 1 && route.Candidates[0].Score-route.Candidates[1].Score < margin { return false }
+    return true
+}
+
+// candidates returns the allowed registered skills, sorted by name
+func (r *Router) candidates(allow []string) ([]*skills.Skill, error) {
+    var out []*skills.Skill
+    if len(allow) == 0 {
+        out = r.Skills.List()
+    } else {
+        for _, name := range allow {
+            if s, ok := r.Skills.Get(name); ok { out = append(out, s) }
+        }
+    }
+    if len(out) == 0 { return nil, fmt.Errorf("router: no skills to route to") }
+    sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+    return out, nil
+}
+
+// routeParts routes each part of a sequential request. It returns nil unless
+// every part matches confidently and at least two different skills are used;
+// the chain's confidence is that of its weakest step.
+func (r *Router) routeParts(ctx context.Context, parts []string, cands []*skills.Skill, method string) ([]Step, float64) {
+    var chain []Step
+    conf := 1.0
+    distinct := map[string]bool{}
+    for _, p := range parts {
+        var scored []Candidate
+        var err error
+        if method == MethodEmbedding {
+            if scored, err = r.scoreEmbedding(ctx, p, cands); err != nil { return nil, 0 }
+        } else {
+            scored = scoreKeywords(p, cands)
+        }
+        best := scored[0]
+        if !r.confident(&Route{Confidence: best.Score, Chain: []Step{{}}, Candidates: scored}, method) { return nil, 0 }
+        if best.Score < conf { conf = best.Score }
+        if n := len(chain); n > 0 && chain[n-1].Skill == best.Skill {
+            chain[n-1].Prompt += "\n" + p
+            continue
+        }
+        chain = append(chain, Step{Skill: best.Skill, Prompt: p})
+        distinct[best.Skill] = true
+    }
+    if len(distinct) < 2 { return nil, 0 }
+    return chain, conf
+}
+
+var stepSep = regexp.MustCompile(`(?i)(?:[,;.]\s*|\s+)(?:and then|then|after that|afterwards|finally)\s+`)
+
+// splitSteps splits a request at sequencing words such as "then"
+func splitSteps(prompt string) []string {
+    var out []string
+    for _, p := range stepSep.Split(prompt, -1) {
+        if p = strings.TrimSpace(p); p != "" { out = append(out, p) }
+    }
+    return out
+}
+
+// skillTexts is what a skill is matched on: its description and examples
+func skillTexts(s *skills.Skill) []string {
+    texts := []string{s.Name + ": " + s.Description}
+    texts = append(texts, s.Examples...)
+    for _, ex := range s.FewShot { texts = append(texts, ex.Input) }
+    return texts
+}
+
+func (r *Router) scoreEmbedding(ctx context.Context, prompt string, cands []*skills.Skill) ([]Candidate, error) {
+    pv, err := r.Embedder.Embed(ctx, prompt)
+    if err != nil { return nil, err }
+    out := make([]Candidate, 0, len(cands))
+    for _, s := range cands {
+        vecs, err := r.skillVectors(ctx, s)
+        if err != nil { return nil, err }
+        best := 0.0
+        for _, v := range vecs {
+            if c := embeddings.Cosine(pv, v); c > best { best = c }
+        }
+        out = append(out, Candidate{Skill: s.Name, Score: best})
+    }
+    sortCandidates(out)
+    return out, nil
+}
+
+// skillVectors embeds a skill's texts once; a changed skill is embedded again
+func (r *Router) skillVectors(ctx context.Context, s *skills.Skill) ([][]float64, error) {
+    texts := skillTexts(s)
+    sum := sha256.Sum256([]byte(strings.Join(texts, "\x00")))
+    key := hex.EncodeToString(sum[:])
+    r.mu.Lock()
+    vecs, ok := r.vectors[key]
+    r.mu.Unlock()
+    if ok { return vecs, nil }
+    for _, t := range texts {
+        v, err := r.Embedder.Embed(ctx, t)
+        if err != nil { return nil, err }
+        vecs = append(vecs, v)
+    }
+    r.mu.Lock()
+    if r.vectors == nil { r.vectors = map[string][][]float64{} }
+    r.vectors[key] = vecs
+    r.mu.Unlock()
+    return vecs, nil
+}
+
+var wordPattern = regexp.MustCompile(`[a-z0-9]+`)
+
+var stopWords = map[string]bool{
+    "a": true, "an": true, "and": true, "the": true, "of": true, "to": true, "in": true, "on": true, "for": true,
+    "with": true, "is": true, "are": true, "be": true, "this": true, "that": true, "it": true, "me": true,
+    "my": true, "i": true, "you": true, "your": true, "please": true, "can": true, "what": true, "how": true,
+}
+
+func words(s string) map[string]bool {
+    out := map[string]bool{}
+    for _, w := range wordPattern.FindAllString(strings.ToLower(s), -1) {
+        if stopWords[w] || len(w) < 2 { continue }
+        out[strings.TrimSuffix(w, "s")] = true
+    }
+    return out
+}
+
+// scoreKeywords scores skills by the share of the request's words found in
+// their description, name and examples
+func scoreKeywords(prompt string, cands []*skills.Skill) []Candidate {
+    pw := words(prompt)
+    out := make([]Candidate, 0, len(cands))
+    for _, s := range cands {
+        sw := words(strings.ReplaceAll(strings.Join(skillTexts(s), " "), "_", " "))
+        hit := 0
+        for w := range pw {
+            if sw[w] { hit++ }
+        }
+        score := 0.0
+        if len(pw) > 0 { score = float64(hit) / float64(len(pw)) }
+        out = append(out, Candidate{Skill: s.Name, Score: score})
+    }
+    sortCandidates(out)
+    return out
+}
+
+func sortCandidates(c []Candidate) {
+    sort.SliceStable(c, func(i, j int) bool { return c[i].Score > c[j].Score })
+}
+
+const classifierPrompt = `Choose the skill, or the sequence of skills, that should handle the request.
+Skills:
+%s
+Reply with only JSON: {"skills": ["<name>", ...], "confidence": <0 to 1>}. List several skills only when the request asks for several steps, in order.
+
+Request:
+%s`
+
+// classify asks the classifier model to choose
+func (r *Router) classify(ctx context.Context, prompt string, cands []*skills.Skill) (*Route, error) {
+    var list strings.Builder
+    known := map[string]bool{}
+    for _, s := range cands {
+        fmt.Fprintf(&list, "- %s: %s\n", s.Name, s.Description)
+        known[s.Name] = true
+    }
+    msgs := []map[string]interface{}{{"role": "user", "content": fmt.Sprintf(classifierPrompt, list.String(), prompt)}}
+    var opts []llm.Option
+    if r.ClassifierModel != "" { opts = append(opts, llm.WithModel(r.ClassifierModel)) }
+    opts = append(opts, llm.WithTemperature(0))
+    content, _, err := r.Classifier.ChatWithTools(ctx, msgs, nil, opts...)
+    if err != nil { return nil, err }
+    var reply struct {
+        Skills     []string `json:"skills"`
+        Confidence float64  `json:"confidence"`
+    }
+    text := content
+    if i, j := strings.Index(text, "{"), strings.LastIndex(text, "}"); i >= 0 && j > i { text = text[i : j+1] }
+    if err := json.Unmarshal([]byte(text), &reply); err != nil { return nil, fmt.Errorf("router: unreadable classifier reply %q", content) }
+    if len(reply.Skills) == 0 { return nil, fmt.Errorf("router: classifier chose no skill") }
+    route := &Route{Skill: reply.Skills[0], Confidence: reply.Confidence, Method: MethodClassifier}
+    for _, name := range reply.Skills {
+        if !known[name] { return nil, fmt.Errorf("router: classifier chose unknown skill %q", name) }
+        route.Chain = append(route.Chain, Step{Skill: name, Prompt: prompt})
+    }
+    return route, nil
+}
+
+// StepPrompt is the prompt for a chain step, given the previous step's answer
+func StepPrompt(step Step, previous string) string {
+    if previous == "" { return step.Prompt }
+    return step.Prompt + "\n\nResult of the previous step:\n" + previous
+}
@@ -0,0 +1,344 @@
+// Package skillpack installs skill packs: portable bundles of skills with the
+// tool metadata, resources and eval suites they need. A pack is a directory,
+// or a zip of one, with a pack.yaml manifest:
+//
+//	pack.yaml    name, version, description, requires_tools, env
+//	skills/      skill YAML, as in defs/skills
+//	tools/       http tool definitions, as in defs/tools
+//	resources/   files the skills reference (relative to the skill file, e.g. ../resources/guide.md)
+//	evals/       eval suites for the skills
+//
+// Packs are not trusted: their resources must stay inside the pack, their
+// tools can only be http tools of their own, and the environment variables
+// those tools read are declared under env and must be allowed at install.
+// Installed packs live in their own directory under a packs directory and are
+// registered at startup with Load.
+package skillpack
+
+import (
+    "archive/zip"
+    "errors"
+    "fmt"
+    "io"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+
+    "github.com/pradord/llm/internal/skills"
+    "github.com/pradord/llm/internal/tools"
+    "gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the name of a pack's manifest
+const ManifestFile = "pack.yaml"
+
+// Manifest describes a pack
+type Manifest struct {
+    Name          string   `yaml:"name" json:"name"`
+    Version       string   `yaml:"version" json:"version"`
+    Description   string   `yaml:"description" json:"description,omitempty"`
+    Author        string   `yaml:"author" json:"author,omitempty"`
+    RequiresTools []string `yaml:"requires_tools" json:"requires_tools,omitempty"` // tool handlers the host must register
+    Env           []string `yaml:"env" json:"env,omitempty"`                       // environment variables the pack's http tools read
+}
+
+// Pack is a pack read from a directory
+type Pack struct {
+    Manifest
+    Dir    string
+    Skills []*skills.Skill
+    Tools  []tools.ToolMetadata
+}
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// Read reads the pack in dir
+func Read(dir string) (*Pack, error) {
+    data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+    if err != nil { return nil, fmt.Errorf("skill pack %s: %w", dir, err) }
+    var m Manifest
+    if err := yaml.Unmarshal(data, &m); err != nil { return nil, fmt.Errorf("skill pack %s: %s: %w", dir, ManifestFile, err) }
+    if !namePattern.MatchString(m.Name) { return nil, fmt.Errorf("skill pack %s: invalid name %q (lowercase letters, digits, '.', '_' and '-')", dir, m.Name) }
+    if m.Version == "" { return nil, fmt.Errorf("skill pack %s: version is required", m.Name) }
+    p := &Pack{Manifest: m, Dir: dir}
+    if p.Skills, err = skills.ParseSkillsDir(filepath.Join(dir, "skills")); err != nil { return nil, fmt.Errorf("skill pack %s: %w", m.Name, err) }
+    if len(p.Skills) == 0 { return nil, fmt.Errorf("skill pack %s: no skills", m.Name) }
+    if p.Tools, err = tools.LoadToolMetadataDir(filepath.Join(dir, "tools")); err != nil { return nil, fmt.Errorf("skill pack %s: %w", m.Name, err) }
+    if err := p.validate(); err != nil { return nil, fmt.Errorf("skill pack %s: %w", m.Name, err) }
+    return p, nil
+}
+
+// validate keeps the pack to itself: tools must be http tools, reading only
+// the environment variables the manifest declares, and resources must be
+// http(s) URLs or files inside the pack
+func (p *Pack) validate() error {
+    declared := map[string]bool{}
+    for _, v := range p.Env { declared[v] = true }
+    for _, m := range p.Tools {
+        if m.HTTP == nil { return fmt.Errorf("tool %s: packs can only define http tools, not metadata for tools they do not provide", m.Name) }
+        for _, v := range m.HTTP.EnvVars() {
+            if !declared[v] { return fmt.Errorf("tool %s reads ${%s}, which is not listed under env in %s", m.Name, v, ManifestFile) }
+        }
+    }
+    root, err := resolvePath(p.Dir)
+    if err != nil { return err }
+    for _, s := range p.Skills {
+        for _, r := range s.Resources {
+            if strings.Contains(r, "://") {
+                if !strings.HasPrefix(r, "http://") && !strings.HasPrefix(r, "https://") { return fmt.Errorf("skill %s: resource %s: only http(s) URLs are allowed", s.Name, r) }
+                continue
+            }
+            path, err := resolvePath(r)
+            if err != nil { return fmt.Errorf("skill %s: resource %s: %w", s.Name, r, err) }
+            if rel, err := filepath.Rel(root, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+                return fmt.Errorf("skill %s: resource %s is outside the pack", s.Name, r)
+            }
+        }
+    }
+    return nil
+}
+
+// resolvePath makes path absolute and, when it exists, resolves its symlinks
+func resolvePath(path string) (string, error) {
+    if resolved, err := filepath.EvalSymlinks(path); err == nil { path = resolved }
+    return filepath.Abs(path)
+}
+
+// EvalsDir is where the pack's eval suites are, if it has any
+func (p *Pack) EvalsDir() string { return filepath.Join(p.Dir, "evals") }
+
+// SkillNames lists the pack's skills
+func (p *Pack) SkillNames() []string {
+    out := make([]string, len(p.Skills))
+    for i, s := range p.Skills { out[i] = s.Name }
+    return out
+}
+
+// Check reports what keeps the pack from working with the host's tools and
+// skills: required or used tools that are not registered (the pack's own http
+// tools aside), pack tools whose names are already registered, and skills
+// whose names are already taken by skills outside the pack
+func (p *Pack) Check(toolReg *tools.ToolRegistry, skillReg *skills.SkillRegistry) error {
+    return p.check(toolReg, skillReg, nil)
+}
+
+// check is Check, ignoring conflicts with the skills and tools of old, the
+// installed version of the pack
+func (p *Pack) check(toolReg *tools.ToolRegistry, skillReg *skills.SkillRegistry, old *Pack) error {
+    skip := map[string]bool{}
+    if old != nil {
+        for _, n := range old.SkillNames() { skip[n] = true }
+        for _, n := range old.ToolNames() { skip["tool "+n] = true }
+    }
+    var problems []string
+    for _, m := range p.Tools {
+        if _, taken := toolReg.Get(m.Name); taken && !skip["tool "+m.Name] { problems = append(problems, fmt.Sprintf("tool %s is already registered", m.Name)) }
+    }
+    missing := map[string]bool{}
+    provided := map[string]bool{} // http tools come with the pack
+    for _, m := range p.Tools {
+        if m.HTTP != nil { provided[m.Name] = true }
+    }
+    need := append([]string(nil), p.RequiresTools...)
+    for _, s := range p.Skills { need = append(need, s.Tools...) }
+    for _, m := range p.Tools { need = append(need, m.Name) }
+    for _, t := range need {
+        if _, ok := toolReg.Get(t); !ok && !provided[t] { missing[t] = true }
+    }
+    if len(missing) > 0 { problems = append(problems, "missing tools: "+strings.Join(sortedKeys(missing), ", ")) }
+    if skillReg != nil {
+        for _, s := range p.Skills {
+            if _, taken := skillReg.Get(s.Name); taken && !skip[s.Name] { problems = append(problems, fmt.Sprintf("skill %s is already registered", s.Name)) }
+        }
+    }
+    if len(problems) > 0 { return fmt.Errorf("skill pack %s %s is not compatible: %s", p.Name, p.Version, strings.Join(problems, "; ")) }
+    return nil
+}
+
+// ToolNames lists the pack's http tools
+func (p *Pack) ToolNames() []string {
+    out := make([]string, len(p.Tools))
+    for i, m := range p.Tools { out[i] = m.Name }
+    return out
+}
+
+// Register adds the pack's skills and http tools
+func (p *Pack) Register(toolReg *tools.ToolRegistry, skillReg *skills.SkillRegistry) error {
+    if err := tools.ApplyToolMetadata(toolReg, p.Tools); err != nil { return err }
+    return skillReg.Replace(nil, p.Skills)
+}
+
+// Options controls Install
+type Options struct {
+    Tools    *tools.ToolRegistry   // must have the tools the pack requires; its http tools are registered into it
+    Skills   *skills.SkillRegistry // checked for name conflicts; the pack's skills are registered into it (optional)
+    Force    bool                  // replace an installed pack of the same name, e.g. to upgrade
+    AllowEnv []string              // environment variables the pack may read; every one in its env must be listed
+}
+
+// Install copies the pack at src (a directory or .zip) into packsDir/<name>,
+// after checking it against the registries, and registers its skills
+func Install(src, packsDir string, opts Options) (*Pack, error) {
+    if err := os.MkdirAll(packsDir, 0o755); err != nil { return nil, err }
+    staging, err := os.MkdirTemp(packsDir, ".install-")
+    if err != nil { return nil, err }
+    defer os.RemoveAll(staging)
+    if strings.EqualFold(filepath.Ext(src), ".zip") {
+        err = unzip(src, staging)
+    } else {
+        err = copyTree(src, staging)
+    }
+    if err != nil { return nil, fmt.Errorf("skill pack %s: %w", src, err) }
+    // a zip may hold the pack in a single top-level directory
+    root := staging
+    if _, err := os.Stat(filepath.Join(root, ManifestFile)); err != nil {
+        if entries, _ := os.ReadDir(root); len(entries) == 1 && entries[0].IsDir() { root = filepath.Join(root, entries[0].Name()) }
+    }
+    p, err := Read(root)
+    if err != nil { return nil, err }
+    if denied := p.deniedEnv(opts.AllowEnv); len(denied) > 0 {
+        return nil, fmt.Errorf("skill pack %s reads environment variables %s; allow them with --allow-env", p.Name, strings.Join(denied, ", "))
+    }
+
+    dest := filepath.Join(packsDir, p.Name)
+    var old *Pack
+    if _, err := os.Stat(dest); err == nil {
+        old, _ = Read(dest)
+        if !opts.Force {
+            if old != nil { return nil, fmt.Errorf("skill pack %s %s is already installed", old.Name, old.Version) }
+            return nil, fmt.Errorf("skill pack %s is already installed", p.Name)
+        }
+    }
+    if err := p.check(opts.Tools, opts.Skills, old); err != nil { return nil, err }
+    if err := os.RemoveAll(dest); err != nil { return nil, err }
+    if err := os.Rename(root, dest); err != nil { return nil, err }
+    if p, err = Read(dest); err != nil { return nil, err } // resources now resolve inside dest
+    var replacing []string
+    if old != nil {
+        replacing = old.SkillNames()
+        opts.Tools.Unregister(old.ToolNames()...)
+    }
+    if err := tools.ApplyToolMetadata(opts.Tools, p.Tools); err != nil { return nil, err }
+    if opts.Skills != nil {
+        if err := opts.Skills.Replace(replacing, p.Skills); err != nil { return nil, err }
+    }
+    return p, nil
+}
+
+// deniedEnv lists the environment variables the pack declares that are not in allow
+func (p *Pack) deniedEnv(allow []string) []string {
+    allowed := map[string]bool{}
+    for _, v := range allow { allowed[v] = true }
+    var out []string
+    for _, v := range p.Env {
+        if !allowed[v] { out = append(out, v) }
+    }
+    return out
+}
+
+// Uninstall removes an installed pack and unregisters its tools and skills
+// (either registry may be nil)
+func Uninstall(packsDir, name string, toolReg *tools.ToolRegistry, skillReg *skills.SkillRegistry) error {
+    if !namePattern.MatchString(name) { return fmt.Errorf("invalid skill pack name %q", name) }
+    dir := filepath.Join(packsDir, name)
+    p, err := Read(dir)
+    if err != nil {
+        if errors.Is(err, fs.ErrNotExist) { return fmt.Errorf("skill pack %s is not installed", name) }
+        return err
+    }
+    if err := os.RemoveAll(dir); err != nil { return err }
+    if toolReg != nil { toolReg.Unregister(p.ToolNames()...) }
+    if skillReg != nil { return skillReg.Replace(p.SkillNames(), nil) }
+    return nil
+}
+
+// List returns the installed packs, sorted by name
+func List(packsDir string) ([]*Pack, error) {
+    entries, err := os.ReadDir(packsDir)
+    if os.IsNotExist(err) { return nil, nil }
+    if err != nil { return nil, err }
+    var out []*Pack
+    for _, e := range entries {
+        if !e.IsDir() || strings.HasPrefix(e.Name(), ".") { continue }
+        p, err := Read(filepath.Join(packsDir, e.Name()))
+        if err != nil { return out, err }
+        out = append(out, p)
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+    return out, nil
+}
+
+// Load registers every installed pack. Packs that are not compatible with the
+// registries are skipped and reported in the returned error; the others load.
+func Load(packsDir string, toolReg *tools.ToolRegistry, skillReg *skills.SkillRegistry) ([]*Pack, error) {
+    packs, err := List(packsDir)
+    if err != nil { return nil, err }
+    var loaded []*Pack
+    var problems []string
+    for _, p := range packs {
+        if err := p.Check(toolReg, skillReg); err != nil { problems = append(problems, err.Error()); continue }
+        if err := p.Register(toolReg, skillReg); err != nil { problems = append(problems, err.Error()); continue }
+        loaded = append(loaded, p)
+    }
+    if len(problems) > 0 { return loaded, fmt.Errorf("%s", strings.Join(problems, "\n")) }
+    return loaded, nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+    out := make([]string, 0, len(m))
+    for k := range m { out = append(out, k) }
+    sort.Strings(out)
+    return out
+}
+
+// unzip extracts src into dir, rejecting entries that would land outside it
+func unzip(src, dir string) error {
+    zr, err := zip.OpenReader(src)
+    if err != nil { return err }
+    defer zr.Close()
+    for _, f := range zr.File {
+        name := filepath.FromSlash(f.Name)
+        target := filepath.Join(dir, name)
+        if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) { return fmt.Errorf("zip entry %q escapes the pack", f.Name) }
+        if f.FileInfo().IsDir() {
+            if err := os.MkdirAll(target, 0o755); err != nil { return err }
+            continue
+        }
+        if !f.Mode().IsRegular() { return fmt.Errorf("zip entry %q is not a regular file", f.Name) }
+        if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil { return err }
+        rc, err := f.Open()
+        if err != nil { return err }
+        err = writeFile(target, rc)
+        rc.Close()
+        if err != nil { return err }
+    }
+    return nil
+}
+
+// copyTree copies the regular files under src into dir
+func copyTree(src, dir string) error {
+    info, err := os.Stat(src)
+    if err != nil { return err }
+    if !info.IsDir() { return fmt.Errorf("not a directory or .zip file") }
+    return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+        if err != nil { return err }
+        rel, _ := filepath.Rel(src, path)
+        target := filepath.Join(dir, rel)
+        if d.IsDir() { return os.MkdirAll(target, 0o755) }
+        if !d.Type().IsRegular() { return nil }
+        f, err := os.Open(path)
+        if err != nil { return err }
+        defer f.Close()
+        return writeFile(target, f)
+    })
+}
+
+func writeFile(path string, r io.Reader) error {
+    f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+    if err != nil { return err }
+    if _, err := io.Copy(f, r); err != nil { f.Close(); return err }
+    return f.Close()
+}
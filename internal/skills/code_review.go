@@ -21,11 +21,30 @@ Focus on being helpful and educational, not just critical.`,
 		Tools: []string{
 			// Code analysis tools would go here
 		},
+		Inputs: []Input{
+			{Name: "language", Type: "string", Description: "Programming language of the code"},
+			{Name: "diff", Type: "string", Description: "Code or unified diff to review", Required: true},
+			{Name: "focus", Type: "string", Description: "What to concentrate on", Default: "general",
+				Enum: []interface{}{"general", "bugs", "security", "performance", "style"}},
+		},
+		PromptTemplate: `Review the following {{with .language}}{{.}} {{end}}code{{if ne .focus "general"}}, focusing on {{.focus}}{{end}}.
+
+{{.diff}}`,
 		Examples: []string{
 			"Review this Go function for potential issues",
 			"Analyze this API endpoint for security vulnerabilities",
 			"Suggest improvements for this database query",
 		},
+		FewShot: []Example{
+			{
+				Input: `Review this Go function:
+
+func Last(xs []int) int {
+	return xs[len(xs)-1]
+}`,
+				Output: "**Bug:** `Last` panics with an index out of range when `xs` is empty or nil.\n\n**Suggestion:** let callers handle the empty case explicitly:\n\n```go\n// Last returns the last element of xs and whether there was one\nfunc Last(xs []int) (int, bool) {\n\tif len(xs) == 0 {\n\t\treturn 0, false\n\t}\n\treturn xs[len(xs)-1], true\n}\n```\n\n**Minor:** a doc comment should say what happens for an empty slice, whichever behavior you choose.",
+			},
+		},
 		DefaultModel: llm.ModelClaude35Sonnet,
 	}
 }
@@ -28,6 +28,12 @@ Use execute_code to test and verify code when appropriate.`,
 			"Debug this JavaScript code",
 			"Explain how this algorithm works",
 		},
+		FewShot: []Example{
+			{
+				Input:  `Write a Python function that checks whether a string is a palindrome, ignoring case and spaces`,
+				Output: "```python\ndef is_palindrome(text: str) -> bool:\n    \"\"\"Return True if text reads the same backwards, ignoring case and spaces.\"\"\"\n    chars = [c.lower() for c in text if not c.isspace()]\n    return chars == chars[::-1]\n```\n\nIt drops whitespace, lowercases the rest and compares the characters with their reverse, in O(n) time and memory. For example, `is_palindrome(\"Never odd or even\")` returns `True`.",
+			},
+		},
 		DefaultModel: llm.ModelClaude35Sonnet,
 	}
 }
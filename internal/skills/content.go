@@ -26,6 +26,12 @@ Always create content that is informative, engaging, and well-sourced.`,
 			"Create social media content about productivity hacks",
 			"Draft a video script about the future of renewable energy",
 		},
+		FewShot: []Example{
+			{
+				Input:  `Write a three-sentence product blurb for a reusable water bottle`,
+				Output: `Meet the bottle that keeps up with you: double-walled steel keeps drinks cold for 24 hours and hot for 12. Its leak-proof lid and slim shape slide into any bag, so you can skip the single-use plastic wherever the day takes you. Refill, reuse and make every sip count.`,
+			},
+		},
 		DefaultModel: llm.ModelGPT4o,
 	}
 }
@@ -25,6 +25,16 @@ Always explain your methodology and reasoning clearly.`,
 			"Find correlations in this user behavior dataset",
 			"Provide insights from this customer feedback",
 		},
+		FewShot: []Example{
+			{
+				Input: `Monthly signups: Jan 120, Feb 135, Mar 150, Apr 90, May 160. What stands out?`,
+				Output: `**Trend:** signups grow by about 15 a month (120 → 135 → 150), and May's 160 is back on that line.
+
+**Anomaly:** April's 90 is about 60 below the roughly 165 the trend predicts, a 36% drop, and it recovers fully the next month. A one-month dip followed by a full recovery points to a temporary cause, such as a broken signup flow, a tracking outage or a paused campaign, rather than falling demand.
+
+**Recommendation:** check April's release notes, analytics events and marketing calendar before reading the dip as a change in demand.`,
+			},
+		},
 		DefaultModel: llm.ModelGPT4o,
 	}
 }
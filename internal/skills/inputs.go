@@ -0,0 +1,207 @@
+package skills
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/pradord/llm/internal/jsonschema"
+)
+
+// Input is a named, typed parameter of a skill. Skills with inputs render their
+// user prompt from PromptTemplate instead of taking free text.
+type Input struct {
+	Name        string        `yaml:"name" json:"name"`
+	Type        string        `yaml:"type" json:"type"` // string (default), integer, number, boolean, array or object
+	Description string        `yaml:"description" json:"description,omitempty"`
+	Required    bool          `yaml:"required" json:"required,omitempty"`
+	Default     interface{}   `yaml:"default" json:"default,omitempty"`
+	Enum        []interface{} `yaml:"enum" json:"enum,omitempty"`
+}
+
+func (in Input) typ() string {
+	if in.Type == "" {
+		return "string"
+	}
+	return in.Type
+}
+
+// CheckInputs reports malformed input declarations and a prompt template that
+// does not parse
+func (s *Skill) CheckInputs() error {
+	seen := map[string]bool{}
+	for _, in := range s.Inputs {
+		if in.Name == "" {
+			return fmt.Errorf("skill %s: input without a name", s.Name)
+		}
+		if seen[in.Name] {
+			return fmt.Errorf("skill %s: input %s is declared twice", s.Name, in.Name)
+		}
+		seen[in.Name] = true
+		switch in.typ() {
+		case "string", "integer", "number", "boolean", "array", "object":
+		default:
+			return fmt.Errorf("skill %s: input %s has unknown type %q", s.Name, in.Name, in.Type)
+		}
+	}
+	if s.PromptTemplate != "" {
+		if _, err := s.template(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Skill) template() (*template.Template, error) {
+	t, err := template.New(s.Name).Option("missingkey=error").Funcs(template.FuncMap{"join": strings.Join}).Parse(s.PromptTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("skill %s: prompt_template: %w", s.Name, err)
+	}
+	return t, nil
+}
+
+// InputSchema describes the skill's inputs as a JSON Schema object, e.g. to
+// build a form. It is nil for skills without inputs.
+func (s *Skill) InputSchema() map[string]interface{} {
+	if len(s.Inputs) == 0 {
+		return nil
+	}
+	props := map[string]interface{}{}
+	required := []interface{}{}
+	for _, in := range s.Inputs {
+		p := map[string]interface{}{"type": in.typ()}
+		if in.Description != "" {
+			p["description"] = in.Description
+		}
+		if in.Default != nil {
+			p["default"] = in.Default
+		}
+		if len(in.Enum) > 0 {
+			p["enum"] = in.Enum
+		}
+		props[in.Name] = p
+		if in.Required {
+			required = append(required, in.Name)
+		}
+	}
+	return map[string]interface{}{"type": "object", "properties": props, "required": required, "additionalProperties": false}
+}
+
+// ParseInputs converts command line or form values to the declared input types.
+// A value of the form @path is read from that file.
+func (s *Skill) ParseInputs(raw map[string]string, readFile func(string) ([]byte, error)) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(raw))
+	for name, v := range raw {
+		if strings.HasPrefix(v, "@") && readFile != nil {
+			data, err := readFile(v[1:])
+			if err != nil {
+				return nil, fmt.Errorf("input %s: %w", name, err)
+			}
+			v = string(data)
+		}
+		typ := "string"
+		for _, in := range s.Inputs {
+			if in.Name == name {
+				typ = in.typ()
+			}
+		}
+		var err error
+		switch typ {
+		case "integer":
+			out[name], err = strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		case "number":
+			out[name], err = strconv.ParseFloat(strings.TrimSpace(v), 64)
+		case "boolean":
+			out[name], err = strconv.ParseBool(strings.TrimSpace(v))
+		case "array", "object":
+			var x interface{}
+			err = json.Unmarshal([]byte(v), &x)
+			if err != nil && typ == "array" {
+				// comma separated list
+				var items []interface{}
+				for _, item := range strings.Split(v, ",") {
+					items = append(items, strings.TrimSpace(item))
+				}
+				x, err = items, nil
+			}
+			out[name] = x
+		default:
+			out[name] = v
+		}
+		if err != nil {
+			return nil, fmt.Errorf("input %s: expected %s, got %q", name, typ, v)
+		}
+	}
+	return out, nil
+}
+
+// ValidateInputs fills in defaults and checks values against InputSchema. The
+// returned map is a copy.
+func (s *Skill) ValidateInputs(values map[string]interface{}) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(s.Inputs))
+	for k, v := range values {
+		out[k] = v
+	}
+	for _, in := range s.Inputs {
+		if _, ok := out[in.Name]; !ok && in.Default != nil {
+			out[in.Name] = in.Default
+		}
+	}
+	if schema := s.InputSchema(); schema != nil {
+		// validate the JSON form so Go ints and YAML values compare like decoded JSON
+		data, err := json.Marshal(out)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := jsonschema.ValidateJSON(schema, data); err != nil {
+			return nil, fmt.Errorf("skill %s: inputs %w", s.Name, err)
+		}
+	}
+	return out, nil
+}
+
+// RenderPrompt validates values and renders the user prompt. Without a
+// PromptTemplate, each input becomes a "name:" section in declaration order.
+func (s *Skill) RenderPrompt(values map[string]interface{}) (string, error) {
+	values, err := s.ValidateInputs(values)
+	if err != nil {
+		return "", err
+	}
+	if s.PromptTemplate == "" {
+		var b strings.Builder
+		names := make([]string, 0, len(values))
+		for _, in := range s.Inputs {
+			if _, ok := values[in.Name]; ok {
+				names = append(names, in.Name)
+			}
+		}
+		if len(s.Inputs) == 0 {
+			for k := range values {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+		}
+		for _, n := range names {
+			fmt.Fprintf(&b, "%s:\n%v\n\n", n, values[n])
+		}
+		return strings.TrimSpace(b.String()), nil
+	}
+	// optional inputs left unset render as empty strings; unknown names are errors
+	for _, in := range s.Inputs {
+		if _, ok := values[in.Name]; !ok {
+			values[in.Name] = ""
+		}
+	}
+	t, err := s.template()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, values); err != nil {
+		return "", fmt.Errorf("skill %s: prompt_template: %w", s.Name, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
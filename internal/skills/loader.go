@@ -1,6 +1,7 @@
 package skills
 
 import (
+    "fmt"
     "io/fs"
     "os"
     "path/filepath"
@@ -13,39 +14,87 @@ import (
 
 // SkillYAML captures skill metadata for YAML loading
 type SkillYAML struct {
-    Name         string     `yaml:"name"`
-    Description  string     `yaml:"description"`
-    SystemPrompt string     `yaml:"system_prompt"`
-    Tools        []string   `yaml:"tools"`
-    Resources    []string   `yaml:"resources"`
-    Examples     []string   `yaml:"examples"`
-    DefaultModel llm.Model  `yaml:"default_model"`
+    Name           string                 `yaml:"name"`
+    Description    string                 `yaml:"description"`
+    SystemPrompt   string                 `yaml:"system_prompt"`
+    Tools          []string               `yaml:"tools"`
+    Resources      []string               `yaml:"resources"`
+    Examples       []ExampleYAML          `yaml:"examples"` // a request, or an input/output pair
+    RequiredSkills []string               `yaml:"required_skills"` // callable as skill_<name> tools
+    DefaultModel   llm.Model              `yaml:"default_model"`
+    Temperature    *float64               `yaml:"temperature"`
+    MaxSteps       int                    `yaml:"max_steps"`
+    MaxChars       int                    `yaml:"max_chars"`
+    OutputSchema   map[string]interface{} `yaml:"output_schema"` // JSON Schema for structured answers
+    OutputRetries  int                    `yaml:"output_retries"`
+    Inputs         []Input                `yaml:"inputs"`
+    PromptTemplate string                 `yaml:"prompt_template"`
 }
 
-// LoadSkillsDir loads skills from YAML files and registers them
+// ExampleYAML is either a plain example request or an {input, output} pair
+type ExampleYAML Example
+
+func (e *ExampleYAML) UnmarshalYAML(n *yaml.Node) error {
+    if n.Kind == yaml.ScalarNode { return n.Decode(&e.Input) }
+    return n.Decode((*Example)(e))
+}
+
+// LoadSkillsDir loads skills from YAML files and registers them. Relative
+// resource paths are resolved against the YAML file's directory.
 func LoadSkillsDir(dir string, reg *SkillRegistry) error {
-    if dir == "" { return nil }
-    if _, err := os.Stat(dir); err != nil { return nil }
-    return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+    list, err := ParseSkillsDir(dir)
+    if err != nil { return err }
+    for _, s := range list {
+        if err := reg.Register(s); err != nil { return err }
+    }
+    return nil
+}
+
+// ParseSkillsDir reads the skills defined in dir without registering them
+func ParseSkillsDir(dir string) ([]*Skill, error) {
+    var out []*Skill
+    if dir == "" { return out, nil }
+    if _, err := os.Stat(dir); err != nil { return out, nil }
+    err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
         if err != nil { return err }
         if d.IsDir() { return nil }
-        if !strings.HasSuffix(strings.ToLower(d.Name()), ".yaml") { return nil }
+        if ext := strings.ToLower(filepath.Ext(d.Name())); ext != ".yaml" && ext != ".yml" { return nil }
         data, err := os.ReadFile(path)
         if err != nil { return err }
         var s SkillYAML
-        if err := yaml.Unmarshal(data, &s); err != nil { return err }
+        if err := yaml.Unmarshal(data, &s); err != nil { return fmt.Errorf("parse skill yaml %s: %w", path, err) }
         if s.Name == "" { return nil }
-        reg.Register(&Skill{
-            Name:         s.Name,
-            Description:  s.Description,
-            SystemPrompt: s.SystemPrompt,
-            Tools:        s.Tools,
-            Resources:    s.Resources,
-            Examples:     s.Examples,
-            DefaultModel: s.DefaultModel,
+        var examples []string
+        var fewShot []Example
+        for _, ex := range s.Examples {
+            if ex.Output == "" { examples = append(examples, ex.Input) } else { fewShot = append(fewShot, Example(ex)) }
+        }
+        resources := make([]string, len(s.Resources))
+        for i, r := range s.Resources {
+            if !strings.Contains(r, "://") && !filepath.IsAbs(r) { r = filepath.Join(filepath.Dir(path), r) }
+            resources[i] = r
+        }
+        out = append(out, &Skill{
+            Name:           s.Name,
+            Description:    s.Description,
+            SystemPrompt:   s.SystemPrompt,
+            Tools:          s.Tools,
+            Resources:      resources,
+            Examples:       examples,
+            FewShot:        fewShot,
+            RequiredSkills: s.RequiredSkills,
+            DefaultModel:   s.DefaultModel,
+            Temperature:    s.Temperature,
+            MaxSteps:       s.MaxSteps,
+            MaxChars:       s.MaxChars,
+            OutputSchema:   s.OutputSchema,
+            OutputRetries:  s.OutputRetries,
+            Inputs:         s.Inputs,
+            PromptTemplate: s.PromptTemplate,
         })
         return nil
     })
+    return out, err
 }
 
 // Optionally ensure tools exist for a skill
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/pradord/llm/internal/llm"
 	"github.com/pradord/llm/internal/tools"
@@ -15,14 +17,29 @@ type Skill struct {
 	Description    string
 	SystemPrompt   string
 	Tools          []string  // Tool names this skill can use
-	Resources      []string  // Files, docs, context
-	Examples       []string  // Few-shot examples
+	Resources      []string  // Files, globs or URLs added to the system prompt as reference material
+	Examples       []string  // Example requests, matched by the router; runs see FewShot instead
+	FewShot        []Example // Input/output pairs sent as example turns before the user prompt
+	Inputs         []Input   // Typed parameters; see RenderPrompt
+	PromptTemplate string    // text/template over Inputs that renders the user prompt
 	RequiredSkills []string  // Other skills this skill depends on
 	DefaultModel   llm.Model
+	Temperature    *float64  // run settings; unset values come from the project or config
+	MaxSteps       int
+	MaxChars       int
+	OutputSchema   map[string]interface{} // JSON Schema the final answer must match; see agent.RunTyped
+	OutputRetries  int                    // re-prompts after an invalid answer (default 2, <0 disables)
 }
 
-// SkillRegistry manages available skills
+// Example is one few-shot demonstration of a skill
+type Example struct {
+	Input  string `yaml:"input" json:"input"`
+	Output string `yaml:"output" json:"output"`
+}
+
+// SkillRegistry manages available skills. It is safe for concurrent use.
 type SkillRegistry struct {
+	mu     sync.RWMutex
 	skills map[string]*Skill
 	tools  *tools.ToolRegistry
 }
@@ -35,19 +52,91 @@ func NewSkillRegistry(toolRegistry *tools.ToolRegistry) *SkillRegistry {
 	}
 }
 
-// Register adds a skill to the registry
-func (sr *SkillRegistry) Register(skill *Skill) {
+// Register adds a skill to the registry. It fails when the skill's
+// RequiredSkills would form a cycle with registered skills; required skills
+// that are not registered yet are allowed.
+func (sr *SkillRegistry) Register(skill *Skill) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if cycle := requiredCycle(sr.skills, skill); cycle != nil {
+		return fmt.Errorf("skill %s: required skills form a cycle: %s", skill.Name, strings.Join(cycle, " -> "))
+	}
 	sr.skills[skill.Name] = skill
+	return nil
+}
+
+// Replace removes the named skills and registers add in their place as one
+// step, e.g. when a definitions directory is reloaded. On a RequiredSkills
+// cycle nothing changes.
+func (sr *SkillRegistry) Replace(remove []string, add []*Skill) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	next := make(map[string]*Skill, len(sr.skills))
+	for name, s := range sr.skills {
+		next[name] = s
+	}
+	for _, name := range remove {
+		delete(next, name)
+	}
+	for _, s := range add {
+		next[s.Name] = s
+	}
+	for _, s := range add {
+		if cycle := requiredCycle(next, s); cycle != nil {
+			return fmt.Errorf("skill %s: required skills form a cycle: %s", s.Name, strings.Join(cycle, " -> "))
+		}
+	}
+	sr.skills = next
+	return nil
+}
+
+// requiredCycle returns the path of a RequiredSkills cycle through skill, if any
+func requiredCycle(skills map[string]*Skill, skill *Skill) []string {
+	get := func(name string) *Skill {
+		if name == skill.Name {
+			return skill
+		}
+		return skills[name]
+	}
+	done := map[string]bool{}
+	var path []string
+	var visit func(s *Skill) []string
+	visit = func(s *Skill) []string {
+		for i, name := range path {
+			if name == s.Name {
+				return append(append([]string(nil), path[i:]...), s.Name)
+			}
+		}
+		if done[s.Name] {
+			return nil
+		}
+		path = append(path, s.Name)
+		for _, req := range s.RequiredSkills {
+			if r := get(req); r != nil {
+				if cycle := visit(r); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		done[s.Name] = true
+		return nil
+	}
+	return visit(skill)
 }
 
 // Get retrieves a skill by name
 func (sr *SkillRegistry) Get(name string) (*Skill, bool) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
 	skill, ok := sr.skills[name]
 	return skill, ok
 }
 
 // List returns all registered skills
 func (sr *SkillRegistry) List() []*Skill {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
 	skillList := make([]*Skill, 0, len(sr.skills))
 	for _, skill := range sr.skills {
 		skillList = append(skillList, skill)
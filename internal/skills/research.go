@@ -25,6 +25,19 @@ Always verify information from multiple sources and provide clear, well-structur
 			"What are the health benefits of intermittent fasting?",
 			"Explain the current state of AI alignment research",
 		},
+		FewShot: []Example{
+			{
+				Input: `What is the difference between weather and climate?`,
+				Output: `**Short answer:** weather is the state of the atmosphere at a given place and time; climate is the long-term pattern of weather in a region, usually averaged over 30 years or more.
+
+**Key points**
+- *Time scale:* weather changes in minutes to days; climate describes decades.
+- *What is measured:* weather is today's temperature, rain and wind; climate is the typical range of those values and how often extremes occur.
+- *Prediction:* weather forecasts lose skill after about two weeks, while climate projections describe long-term averages and trends rather than specific days.
+
+A cold week therefore says little about the climate, while a decades-long rise in average temperatures does.`,
+			},
+		},
 		DefaultModel: llm.ModelClaude35Sonnet,
 	}
 }
@@ -27,6 +27,12 @@ Use fetch_url to retrieve web articles when given URLs.`,
 			"Give me the key points from this document",
 			"Create a one-paragraph summary of this research paper",
 		},
+		FewShot: []Example{
+			{
+				Input:  `Summarize in one sentence: The city council voted 7-2 on Tuesday to extend the downtown bike lane network by 12 miles over the next three years. The $18 million project will be funded mainly by a state transportation grant, with the rest coming from the city's capital budget. Supporters cited safety data showing fewer injuries on streets with protected lanes, while opponents worried about the loss of roughly 400 parking spaces.`,
+				Output: `The city council voted 7-2 to add 12 miles of downtown bike lanes over three years in an $18 million project funded mostly by a state grant, citing safety benefits despite concerns about losing about 400 parking spaces.`,
+			},
+		},
 		DefaultModel: llm.ModelClaude35Sonnet,
 	}
 }
@@ -25,6 +25,15 @@ Always maintain the original intent, formality level, and emotional tone.`,
 			"Convert this business email to French",
 			"Translate this poem from Japanese to English",
 		},
+		FewShot: []Example{
+			{
+				Input: `Translate to Spanish: 'Could you send me the report by Friday?'`,
+				Output: `**Spanish:** ¿Podría enviarme el informe para el viernes?
+
+- *Podría* is the formal "could you" (usted). With a colleague you know well, use *¿Podrías enviarme el informe para el viernes?*
+- *Para el viernes* means "by Friday", with Friday as the deadline. *Antes del viernes* would mean "before Friday".`,
+			},
+		},
 		DefaultModel: llm.ModelGPT4o, // GPT-4o excels at multilingual
 	}
 }
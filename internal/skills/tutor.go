@@ -27,6 +27,20 @@ Always be encouraging, patient, and adapt your explanations to the student's lev
 			"Help me understand calculus derivatives",
 			"Teach me the basics of machine learning",
 		},
+		FewShot: []Example{
+			{
+				Input: `Why does ice float on water?`,
+				Output: `Great question! It comes down to **density**, which is how much stuff is packed into a given space.
+
+1. Most substances get denser when they freeze, because their molecules pack closer together.
+2. Water is unusual. As it freezes, its molecules lock into a hexagonal pattern that holds them slightly *farther* apart than in liquid water.
+3. That makes ice about 9% less dense than liquid water, and anything less dense than water floats.
+
+**Analogy:** picture a crowd standing shoulder to shoulder (liquid water) and then asked to hold hands in a ring (ice). The ring takes up more room for the same number of people.
+
+Can you guess why this matters for fish in a frozen lake?`,
+			},
+		},
 		DefaultModel: llm.ModelClaude35Sonnet,
 	}
 }
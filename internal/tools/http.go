@@ -0,0 +1,262 @@
+package tools
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "regexp"
+    "sort"
+    "strings"
+    "text/template"
+    "time"
+
+    "github.com/pradord/llm/internal/jsonpath"
+    "github.com/pradord/llm/internal/llm"
+)
+
+// HTTPSpec makes a tool definition a complete HTTP tool, so internal APIs can
+// be called without writing a handler. Url, query, headers and body are
+// text/template over the call's arguments, e.g. {{.id}}; ${VAR} reads the
+// environment, for secrets, and is never taken from arguments.
+type HTTPSpec struct {
+    Method           string            `yaml:"method"`             // default GET
+    URL              string            `yaml:"url"`                // arguments are URL-escaped
+    Query            map[string]string `yaml:"query"`              // parameters that render empty are left out
+    Headers          map[string]string `yaml:"headers"`
+    Body             string            `yaml:"body"`               // default for POST, PUT and PATCH: the arguments as JSON; string arguments are JSON-escaped in a body starting with { or [
+    Extract          string            `yaml:"extract"`            // JSON path of the part of the response to return, e.g. $.items[*].title
+    MaxResponseBytes int64             `yaml:"max_response_bytes"` // default 1MB; longer responses are truncated, or fail with extract
+    Timeout          string            `yaml:"timeout"`            // default 30s
+}
+
+const defaultMaxResponse = 1 << 20
+
+// HTTPTool executes the HTTPSpec of a ToolMetadata
+type HTTPTool struct {
+    name       string
+    meta       ToolMetadata
+    spec       HTTPSpec
+    params     []string // declared parameters, rendered as "" when not passed
+    httpClient *http.Client
+}
+
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// EnvVars lists the environment variables the spec's templates read, sorted
+func (s *HTTPSpec) EnvVars() []string {
+    texts := []string{s.URL, s.Body}
+    for _, v := range s.Query { texts = append(texts, v) }
+    for _, v := range s.Headers { texts = append(texts, v) }
+    seen := map[string]bool{}
+    var out []string
+    for _, text := range texts {
+        for _, m := range envRef.FindAllStringSubmatch(text, -1) {
+            if !seen[m[1]] { seen[m[1]] = true; out = append(out, m[1]) }
+        }
+    }
+    sort.Strings(out)
+    return out
+}
+
+// NewHTTPTool checks m.HTTP and parses its templates
+func NewHTTPTool(m ToolMetadata) (*HTTPTool, error) {
+    if m.HTTP == nil { return nil, fmt.Errorf("tool %s: no http spec", m.Name) }
+    spec := *m.HTTP
+    spec.Method = strings.ToUpper(spec.Method)
+    if spec.Method == "" { spec.Method = http.MethodGet }
+    switch spec.Method {
+    case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead:
+    default:
+        return nil, fmt.Errorf("tool %s: unsupported http method %q", m.Name, spec.Method)
+    }
+    if spec.URL == "" { return nil, fmt.Errorf("tool %s: http url is required", m.Name) }
+    if spec.MaxResponseBytes <= 0 { spec.MaxResponseBytes = defaultMaxResponse }
+    timeout := 30 * time.Second
+    if spec.Timeout != "" {
+        d, err := time.ParseDuration(spec.Timeout)
+        if err != nil || d <= 0 { return nil, fmt.Errorf("tool %s: invalid http timeout %q", m.Name, spec.Timeout) }
+        timeout = d
+    }
+    if spec.Extract != "" && !strings.HasPrefix(spec.Extract, "$") { return nil, fmt.Errorf("tool %s: extract must be a JSON path starting with $", m.Name) }
+
+    if m.Parameters == nil { m.Parameters = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}} }
+    t := &HTTPTool{name: m.Name, meta: m, spec: spec, httpClient: &http.Client{Timeout: timeout}}
+    if props, ok := m.Parameters["properties"].(map[string]interface{}); ok {
+        for name := range props { t.params = append(t.params, name) }
+    }
+    templates := map[string]string{"url": spec.URL, "body": spec.Body}
+    for k, v := range spec.Query { templates["query "+k] = v }
+    for k, v := range spec.Headers { templates["header "+k] = v }
+    for what, text := range templates {
+        if _, err := parseTemplate(what, text); err != nil { return nil, fmt.Errorf("tool %s: http %w", m.Name, err) }
+    }
+    return t, nil
+}
+
+func (t *HTTPTool) Name() string             { return t.name }
+func (t *HTTPTool) Description() string      { return t.meta.Description }
+func (t *HTTPTool) Parameters() interface{}  { return t.meta.Parameters }
+func (t *HTTPTool) RequiredModel() llm.Model { return t.meta.RequiredModel }
+func (t *HTTPTool) ModelType() llm.ModelType { return llm.ModelTypeText }
+
+func (t *HTTPTool) Execute(ctx context.Context, args json.RawMessage) (string, error) {
+    params := map[string]interface{}{}
+    if len(bytes.TrimSpace(args)) > 0 {
+        if err := json.Unmarshal(args, &params); err != nil { return "", fmt.Errorf("tool %s: arguments: %w", t.name, err) }
+    }
+    req, err := t.request(ctx, params)
+    if err != nil { return "", err }
+    resp, err := t.httpClient.Do(req)
+    if err != nil { return "", fmt.Errorf("tool %s: %s request failed: %w", t.name, t.spec.Method, scrub(err)) }
+    defer resp.Body.Close()
+
+    body, err := io.ReadAll(io.LimitReader(resp.Body, t.spec.MaxResponseBytes+1))
+    if err != nil { return "", fmt.Errorf("tool %s: read response: %w", t.name, err) }
+    truncated := int64(len(body)) > t.spec.MaxResponseBytes
+    if truncated { body = body[:t.spec.MaxResponseBytes] }
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return "", fmt.Errorf("tool %s: HTTP %d: %s", t.name, resp.StatusCode, clipText(strings.TrimSpace(string(body)), 500))
+    }
+    if t.spec.Extract == "" {
+        if truncated { return string(body) + fmt.Sprintf("\n[response truncated at %d bytes]", t.spec.MaxResponseBytes), nil }
+        return string(body), nil
+    }
+    if truncated { return "", fmt.Errorf("tool %s: response is larger than %d bytes", t.name, t.spec.MaxResponseBytes) }
+    var doc interface{}
+    if err := json.Unmarshal(body, &doc); err != nil { return "", fmt.Errorf("tool %s: response is not JSON", t.name) }
+    v, err := jsonpath.Lookup(doc, t.spec.Extract)
+    if err != nil { return "", fmt.Errorf("tool %s: extract %w", t.name, err) }
+    if s, ok := v.(string); ok { return s, nil }
+    out, err := json.Marshal(v)
+    if err != nil { return "", err }
+    return string(out), nil
+}
+
+// request builds the request for one call
+func (t *HTTPTool) request(ctx context.Context, params map[string]interface{}) (*http.Request, error) {
+    raw := make(map[string]interface{}, len(params)+len(t.params))
+    escaped := make(map[string]interface{}, len(params)+len(t.params))
+    for _, name := range t.params { raw[name], escaped[name] = "", "" }
+    for k, v := range params {
+        raw[k] = v
+        escaped[k] = escapeArg(v)
+    }
+
+    u, err := t.render("url", t.spec.URL, escaped)
+    if err != nil { return nil, err }
+    parsed, err := url.Parse(u)
+    if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+        return nil, fmt.Errorf("tool %s: url does not render to an http(s) URL", t.name)
+    }
+    if len(t.spec.Query) > 0 {
+        q := parsed.Query()
+        for k, text := range t.spec.Query {
+            v, err := t.render("query "+k, text, raw)
+            if err != nil { return nil, err }
+            if v != "" { q.Set(k, v) }
+        }
+        parsed.RawQuery = q.Encode()
+    }
+
+    var body io.Reader
+    var contentType string
+    switch {
+    case t.spec.Body != "":
+        data := raw
+        if isJSONTemplate(t.spec.Body) { data = jsonArgs(raw) }
+        b, err := t.render("body", t.spec.Body, data)
+        if err != nil { return nil, err }
+        body = strings.NewReader(b)
+        if json.Valid([]byte(b)) { contentType = "application/json" } else { contentType = "text/plain; charset=utf-8" }
+    case t.spec.Method == http.MethodPost || t.spec.Method == http.MethodPut || t.spec.Method == http.MethodPatch:
+        b, _ := json.Marshal(params)
+        body = bytes.NewReader(b)
+        contentType = "application/json"
+    }
+    req, err := http.NewRequestWithContext(ctx, t.spec.Method, parsed.String(), body)
+    if err != nil { return nil, fmt.Errorf("tool %s: %w", t.name, scrub(err)) }
+    req.Header.Set("User-Agent", "LLM-Library/1.0")
+    if contentType != "" { req.Header.Set("Content-Type", contentType) }
+    for k, text := range t.spec.Headers {
+        v, err := t.render("header "+k, text, raw)
+        if err != nil { return nil, err }
+        if strings.ContainsAny(v, "\r\n") { return nil, fmt.Errorf("tool %s: header %s contains a line break", t.name, k) }
+        req.Header.Set(k, v)
+    }
+    return req, nil
+}
+
+// render fills in the ${VAR} references of a template from the environment,
+// then executes it over the arguments. Environment values are substituted
+// into the definition first, so arguments cannot name a variable to read.
+func (t *HTTPTool) render(what, text string, data map[string]interface{}) (string, error) {
+    var missing []string
+    text = envRef.ReplaceAllStringFunc(text, func(ref string) string {
+        name := envRef.FindStringSubmatch(ref)[1]
+        v, ok := os.LookupEnv(name)
+        if !ok { missing = append(missing, name) }
+        return strings.ReplaceAll(v, "{{", `{{"{{"}}`)
+    })
+    if len(missing) > 0 { return "", fmt.Errorf("tool %s: environment variable %s is not set", t.name, strings.Join(missing, ", ")) }
+    tmpl, err := parseTemplate(what, text)
+    if err != nil { return "", fmt.Errorf("tool %s: %w", t.name, err) }
+    var b strings.Builder
+    if err := tmpl.Execute(&b, data); err != nil { return "", fmt.Errorf("tool %s: %w", t.name, err) }
+    return b.String(), nil
+}
+
+func parseTemplate(what, text string) (*template.Template, error) {
+    return template.New(what).Option("missingkey=error").Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+}
+
+// escapeArg formats an argument for a URL, escaping it for both paths and queries
+func escapeArg(v interface{}) string {
+    s, ok := v.(string)
+    if !ok { b, _ := json.Marshal(v); s = string(b) }
+    return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// jsonText is a string argument of a JSON body: it prints JSON-escaped, without
+// quotes, so "{{.q}}" cannot break out of its string
+type jsonText string
+
+func (s jsonText) String() string {
+    b, _ := json.Marshal(string(s))
+    return string(b[1 : len(b)-1])
+}
+
+func isJSONTemplate(text string) bool {
+    text = strings.TrimSpace(text)
+    return strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[")
+}
+
+// jsonArgs returns args with the strings as jsonText
+func jsonArgs(args map[string]interface{}) map[string]interface{} {
+    out := make(map[string]interface{}, len(args))
+    for k, v := range args {
+        if s, ok := v.(string); ok { out[k] = jsonText(s) } else { out[k] = v }
+    }
+    return out
+}
+
+func toJSON(v interface{}) (string, error) {
+    if s, ok := v.(jsonText); ok { v = string(s) }
+    b, err := json.Marshal(v)
+    return string(b), err
+}
+
+// scrub drops the URL from transport errors, since it may carry secrets
+func scrub(err error) error {
+    if ue, ok := err.(*url.Error); ok { return fmt.Errorf("%s: %w", ue.Op, ue.Err) }
+    return err
+}
+
+func clipText(s string, n int) string {
+    if len(s) <= n { return s }
+    return s[:n] + "..."
+}
@@ -0,0 +1,119 @@
+package tools
+
+import (
+    "context"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// request is what the test server saw of the last call
+type request struct {
+    method, path, rawPath, query, body, contentType string
+    header                                          http.Header
+}
+
+func testServer(t *testing.T, status int, reply string) (*httptest.Server, *request) {
+    t.Helper()
+    var got request
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        b, _ := io.ReadAll(r.Body)
+        got = request{method: r.Method, path: r.URL.Path, rawPath: r.URL.EscapedPath(), query: r.URL.RawQuery, body: string(b), contentType: r.Header.Get("Content-Type"), header: r.Header}
+        w.WriteHeader(status)
+        io.WriteString(w, reply)
+    }))
+    t.Cleanup(srv.Close)
+    return srv, &got
+}
+
+func newTestHTTPTool(t *testing.T, spec HTTPSpec) *HTTPTool {
+    t.Helper()
+    tool, err := NewHTTPTool(ToolMetadata{Name: "test_http", HTTP: &spec, Parameters: map[string]interface{}{
+        "type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "string"}, "q": map[string]interface{}{"type": "string"}},
+    }})
+    if err != nil { t.Fatal(err) }
+    return tool
+}
+
+func TestHTTPToolEscapesURLArguments(t *testing.T) {
+    srv, got := testServer(t, 200, "ok")
+    tool := newTestHTTPTool(t, HTTPSpec{URL: srv.URL + "/items/{{.id}}", Query: map[string]string{"q": "{{.q}}"}})
+    if _, err := tool.Execute(context.Background(), json.RawMessage(`{"id":"a/../b?c=1 d","q":"x&y=z"}`)); err != nil { t.Fatal(err) }
+    if got.rawPath != "/items/a%2F..%2Fb%3Fc%3D1%20d" { t.Fatalf("path %q: argument was not escaped", got.rawPath) }
+    if got.query != "q=x%26y%3Dz" { t.Fatalf("query %q: argument was not encoded", got.query) }
+
+    // parameters that render empty are left out of the query
+    if _, err := tool.Execute(context.Background(), json.RawMessage(`{"id":"1"}`)); err != nil { t.Fatal(err) }
+    if got.query != "" { t.Fatalf("query %q, want none", got.query) }
+}
+
+func TestHTTPToolEnvironment(t *testing.T) {
+    srv, got := testServer(t, 200, "ok")
+    tool := newTestHTTPTool(t, HTTPSpec{URL: srv.URL + "/{{.id}}", Headers: map[string]string{"Authorization": "Bearer ${HTTP_TEST_TOKEN}"}})
+
+    t.Setenv("HTTP_TEST_TOKEN", "s3cret{{.id}}")
+    if _, err := tool.Execute(context.Background(), json.RawMessage(`{"id":"1"}`)); err != nil { t.Fatal(err) }
+    // the value is used as is, not as a template
+    if h := got.header.Get("Authorization"); h != "Bearer s3cret{{.id}}" { t.Fatalf("Authorization %q", h) }
+
+    // arguments cannot name a variable to read
+    if _, err := tool.Execute(context.Background(), json.RawMessage(`{"id":"${HTTP_TEST_TOKEN}"}`)); err != nil { t.Fatal(err) }
+    if strings.Contains(got.path, "s3cret") { t.Fatalf("argument read the environment: %q", got.path) }
+
+    missing := newTestHTTPTool(t, HTTPSpec{URL: srv.URL + "/${HTTP_TEST_UNSET}"})
+    _, err := missing.Execute(context.Background(), nil)
+    if err == nil || !strings.Contains(err.Error(), "HTTP_TEST_UNSET is not set") { t.Fatalf("missing variable: %v", err) }
+}
+
+func TestHTTPToolRejectsHeaderLineBreaks(t *testing.T) {
+    srv, _ := testServer(t, 200, "ok")
+    tool := newTestHTTPTool(t, HTTPSpec{URL: srv.URL, Headers: map[string]string{"X-Query": "{{.q}}"}})
+    _, err := tool.Execute(context.Background(), json.RawMessage(`{"q":"a\r\nX-Injected: 1"}`))
+    if err == nil || !strings.Contains(err.Error(), "line break") { t.Fatalf("got %v, want a line break error", err) }
+}
+
+func TestHTTPToolJSONBody(t *testing.T) {
+    srv, got := testServer(t, 200, "ok")
+    tool := newTestHTTPTool(t, HTTPSpec{Method: "POST", URL: srv.URL, Body: `{"q":"{{.q}}","id":{{json .id}}}`})
+    q := `x","admin":true,"y":"`
+    args, _ := json.Marshal(map[string]string{"q": q, "id": "7"})
+    if _, err := tool.Execute(context.Background(), args); err != nil { t.Fatal(err) }
+    var body map[string]interface{}
+    if err := json.Unmarshal([]byte(got.body), &body); err != nil { t.Fatalf("body %q is not JSON: %v", got.body, err) }
+    if len(body) != 2 || body["q"] != q || body["id"] != "7" { t.Fatalf("body %q", got.body) }
+    if got.contentType != "application/json" { t.Fatalf("Content-Type %q", got.contentType) }
+
+    // bodies that are not JSON get the arguments as they are
+    text := newTestHTTPTool(t, HTTPSpec{Method: "POST", URL: srv.URL, Body: `search: {{.q}}`})
+    if _, err := text.Execute(context.Background(), json.RawMessage(`{"q":"a \"b\""}`)); err != nil { t.Fatal(err) }
+    if got.body != `search: a "b"` { t.Fatalf("body %q", got.body) }
+}
+
+func TestHTTPToolMaxResponseBytes(t *testing.T) {
+    srv, _ := testServer(t, 200, `{"items":["one","two","three"]}`)
+
+    tool := newTestHTTPTool(t, HTTPSpec{URL: srv.URL, MaxResponseBytes: 10})
+    out, err := tool.Execute(context.Background(), nil)
+    if err != nil { t.Fatal(err) }
+    if !strings.HasPrefix(out, `{"items":[`) || !strings.Contains(out, "[response truncated at 10 bytes]") { t.Fatalf("got %q", out) }
+
+    extract := newTestHTTPTool(t, HTTPSpec{URL: srv.URL, MaxResponseBytes: 10, Extract: "$.items"})
+    if _, err := extract.Execute(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "larger than 10 bytes") {
+        t.Fatalf("got %v, want a size error", err)
+    }
+
+    whole := newTestHTTPTool(t, HTTPSpec{URL: srv.URL, Extract: "$.items"})
+    out, err = whole.Execute(context.Background(), nil)
+    if err != nil { t.Fatal(err) }
+    if out != `["one","two","three"]` { t.Fatalf("got %q", out) }
+}
+
+func TestHTTPToolNon2xx(t *testing.T) {
+    srv, _ := testServer(t, 404, "no such ticket")
+    tool := newTestHTTPTool(t, HTTPSpec{URL: srv.URL})
+    _, err := tool.Execute(context.Background(), nil)
+    if err == nil || !strings.Contains(err.Error(), "HTTP 404: no such ticket") { t.Fatalf("got %v, want the status and body", err) }
+}
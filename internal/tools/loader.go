@@ -20,6 +20,7 @@ type ToolMetadata struct {
     RequiredModel llm.Model              `yaml:"required_model"`
     ModelType     string                 `yaml:"model_type"`
     Parameters    map[string]interface{} `yaml:"parameters"`
+    HTTP          *HTTPSpec              `yaml:"http"` // makes this a complete HTTP tool instead of metadata for a Go handler
 }
 
 // WrappedTool overlays metadata on top of an existing Tool, delegating Execute to the base
@@ -41,7 +42,7 @@ func (w *WrappedTool) Execute(ctx context.Context, args json.RawMessage) (string
 func (w *WrappedTool) RequiredModel() llm.Model     { if w.model != "" { return w.model }; return w.base.RequiredModel() }
 func (w *WrappedTool) ModelType() llm.ModelType     { if w.modelType != llm.ModelTypeInvalid { return w.modelType }; return w.base.ModelType() }
 
-// LoadToolMetadataDir loads all *.yaml from dir and returns metadata
+// LoadToolMetadataDir loads all *.yaml and *.yml from dir and returns metadata
 func LoadToolMetadataDir(dir string) ([]ToolMetadata, error) {
     var out []ToolMetadata
     if dir == "" { return out, nil }
@@ -49,7 +50,7 @@ func LoadToolMetadataDir(dir string) ([]ToolMetadata, error) {
     err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
         if err != nil { return err }
         if d.IsDir() { return nil }
-        if !strings.HasSuffix(strings.ToLower(d.Name()), ".yaml") { return nil }
+        if ext := strings.ToLower(filepath.Ext(d.Name())); ext != ".yaml" && ext != ".yml" { return nil }
         data, err := os.ReadFile(path)
         if err != nil { return err }
         var tm ToolMetadata
@@ -61,33 +62,89 @@ func LoadToolMetadataDir(dir string) ([]ToolMetadata, error) {
     return out, err
 }
 
-// ApplyToolMetadata wraps existing tools in registry with metadata if names match
+// ApplyToolMetadata wraps existing tools in registry with metadata if names
+// match, and registers the HTTP tools defined by metas
 func ApplyToolMetadata(reg *ToolRegistry, metas []ToolMetadata) error {
+    httpTools, err := newHTTPTools(reg, metas)
+    if err != nil { return err }
     for _, m := range metas {
+        if h, ok := httpTools[m.Name]; ok { reg.Register(wrap(h, m)); continue }
         base, ok := reg.Get(m.Name)
         if !ok {
             // metadata exists but no handler; warn and continue
             continue
         }
-        mt := llm.ModelTypeInvalid
-        switch strings.ToLower(m.ModelType) {
-        case "text": mt = llm.ModelTypeText
-        case "image": mt = llm.ModelTypeImage
-        case "audio": mt = llm.ModelTypeAudio
-        case "video": mt = llm.ModelTypeVideo
-        case "transcribe": mt = llm.ModelTypeTranscribe
-        case "embedding": mt = llm.ModelTypeEmbedding
-        case "vision": mt = llm.ModelTypeVision
+        reg.Register(wrap(base, m))
+    }
+    return nil
+}
+
+// ReplaceToolMetadata swaps the metadata of all tools at once: tools are
+// unwrapped from previously applied metadata and wrapped with metas, so
+// metadata that was removed no longer applies. HTTP tools are replaced by
+// those in metas. Readers never see a mix.
+func ReplaceToolMetadata(reg *ToolRegistry, metas []ToolMetadata) error {
+    httpTools, err := newHTTPTools(reg, metas)
+    if err != nil { return err }
+    byName := make(map[string]ToolMetadata, len(metas))
+    for _, m := range metas { byName[m.Name] = m }
+    reg.update(func(cur map[string]Tool) map[string]Tool {
+        out := make(map[string]Tool, len(cur))
+        for name, t := range cur {
+            base := Unwrap(t)
+            if _, ok := base.(*HTTPTool); ok { continue }
+            if m, ok := byName[base.Name()]; ok { out[m.Name] = wrap(base, m); continue }
+            if _, wrapped := t.(*WrappedTool); wrapped { name = base.Name() }
+            out[name] = base
         }
-        wrapped := &WrappedTool{
-            base:        base,
-            name:        m.Name,
-            description: m.Description,
-            parameters:  m.Parameters,
-            model:       m.RequiredModel,
-            modelType:   mt,
+        for name, h := range httpTools { out[name] = wrap(h, byName[name]) }
+        return out
+    })
+    return nil
+}
+
+// newHTTPTools builds the HTTP tools of metas. Their names must not be taken
+// by Go handlers.
+func newHTTPTools(reg *ToolRegistry, metas []ToolMetadata) (map[string]*HTTPTool, error) {
+    out := map[string]*HTTPTool{}
+    for _, m := range metas {
+        if m.HTTP == nil { continue }
+        if t, ok := reg.Get(m.Name); ok {
+            if _, isHTTP := Unwrap(t).(*HTTPTool); !isHTTP { return nil, fmt.Errorf("tool %s: an http tool cannot replace a registered handler", m.Name) }
         }
-        reg.Register(wrapped)
+        h, err := NewHTTPTool(m)
+        if err != nil { return nil, err }
+        out[m.Name] = h
+    }
+    return out, nil
+}
+
+// Unwrap returns the handler underneath any metadata overlays
+func Unwrap(t Tool) Tool {
+    for {
+        w, ok := t.(*WrappedTool)
+        if !ok { return t }
+        t = w.base
+    }
+}
+
+func wrap(base Tool, m ToolMetadata) *WrappedTool {
+    mt := llm.ModelTypeInvalid
+    switch strings.ToLower(m.ModelType) {
+    case "text": mt = llm.ModelTypeText
+    case "image": mt = llm.ModelTypeImage
+    case "audio": mt = llm.ModelTypeAudio
+    case "video": mt = llm.ModelTypeVideo
+    case "transcribe": mt = llm.ModelTypeTranscribe
+    case "embedding": mt = llm.ModelTypeEmbedding
+    case "vision": mt = llm.ModelTypeVision
+    }
+    return &WrappedTool{
+        base:        base,
+        name:        m.Name,
+        description: m.Description,
+        parameters:  m.Parameters,
+        model:       m.RequiredModel,
+        modelType:   mt,
     }
-    return nil
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/pradord/llm/internal/llm"
 )
@@ -18,8 +20,10 @@ type Tool interface {
 	ModelType() llm.ModelType   // What type of model this needs
 }
 
-// ToolRegistry manages all available tools
+// ToolRegistry manages all available tools. It is safe for concurrent use, so
+// definitions can be reloaded while runs are in progress.
 type ToolRegistry struct {
+	mu    sync.RWMutex
 	tools map[string]Tool
 }
 
@@ -32,17 +36,32 @@ func NewToolRegistry() *ToolRegistry {
 
 // Register adds a tool (app-defined OR built-in)
 func (r *ToolRegistry) Register(tool Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.tools[tool.Name()] = tool
 }
 
+// Unregister removes tools by name
+func (r *ToolRegistry) Unregister(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range names {
+		delete(r.tools, name)
+	}
+}
+
 // Get retrieves a tool by name
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	tool, ok := r.tools[name]
 	return tool, ok
 }
 
 // List returns all tool schemas for LLM
 func (r *ToolRegistry) List() []map[string]interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	schemas := make([]map[string]interface{}, 0, len(r.tools))
 	for _, tool := range r.tools {
 		schemas = append(schemas, map[string]interface{}{
@@ -57,6 +76,29 @@ func (r *ToolRegistry) List() []map[string]interface{} {
 	return schemas
 }
 
+// Names returns the registered tool names, sorted
+func (r *ToolRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.tools))
+	for name := range r.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// update replaces the registered tools with fn's result in one step
+func (r *ToolRegistry) update(fn func(map[string]Tool) map[string]Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cur := make(map[string]Tool, len(r.tools))
+	for name, t := range r.tools {
+		cur[name] = t
+	}
+	r.tools = fn(cur)
+}
+
 // Execute runs a tool by name
 func (r *ToolRegistry) Execute(ctx context.Context, name string, args json.RawMessage) (string, error) {
 	tool, ok := r.Get(name)
@@ -7,17 +7,57 @@ import (
     "fmt"
     "os"
     "path/filepath"
+    "strings"
 
     "github.com/pradord/llm/pkg/config"
     "github.com/pradord/llm/pkg/conversation"
+    "github.com/pradord/llm/pkg/defs"
+    "github.com/pradord/llm/pkg/knowledge"
     "github.com/pradord/llm/pkg/llm"
+    "github.com/pradord/llm/pkg/mcp"
     "github.com/pradord/llm/pkg/skills"
     "github.com/pradord/llm/pkg/tools"
     "github.com/pradord/llm/pkg/agent"
     "github.com/pradord/llm/pkg/project"
+    "github.com/pradord/llm/pkg/router"
+    "github.com/pradord/llm/pkg/skillpack"
 )
 
 func main() {
+    // Subcommands
+    if len(os.Args) > 1 {
+        switch os.Args[1] {
+        case "search":
+            os.Exit(runSearch(os.Args[2:]))
+        case "export":
+            os.Exit(runExport(os.Args[2:]))
+        case "import":
+            os.Exit(runImport(os.Args[2:]))
+        case "dataset":
+            os.Exit(runDataset(os.Args[2:]))
+        case "retention":
+            os.Exit(runRetention(os.Args[2:]))
+        case "purge-user":
+            os.Exit(runPurgeUser(os.Args[2:]))
+        case "keygen":
+            os.Exit(runKeygen(os.Args[2:]))
+        case "projects":
+            os.Exit(runProjects(os.Args[2:]))
+        case "knowledge":
+            os.Exit(runKnowledge(os.Args[2:]))
+        case "skills":
+            os.Exit(runSkills(os.Args[2:]))
+        case "defs":
+            os.Exit(runDefs(os.Args[2:]))
+        case "eval":
+            os.Exit(runEval(os.Args[2:]))
+        case "packs":
+            os.Exit(runPacks(os.Args[2:]))
+        case "mcp":
+            os.Exit(runMCP(os.Args[2:]))
+        }
+    }
+
     // Command line flags
     configPath := flag.String("config", "", "Path to config file (auto-detected if not provided)")
     generateConfig := flag.String("generate-config", "", "Generate example config file (yaml or json)")
@@ -25,19 +65,34 @@ func main() {
     agentSteps := flag.Int("agent-steps", 0, "Override agent max steps")
     agentMaxChars := flag.Int("agent-max-chars", 0, "Override agent max chars budget")
     agentTemp := flag.Float64("agent-temp", 0, "Override agent temperature")
+    agentModel := flag.String("agent-model", "", "Override the model of the agent run")
+    explain := flag.Bool("explain", false, "Print the effective run settings and where each came from")
     defsDir := flag.String("defs-dir", "", "Path to defs directory containing tools/ and skills/ YAML")
+    strictDefs := flag.Bool("strict-defs", false, "Lint --defs-dir before loading and stop on errors (see `llm defs lint`)")
+    packsDir := flag.String("packs-dir", ".llm_packs", "Directory of installed skill packs (see `llm packs`)")
     projectsDir := flag.String("projects-dir", "", "Path to projects directory with YAML definitions")
     projectID := flag.String("project", "", "Select a project ID to scope tools/skills and system prompt")
     // Retrieval flags
     threadsDir := flag.String("threads-dir", ".llm_threads", "Directory to store conversation threads")
     threadID := flag.String("thread-id", "", "Conversation thread ID for retrieval context")
+    userID := flag.String("user", "", "User ID recorded as the thread owner (see purge-user)")
+    userName := flag.String("user-name", "", "User name available to project prompt templates as {{.User}}")
+    var promptVars multiFlag
+    flag.Var(&promptVars, "var", "Project prompt variable key=value (repeatable)")
+    knowledgeDir := flag.String("knowledge-dir", "", "Directory of project knowledge indexes (default from config)")
     retrieve := flag.Bool("retrieve", true, "Enable retrieval of prior messages as context")
+    skillName := flag.String("skill", "", "Run this skill instead of the project's first skill; \"auto\" routes the prompt to the best skill")
+    promptText := flag.String("prompt", "", "User prompt of the agent run (default: a demo prompt)")
+    var skillInputs multiFlag
+    flag.Var(&skillInputs, "input", "Skill input name=value, or name=@file to read the value from a file (repeatable)")
     flag.Parse()
 
     // Load projects if provided and pick selected project
     var activeProject *project.Project
+    var projs map[string]*project.Project
     if *projectsDir != "" {
-        projs, err := project.LoadDir(*projectsDir)
+        var err error
+        projs, err = project.LoadDir(*projectsDir)
         if err != nil {
             fmt.Printf("Warning: project defs load error: %v\n", err)
         } else if *projectID != "" {
@@ -80,6 +135,14 @@ func main() {
 
     fmt.Printf("Config loaded (using model: %s)\n\n", cfg.LLM.DefaultModel)
 
+    // Thread storage is encrypted at rest when a key is configured
+    encKey, err := cfg.Persistence.Key()
+    if err != nil {
+        fmt.Printf("Invalid encryption key: %v\n", err)
+        os.Exit(1)
+    }
+    threadStoreCfg := conversation.FileStoreConfig{Key: encKey}
+
     // Create LLM client from config
     client := llm.NewClient(llm.ClientConfig{
         APIKey:         cfg.LLM.APIKey,
@@ -113,22 +176,38 @@ func main() {
     toolRegistry.Register(tools.NewImageGenerator(imageAPIKey, cfg.LLM.BaseURL))
     toolRegistry.Register(tools.NewAudioTTS(cfg.Tools.ImageAPIKey))
     toolRegistry.Register(tools.NewVideoGenerator(cfg.Tools.ImageAPIKey))
+    // Tools of the configured MCP servers, as <server>_<tool>
+    if len(cfg.MCP.Servers) > 0 {
+        mcpClients, err := mcp.ConnectAll(context.Background(), cfg.MCP.Servers, toolRegistry)
+        if err != nil { fmt.Printf("Warning: mcp: %v\n", err) }
+        for _, c := range mcpClients { defer c.Close() }
+    }
 
     // Create skill registry
     skillRegistry := skills.NewSkillRegistry(toolRegistry)
 
     // Register built-in skills
-    skillRegistry.Register(skills.NewResearchAssistant())
-    skillRegistry.Register(skills.NewContentCreator())
-    skillRegistry.Register(skills.NewCodeReviewer())
-    skillRegistry.Register(skills.NewDataAnalyst())
+    for _, s := range []*skills.Skill{skills.NewResearchAssistant(), skills.NewContentCreator(), skills.NewCodeReviewer(), skills.NewDataAnalyst()} {
+        if err := skillRegistry.Register(s); err != nil { fmt.Printf("Warning: %v\n", err) }
+    }
 
     // Load YAML tool/skill definitions if provided
+    if *defsDir != "" && *strictDefs {
+        var builtins []string
+        for _, s := range skillRegistry.List() { builtins = append(builtins, s.Name) }
+        diags, err := defs.Lint(defs.LintConfig{DefsDir: *defsDir, Tools: toolRegistry.Names(), Skills: builtins,
+            ValidModel: func(m string) bool { return llm.Model(m).IsValid() }})
+        if err != nil { fmt.Printf("Error: lint %s: %v\n", *defsDir, err); os.Exit(1) }
+        for _, d := range diags { fmt.Println(d) }
+        if defs.HasErrors(diags) { fmt.Printf("Error: definitions in %s have errors\n", *defsDir); os.Exit(1) }
+    }
     if *defsDir != "" {
         // Tools metadata: defs/tools/*.yaml
         if metas, err := tools.LoadToolMetadataDir(filepath.Join(*defsDir, "tools")); err == nil {
             // Apply metadata to existing handlers (wrap)
-            _ = tools.ApplyToolMetadata(toolRegistry, metas)
+            if err := tools.ApplyToolMetadata(toolRegistry, metas); err != nil {
+                fmt.Printf("Warning: tool defs: %v\n", err)
+            }
         } else {
             fmt.Printf("Warning: tool defs load error: %v\n", err)
         }
@@ -141,6 +220,22 @@ func main() {
             fmt.Printf("Warning: some skills reference missing tools: %v\n", missing)
         }
     }
+    // Installed skill packs
+    if _, err := skillpack.Load(*packsDir, toolRegistry, skillRegistry); err != nil {
+        fmt.Printf("Warning: skill packs: %v\n", err)
+    }
+
+    // Check project definitions against the registries
+    projectValidator := &project.Validator{
+        HasTool:    func(name string) bool { _, ok := toolRegistry.Get(name); return ok },
+        HasSkill:   func(name string) bool { _, ok := skillRegistry.Get(name); return ok },
+        ValidModel: func(m string) bool { return llm.Model(m).IsValid() },
+    }
+    for _, id := range sortedIDs(projs) {
+        if err := projectValidator.Validate(projs[id]); err != nil {
+            fmt.Printf("Warning: %v\n", err)
+        }
+    }
 
     // Example 1: Using client with config defaults
     fmt.Println("=== Example 1: Simple LLM Call (using config defaults) ===")
@@ -223,33 +318,91 @@ func main() {
 
     // Example 7: Skill Execution via agent loop (multi-step)
     fmt.Println("=== Example 7: Execute Skill via Agent Loop ===")
-    // Build executor with config/flags
-    maxSteps := cfg.Agent.MaxSteps
-    maxChars := cfg.Agent.MaxChars
-    temp := cfg.Agent.Temperature
-    if *agentSteps > 0 { maxSteps = *agentSteps }
-    if *agentMaxChars > 0 { maxChars = *agentMaxChars }
-    if *agentTemp > 0 { temp = *agentTemp }
-    exec := agent.NewExecutorWithConfig(client, maxSteps, maxChars, temp)
-    // Choose skill and apply project system prompt if any
+    exec := agent.NewExecutorWithClient(apiClient(client, cfg))
+    // Skill resources given as URLs are read with fetch_url
+    if fetcher, ok := toolRegistry.Get("fetch_url"); ok {
+        exec.SetResourceLoader(agent.NewResourceLoader(fetcher))
+    }
+    // Choose skill: the project's first skill if it lists any, else the research assistant
     skill := skills.NewResearchAssistant()
-    if activeProject != nil && activeProject.SystemPrompt != "" {
-        skill.SystemPrompt = activeProject.SystemPrompt + "\n\n" + skill.SystemPrompt
+    if activeProject != nil {
+        for _, name := range activeProject.Skills {
+            if s, ok := skillRegistry.Get(name); ok {
+                sk := *s
+                skill = &sk
+                break
+            }
+        }
+    }
+    userPrompt := *promptText
+    if userPrompt == "" { userPrompt = "Summarize the latest stable features of Go language in 3 bullets." }
+    // --skill auto picks the skill, or a chain of skills, within the project's allowlist
+    var route *router.Route
+    if *skillName == router.Auto {
+        rt := router.New(skillRegistry, knowledge.NewEmbeddingsClient(knowledge.EmbeddingsConfig{
+            APIKey: cfg.LLM.APIKey, BaseURL: cfg.LLM.BaseURL, Model: cfg.Persistence.EmbeddingModel,
+        }))
+        rt.Classifier = agent.AsChatClient(client)
+        var allow []string
+        if activeProject != nil { allow = activeProject.Skills }
+        route, err = rt.Route(context.Background(), userPrompt, allow)
+        if err != nil { fmt.Printf("Error: %v\n", err); os.Exit(1) }
+        fmt.Printf("Routed to %s\n", route)
+        s, ok := skillRegistry.Get(route.Skill)
+        if !ok { fmt.Printf("Unknown skill: %s\n", route.Skill); os.Exit(1) }
+        sk := *s
+        skill = &sk
+        userPrompt = route.Chain[0].Prompt
+    } else if *skillName != "" {
+        s, ok := skillRegistry.Get(*skillName)
+        if !ok { fmt.Printf("Unknown skill: %s\n", *skillName); os.Exit(1) }
+        sk := *s
+        skill = &sk
+    }
+    // Resolve run settings: defaults < config < project < skill < flags
+    callLayer := agent.Layer{Name: agent.LayerCall}
+    callLayer.Model = llm.Model(*agentModel)
+    callLayer.MaxSteps = *agentSteps
+    callLayer.MaxChars = *agentMaxChars
+    if *agentTemp > 0 { callLayer.Temperature = agentTemp }
+    setup := &agent.RunSetup{
+        Layers: []agent.Layer{agent.DefaultsLayer(), agent.ConfigLayer(cfg), agent.ProjectLayer(activeProject)},
+        Call:   []agent.Layer{callLayer},
+        Tools:  toolRegistry,
+    }
+    settings := setup.Settings(skill)
+    if *explain {
+        fmt.Printf("Run settings for skill %s:\n%s\n", skill.Name, settings)
+    }
+    // The project system prompt goes in front of every skill's, delegates included
+    promptData := project.PromptData{UserID: *userID, UserName: *userName, Vars: map[string]string{}}
+    for _, kv := range promptVars {
+        k, v, _ := strings.Cut(kv, "=")
+        promptData.Vars[k] = v
+    }
+    setup.Prompt = func(p string) (string, error) { return activeProject.SkillSystemPrompt(p, promptData) }
+    if _, err := setup.Prompt(""); err != nil {
+        fmt.Printf("Warning: %v\n", err)
+        setup.Prompt = nil
     }
     // Simple retrieval: use last N messages as context (no vector store needed)
     if *retrieve && *threadID != "" {
-        fs, err := conversation.NewFileStore(*threadsDir)
+        fs, err := conversation.NewFileStoreWithConfig(*threadsDir, threadStoreCfg)
         if err == nil {
             if th, err := fs.GetThread(*threadID); err == nil {
-                // Use last 5 messages as context
-                start := len(th.Messages) - 5
+                // Use last 5 messages of the active branch as context
+                path := th.ActivePath()
+                start := len(path) - 5
                 if start < 0 {
                     start = 0
                 }
-                if len(th.Messages) > 0 {
+                if len(path) > 0 {
                     var ctxBlock string
-                    for i := start; i < len(th.Messages); i++ {
-                        m := th.Messages[i]
+                    for i := start; i < len(path); i++ {
+                        m := path[i]
+                        if m.Role != "user" && m.Role != "assistant" || m.Content == "" {
+                            continue
+                        }
                         ctxBlock += fmt.Sprintf("- (%s) %s\n", m.Role, m.Content)
                     }
                     if ctxBlock != "" {
@@ -259,25 +412,93 @@ func main() {
             }
         }
     }
-    // Scope tools if project specifies
-    toolNames := []string{"web_search"}
-    if activeProject != nil && len(activeProject.Tools) > 0 { toolNames = activeProject.Tools }
-    userPrompt := "Summarize the latest stable features of Go language in 3 bullets."
-    out, err := exec.Run(context.Background(), skill, userPrompt, mustGetTools(toolRegistry, toolNames))
+    // Projects with a knowledge folder get search_knowledge; changed files are re-embedded first
+    if activeProject != nil && activeProject.Knowledge != "" {
+        if kb, err := openKnowledge(cfg, *knowledgeDir, activeProject.ID); err != nil {
+            fmt.Printf("Warning: knowledge base: %v\n", err)
+        } else {
+            if _, err := kb.Ingest(context.Background(), activeProject.Knowledge); err != nil {
+                fmt.Printf("Warning: knowledge ingest: %v\n", err)
+            }
+            if kb.Len() > 0 {
+                toolRegistry.Register(knowledge.NewSearchTool(kb, activeProject.ID))
+                setup.Extra = append(setup.Extra, "search_knowledge")
+            }
+        }
+    }
+    // Skills with inputs render the prompt from --input values, checked before any
+    // tokens are spent; a routed skill with inputs needs them as well
+    if len(skill.Inputs) > 0 || len(skillInputs) > 0 {
+        raw := map[string]string{}
+        for _, kv := range skillInputs {
+            k, v, _ := strings.Cut(kv, "=")
+            raw[strings.TrimSpace(k)] = v
+        }
+        values, err := skill.ParseInputs(raw, os.ReadFile)
+        if err == nil { userPrompt, err = skill.RenderPrompt(values) }
+        if err != nil { fmt.Printf("Error: %v\n", err); os.Exit(1) }
+    }
+    // Tools allowed by every layer (web search when none restricts them); required
+    // skills are callable as skill_<name> sub-agents under the same setup
+    res, err := exec.RunSkill(context.Background(), setup, skillRegistry, skill, userPrompt)
     if err != nil {
         fmt.Printf("Error: %v\n", err)
     } else {
-        fmt.Println(out)
+        fmt.Println(res.Output)
+        if res.InvalidToolCalls > 0 { fmt.Printf("(%d tool call(s) had invalid arguments and were returned to the model)\n", res.InvalidToolCalls) }
+    }
+    // Each run's transcript is saved under the skill that produced it
+    type skillRun struct {
+        skill string
+        res   *agent.Result
+    }
+    var runs []skillRun
+    if res != nil { runs = append(runs, skillRun{skill.Name, res}) }
+    // Further steps of a routed chain get the previous step's answer and run
+    // under the same setup as the first: settings, project prompt and tools
+    if route != nil && res != nil && err == nil {
+        for _, step := range route.Chain[1:] {
+            next, ok := skillRegistry.Get(step.Skill)
+            if !ok { fmt.Printf("Error: unknown skill %s\n", step.Skill); break }
+            fmt.Printf("--- %s ---\n", step.Skill)
+            stepRes, serr := exec.RunSkill(context.Background(), setup, skillRegistry, next, router.StepPrompt(step, res.Output))
+            if serr != nil { fmt.Printf("Error: %v\n", serr); break }
+            fmt.Println(stepRes.Output)
+            runs = append(runs, skillRun{next.Name, stepRes})
+            res = stepRes
+        }
     }
 
-    // Append messages to thread (if thread provided)
-    if *threadID != "" {
-        if fs, err := conversation.NewFileStore(*threadsDir); err == nil {
+    // Append the run transcripts (user prompt, tool calls and results, answer) to the thread
+    if *threadID != "" && len(runs) > 0 {
+        if fs, err := conversation.NewFileStoreWithConfig(*threadsDir, threadStoreCfg); err == nil {
+            // keep `llm search` current with what this run writes
+            idx, ierr := openSearchIndex(*threadsDir, encKey)
+            if ierr == nil { fs.AddListener(idx) }
             th, err := fs.GetThread(*threadID)
-            if err == nil {
-                _, _ = fs.AppendMessage(th.ID, conversation.Message{Role: "user", Content: userPrompt})
-                _, _ = fs.AppendMessage(th.ID, conversation.Message{Role: "assistant", Content: out})
+            if err == nil && *userID != "" && th.UserID == "" {
+                th.UserID = *userID
+                err = fs.UpdateThread(th)
             }
+            for _, run := range runs {
+                if err != nil { break }
+                // Skip the system prompt and examples; they are rebuilt from the skill when resuming
+                msgs, merr := conversation.MessagesFromOpenAI(run.res.Messages[run.res.Preamble:], string(run.res.Model))
+                if merr != nil { fmt.Printf("Warning: thread %s: %s transcript not saved: %v\n", *threadID, run.skill, merr); continue }
+                // token counts the provider reported, per assistant reply
+                for i, u := range run.res.Usage {
+                    if j := i - run.res.Preamble; j >= 0 && j < len(msgs) { usage := conversation.Usage(*u); msgs[j].Usage = &usage }
+                }
+                // Record the skill so runs can be selected for datasets later
+                for i := range msgs {
+                    if msgs[i].Metadata == nil { msgs[i].Metadata = map[string]interface{}{} }
+                    msgs[i].Metadata["skill"] = run.skill
+                }
+                _, err = fs.AppendMessages(th.ID, msgs)
+            }
+            if err != nil { fmt.Printf("Warning: thread %s not updated: %v\n", *threadID, err) }
+            if ierr == nil { ierr = idx.Save() }
+            if ierr != nil { fmt.Printf("Warning: search index not updated: %v\n", ierr) }
         }
     }
 
@@ -290,13 +511,14 @@ func main() {
     fmt.Println("Run: ./llm --generate-config=yaml")
 }
 
-// helper to resolve tool implementations from names
-func mustGetTools(reg *tools.ToolRegistry, names []string) []tools.Tool {
-    out := make([]tools.Tool, 0, len(names))
-    for _, n := range names {
-        if t, ok := reg.Get(n); ok {
-            out = append(out, t)
-        }
-    }
-    return out
+// apiClient lets runs use native structured output and record token usage,
+// which client does not expose
+func apiClient(client *llm.Client, cfg *config.Config) *agent.APIClient {
+    return agent.NewAPIClient(client, agent.APIConfig{
+        APIKey:         cfg.LLM.APIKey,
+        BaseURL:        cfg.LLM.BaseURL,
+        DefaultModel:   cfg.LLM.DefaultModel,
+        TimeoutSeconds: cfg.LLM.TimeoutSeconds,
+        MaxRetries:     cfg.LLM.MaxRetries,
+    })
 }
@@ -1,13 +1,44 @@
 package agent
 
 import (
+    "context"
+
     i "github.com/pradord/llm/internal/agent"
+    i_config "github.com/pradord/llm/internal/config"
     i_llm "github.com/pradord/llm/internal/llm"
+    i_project "github.com/pradord/llm/internal/project"
+    i_skills "github.com/pradord/llm/internal/skills"
+    i_tools "github.com/pradord/llm/internal/tools"
     p_llm "github.com/pradord/llm/pkg/llm"
 )
 
 type (
     Executor = i.Executor
+    Result = i.Result
+    Settings = i.Settings
+    Layer = i.Layer
+    Resolved = i.Resolved
+    Explanation = i.Explanation
+    SkillTool = i.SkillTool
+    OutputError = i.OutputError
+    ResourceLoader = i.ResourceLoader
+    ChatClient = i.ChatClient
+    ArgumentError = i.ArgumentError
+    RunSetup = i.RunSetup
+    ChatRequest = i.ChatRequest
+    ChatResponse = i.ChatResponse
+    Usage = i.Usage
+    CompletionClient = i.CompletionClient
+    APIClient = i.APIClient
+    APIConfig = i.APIConfig
+)
+
+const (
+    LayerDefaults = i.LayerDefaults
+    LayerConfig = i.LayerConfig
+    LayerProject = i.LayerProject
+    LayerSkill = i.LayerSkill
+    LayerCall = i.LayerCall
 )
 
 func NewExecutor(client *p_llm.Client) *Executor {
@@ -17,3 +48,31 @@ func NewExecutor(client *p_llm.Client) *Executor {
 func NewExecutorWithConfig(client *p_llm.Client, maxSteps int, maxChars int, temperature float64) *Executor {
     return i.NewExecutorWithConfig((*i_llm.Client)(client), maxSteps, maxChars, temperature)
 }
+
+func NewExecutorWithClient(client ChatClient) *Executor { return i.NewExecutorWithClient(client) }
+
+// AsChatClient lets a client be used where a ChatClient is expected, e.g. by eval runners
+func AsChatClient(client *p_llm.Client) ChatClient { return (*i_llm.Client)(client) }
+
+// NewAPIClient wraps client with direct calls to the chat completions endpoint,
+// for native structured output and token usage
+func NewAPIClient(client *p_llm.Client, cfg APIConfig) *APIClient { return i.NewAPIClient((*i_llm.Client)(client), cfg) }
+
+// Complete sends req through client, natively when it is a CompletionClient
+func Complete(ctx context.Context, client ChatClient, req *ChatRequest) (*ChatResponse, error) { return i.Complete(ctx, client, req) }
+
+func Resolve(layers ...Layer) *Resolved { return i.Resolve(layers...) }
+func DefaultsLayer() Layer { return i.DefaultsLayer() }
+func ConfigLayer(c *i_config.Config) Layer { return i.ConfigLayer(c) }
+func ProjectLayer(p *i_project.Project) Layer { return i.ProjectLayer(p) }
+func SkillLayer(s *i_skills.Skill) Layer { return i.SkillLayer(s) }
+func EffectiveTools(r *Resolved) []string { return i.EffectiveTools(r) }
+func NewResourceLoader(fetcher i_tools.Tool) *ResourceLoader { return i.NewResourceLoader(fetcher) }
+
+// ExecuteTool checks the arguments against the tool's parameters, as the agent loop does, then runs it
+func ExecuteTool(ctx context.Context, t i_tools.Tool, args string) (string, error) { return i.ExecuteTool(ctx, t, args) }
+
+// RunTyped runs a skill and decodes its structured (JSON Schema validated) answer into T
+func RunTyped[T any](ctx context.Context, e *Executor, skill *i_skills.Skill, userPrompt string, toolList []i_tools.Tool) (T, *Result, error) {
+    return i.RunTyped[T](ctx, e, skill, userPrompt, toolList)
+}
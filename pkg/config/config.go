@@ -13,6 +13,8 @@ type (
     CapabilityConfig = i.CapabilityConfig
     AuthConfig = i.AuthConfig
     PersistenceConfig = i.PersistenceConfig
+    MCPConfig = i.MCPConfig
+    MCPServerConfig = i.MCPServerConfig
 )
 
 // Re-map model type fields for LLMConfig to pkg llm.Model through type aliasing
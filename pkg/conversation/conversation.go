@@ -1,14 +1,60 @@
 package conversation
 
 import (
+    "io"
+
     i "github.com/pradord/llm/internal/conversation"
+    "github.com/pradord/llm/pkg/envelope"
 )
 
 type (
     FileStore = i.FileStore
+    FileStoreConfig = i.FileStoreConfig
     Message = i.Message
     Thread = i.Thread
+    Branch = i.Branch
+    Store = i.Store
+    ToolCall = i.ToolCall
+    Usage = i.Usage
+    Attachment = i.Attachment
+    ConflictError = i.ConflictError
+    Listener = i.Listener
+    Importer = i.Importer
+    SearchIndex = i.SearchIndex
+    SearchOptions = i.SearchOptions
+    SearchResult = i.SearchResult
+    ImportResult = i.ImportResult
+    RetentionPolicy = i.RetentionPolicy
+    RetentionReport = i.RetentionReport
+)
+
+const (
+    SourceChatGPT = i.SourceChatGPT
+    SourceClaude = i.SourceClaude
+    SourceJSONL = i.SourceJSONL
+    RetentionArchive = i.RetentionArchive
+    RetentionDelete = i.RetentionDelete
 )
 
+var ErrConflict = i.ErrConflict
+
 func NewFileStore(dir string) (*FileStore, error) { return i.NewFileStore(dir) }
+func NewFileStoreWithConfig(dir string, cfg FileStoreConfig) (*FileStore, error) { return i.NewFileStoreWithConfig(dir, cfg) }
+func MessagesFromOpenAI(msgs []map[string]interface{}, model string) ([]Message, error) { return i.MessagesFromOpenAI(msgs, model) }
+func ToOpenAI(msgs []Message) []map[string]interface{} { return i.ToOpenAI(msgs) }
+func NewSearchIndex() *SearchIndex { return i.NewSearchIndex() }
+func OpenSearchIndex(path string) (*SearchIndex, error) { return i.OpenSearchIndex(path) }
+func OpenSearchIndexWithKey(path string, key *envelope.Key) (*SearchIndex, error) { return i.OpenSearchIndexWithKey(path, key) }
+func SearchIndexPath(threadsDir string) string { return i.SearchIndexPath(threadsDir) }
+func WriteMarkdown(w io.Writer, t *Thread) error { return i.WriteMarkdown(w, t) }
+func WriteHTML(w io.Writer, t *Thread) error { return i.WriteHTML(w, t) }
+func WriteJSONL(w io.Writer, t *Thread) error { return i.WriteJSONL(w, t) }
+func ReadJSONL(r io.Reader) (*Thread, error) { return i.ReadJSONL(r) }
+func ParseChatGPTExport(data []byte) ([]*Thread, error) { return i.ParseChatGPTExport(data) }
+func ParseClaudeExport(data []byte) ([]*Thread, error) { return i.ParseClaudeExport(data) }
+func ImportFile(store Store, source, path, projectID string) (*ImportResult, error) { return i.ImportFile(store, source, path, projectID) }
+func ImportThreads(store Store, threads []*Thread) (*ImportResult, error) { return i.ImportThreads(store, threads) }
+func ImportThread(store Store, t *Thread) (*Thread, error) { return i.ImportThread(store, t) }
+func IsConflict(err error) bool { return i.IsConflict(err) }
+func RetryOnConflict(attempts int, fn func() error) error { return i.RetryOnConflict(attempts, fn) }
 
@@ -0,0 +1,27 @@
+package dataset
+
+import (
+    "io"
+
+    i "github.com/pradord/llm/internal/dataset"
+    "github.com/pradord/llm/pkg/conversation"
+)
+
+type (
+    Dataset = i.Dataset
+    Example = i.Example
+    Format = i.Format
+    Options = i.Options
+    SplitStats = i.SplitStats
+    Stats = i.Stats
+)
+
+const (
+    FormatOpenAI = i.FormatOpenAI
+    FormatAnthropic = i.FormatAnthropic
+)
+
+func Build(store conversation.Store, opts Options) (*Dataset, error) { return i.Build(store, opts) }
+func WriteOpenAI(w io.Writer, examples []Example) error { return i.WriteOpenAI(w, examples) }
+func WriteAnthropic(w io.Writer, examples []Example) error { return i.WriteAnthropic(w, examples) }
+func EstimateTokens(m conversation.Message) int { return i.EstimateTokens(m) }
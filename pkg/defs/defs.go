@@ -0,0 +1,24 @@
+package defs
+
+import i "github.com/pradord/llm/internal/defs"
+
+type (
+    Config = i.Config
+    Event = i.Event
+    Watcher = i.Watcher
+    Diagnostic = i.Diagnostic
+    LintConfig = i.LintConfig
+    LintError = i.LintError
+)
+
+const (
+    KindTools = i.KindTools
+    KindSkills = i.KindSkills
+    KindProjects = i.KindProjects
+    SeverityError = i.SeverityError
+    SeverityWarning = i.SeverityWarning
+)
+
+func NewWatcher(cfg Config) *Watcher { return i.NewWatcher(cfg) }
+func Lint(cfg LintConfig) ([]Diagnostic, error) { return i.Lint(cfg) }
+func HasErrors(diags []Diagnostic) bool { return i.HasErrors(diags) }
@@ -0,0 +1,17 @@
+package envelope
+
+import (
+    i "github.com/pradord/llm/internal/envelope"
+)
+
+type (
+    Key = i.Key
+    DataKey = i.DataKey
+)
+
+var ErrNoKey = i.ErrNoKey
+
+func NewKey(raw []byte) (*Key, error) { return i.NewKey(raw) }
+func ParseKey(s string) (*Key, error) { return i.ParseKey(s) }
+func LoadKeyFile(path string) (*Key, error) { return i.LoadKeyFile(path) }
+func GenerateKey() (string, error) { return i.GenerateKey() }
@@ -0,0 +1,21 @@
+package eval
+
+import i "github.com/pradord/llm/internal/eval"
+
+type (
+    Suite = i.Suite
+    Case = i.Case
+    Variant = i.Variant
+    Assertion = i.Assertion
+    MockStep = i.MockStep
+    Price = i.Price
+    Runner = i.Runner
+    Report = i.Report
+    CaseResult = i.CaseResult
+)
+
+func LoadSuite(path string) (*Suite, error) { return i.LoadSuite(path) }
+func LoadDir(dir string) ([]*Suite, error) { return i.LoadDir(dir) }
+func Compare(reports ...*Report) string { return i.Compare(reports...) }
+func WriteReports(path string, reports []*Report) error { return i.WriteReports(path, reports) }
+func ReadReports(path string) ([]*Report, error) { return i.ReadReports(path) }
@@ -0,0 +1,17 @@
+package jsonschema
+
+import (
+    "reflect"
+
+    i "github.com/pradord/llm/internal/jsonschema"
+)
+
+type (
+    Problem = i.Problem
+    ValidationError = i.ValidationError
+)
+
+func Validate(schema map[string]interface{}, v interface{}) error { return i.Validate(schema, v) }
+func ValidateJSON(schema map[string]interface{}, data []byte) (interface{}, error) { return i.ValidateJSON(schema, data) }
+func ApplyDefaults(schema map[string]interface{}, v interface{}) interface{} { return i.ApplyDefaults(schema, v) }
+func For(t reflect.Type) map[string]interface{} { return i.For(t) }
@@ -0,0 +1,26 @@
+package knowledge
+
+import (
+    "github.com/pradord/llm/internal/embeddings"
+    i "github.com/pradord/llm/internal/knowledge"
+)
+
+type (
+    Base = i.Base
+    Config = i.Config
+    Chunk = i.Chunk
+    Result = i.Result
+    IngestReport = i.IngestReport
+    Embedder = i.Embedder
+    SearchTool = i.SearchTool
+    EmbeddingsConfig = embeddings.Config
+)
+
+func Open(dir string, e Embedder, cfg Config) (*Base, error) { return i.Open(dir, e, cfg) }
+func Dir(root, projectID string) string { return i.Dir(root, projectID) }
+func NewSearchTool(b *Base, projectID string) *SearchTool { return i.NewSearchTool(b, projectID) }
+func SplitText(text string, size, overlap int) []Chunk { return i.SplitText(text, size, overlap) }
+func Supported(path string) bool { return i.Supported(path) }
+
+// NewEmbeddingsClient returns an Embedder for an OpenAI-compatible embeddings API
+func NewEmbeddingsClient(cfg EmbeddingsConfig) Embedder { return embeddings.New(cfg) }
@@ -0,0 +1,39 @@
+package mcp
+
+import (
+    "context"
+
+    i "github.com/pradord/llm/internal/mcp"
+    "github.com/pradord/llm/pkg/config"
+    "github.com/pradord/llm/pkg/skills"
+    "github.com/pradord/llm/pkg/tools"
+)
+
+type (
+    Client = i.Client
+    Tool = i.Tool
+    ToolInfo = i.ToolInfo
+    CallResult = i.CallResult
+    Content = i.Content
+    ResourceContent = i.ResourceContent
+    Error = i.Error
+    Server = i.Server
+    Prompt = i.Prompt
+    PromptArgument = i.PromptArgument
+    Resource = i.Resource
+)
+
+const (
+    ProtocolVersion = i.ProtocolVersion
+    SkillsAsTools = i.SkillsAsTools
+    SkillsAsPrompts = i.SkillsAsPrompts
+    SkillsAsBoth = i.SkillsAsBoth
+)
+
+func Connect(ctx context.Context, cfg config.MCPServerConfig) (*Client, error) { return i.Connect(ctx, cfg) }
+func NewTool(c *Client, info ToolInfo) *Tool { return i.NewTool(c, info) }
+func Register(ctx context.Context, reg *tools.ToolRegistry, c *Client, allow []string) ([]string, error) { return i.Register(ctx, reg, c, allow) }
+func ConnectAll(ctx context.Context, servers []config.MCPServerConfig, reg *tools.ToolRegistry) ([]*Client, error) { return i.ConnectAll(ctx, servers, reg) }
+
+// NewServer publishes the tools of toolReg and the skills of skillReg; see Server for the other settings
+func NewServer(toolReg *tools.ToolRegistry, skillReg *skills.SkillRegistry) *Server { return i.NewServer(toolReg, skillReg) }
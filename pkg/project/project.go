@@ -6,9 +6,24 @@ import (
 
 type (
     FileStore = i.FileStore
+    FileStoreConfig = i.FileStoreConfig
     Project = i.Project
+    Retention = i.Retention
+    Store = i.Store
+    Validator = i.Validator
+    ValidationError = i.ValidationError
+    ConflictError = i.ConflictError
+    SyncReport = i.SyncReport
+    PromptData = i.PromptData
+)
+
+var (
+    ErrConflict = i.ErrConflict
+    ErrExists = i.ErrExists
 )
 
 func NewFileStore(dir string) (*FileStore, error) { return i.NewFileStore(dir) }
+func NewFileStoreWithConfig(dir string, cfg FileStoreConfig) (*FileStore, error) { return i.NewFileStoreWithConfig(dir, cfg) }
 func LoadDir(dir string) (map[string]*Project, error) { return i.LoadDir(dir) }
-
+func Sync(store Store, defs map[string]*Project, v *Validator, prune bool) (*SyncReport, error) { return i.Sync(store, defs, v, prune) }
+func Resolve(defs map[string]*Project) (map[string]*Project, error) { return i.Resolve(defs) }
@@ -0,0 +1,24 @@
+package router
+
+import (
+    i "github.com/pradord/llm/internal/router"
+    i_skills "github.com/pradord/llm/internal/skills"
+)
+
+type (
+    Router = i.Router
+    Route = i.Route
+    Step = i.Step
+    Candidate = i.Candidate
+    Embedder = i.Embedder
+)
+
+const (
+    Auto = i.Auto
+    MethodEmbedding = i.MethodEmbedding
+    MethodKeyword = i.MethodKeyword
+    MethodClassifier = i.MethodClassifier
+)
+
+func New(reg *i_skills.SkillRegistry, e Embedder) *Router { return i.New(reg, e) }
+func StepPrompt(step Step, previous string) string { return i.StepPrompt(step, previous) }
@@ -0,0 +1,21 @@
+package skillpack
+
+import (
+    i "github.com/pradord/llm/internal/skillpack"
+    "github.com/pradord/llm/pkg/skills"
+    "github.com/pradord/llm/pkg/tools"
+)
+
+type (
+    Manifest = i.Manifest
+    Pack = i.Pack
+    Options = i.Options
+)
+
+const ManifestFile = i.ManifestFile
+
+func Read(dir string) (*Pack, error) { return i.Read(dir) }
+func Install(src, packsDir string, opts Options) (*Pack, error) { return i.Install(src, packsDir, opts) }
+func Uninstall(packsDir, name string, toolReg *tools.ToolRegistry, skillReg *skills.SkillRegistry) error { return i.Uninstall(packsDir, name, toolReg, skillReg) }
+func List(packsDir string) ([]*Pack, error) { return i.List(packsDir) }
+func Load(packsDir string, toolReg *tools.ToolRegistry, skillReg *skills.SkillRegistry) ([]*Pack, error) { return i.Load(packsDir, toolReg, skillReg) }
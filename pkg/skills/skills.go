@@ -9,12 +9,20 @@ import (
 type (
     Skill = i.Skill
     SkillRegistry = i.SkillRegistry
+    Example = i.Example
+    Input = i.Input
 )
 
 func NewSkillRegistry(toolRegistry *p_tools.ToolRegistry) *SkillRegistry {
     return i.NewSkillRegistry((*i_tools.ToolRegistry)(toolRegistry))
 }
 
+// GetAllBuiltInSkills returns the built-in skills by name
+func GetAllBuiltInSkills() map[string]*Skill { return i.GetAllBuiltInSkills() }
+
+// ParseSkillsDir reads skill YAML definitions without registering them
+func ParseSkillsDir(dir string) ([]*Skill, error) { return i.ParseSkillsDir(dir) }
+
 // Simple re-exports for common built-in skills
 func NewResearchAssistant() *Skill { return i.NewResearchAssistant() }
 func NewContentCreator() *Skill { return i.NewContentCreator() }
@@ -1,6 +1,8 @@
 package tools
 
 import (
+    "context"
+
     i "github.com/pradord/llm/internal/tools"
     i_llm "github.com/pradord/llm/internal/llm"
     p_llm "github.com/pradord/llm/pkg/llm"
@@ -9,9 +11,24 @@ import (
 type (
     Tool = i.Tool
     ToolRegistry = i.ToolRegistry
+    SimpleTool = i.SimpleTool
+    ToolMetadata = i.ToolMetadata
+    HTTPSpec = i.HTTPSpec
+    HTTPTool = i.HTTPTool
 )
 
 func NewToolRegistry() *ToolRegistry { return i.NewToolRegistry() }
 
+// NewSimpleTool creates a tool from a name, description and handler
+func NewSimpleTool(name, description string, handler func(ctx context.Context, args map[string]interface{}) (string, error)) *SimpleTool {
+    return i.NewSimpleTool(name, description, handler)
+}
+
+// NewHTTPTool creates the HTTP tool defined by m.HTTP
+func NewHTTPTool(m ToolMetadata) (*HTTPTool, error) { return i.NewHTTPTool(m) }
+
+// GetAllBuiltInTools returns the built-in tools that need no LLM client, by name
+func GetAllBuiltInTools() map[string]Tool { return i.GetAllBuiltInTools() }
+
 // Re-export helpers to register built-in tools through pkg API when needed
 func NewWebSearch(client *p_llm.Client, model p_llm.Model) Tool { return i.NewWebSearch((*i_llm.Client)(client), model) }